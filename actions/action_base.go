@@ -1,6 +1,12 @@
 package actions
 
-import "github.com/zale144/ube/model"
+import (
+	"sort"
+	"time"
+
+	"github.com/zale144/ube/libs/redact"
+	"github.com/zale144/ube/model"
+)
 
 type Base struct {
 	critical       bool
@@ -8,6 +14,13 @@ type Base struct {
 	async          bool
 	batchSize      int
 	skips          map[string]struct{}
+	dependsOn      []string
+	timeout        time.Duration
+	concurrency    int
+	retry          *RetryPolicy
+	label          string
+	redactor       *redact.Redactor
+	orderKey       model.OrderKeyFn
 }
 
 type BaseOption func(p *Base)
@@ -47,6 +60,51 @@ func Async() BaseOption {
 	}
 }
 
+// DependsOn declares the names of the actions that must have run before this one.
+// Actions without declared dependencies depend on the action preceding them in the pipeline
+func DependsOn(actions ...string) BaseOption {
+	return func(a *Base) {
+		a.dependsOn = actions
+	}
+}
+
+// Timeout limits how long the action may take to process all of its batches. The batches in progress get a context that
+// expires when the time is up, and the ones that didn't start by then fail with model.ErrActionTimeout
+func Timeout(timeout time.Duration) BaseOption {
+	return func(a *Base) {
+		a.timeout = timeout
+	}
+}
+
+// Concurrency limits how many batches of an async action are processed at the same time
+func Concurrency(limit int) BaseOption {
+	return func(a *Base) {
+		a.concurrency = limit
+	}
+}
+
+// Ordered makes the async action process the business events with the same key, their group ID or else the keys of
+// their entities, one after another, in the order they came in. The business events with different keys are still
+// processed at the same time
+func Ordered() BaseOption {
+	return OrderedBy(model.GroupKey)
+}
+
+// OrderedBy is Ordered, with the key of the business events being what orderKey returns
+func OrderedBy(orderKey model.OrderKeyFn) BaseOption {
+	return func(a *Base) {
+		a.orderKey = orderKey
+	}
+}
+
+// Label tells apart the actions with the same name. The action name and the label make the ID that the republished
+// business events resume at, so it must not change between deployments, while the position of the action may
+func Label(label string) BaseOption {
+	return func(a *Base) {
+		a.label = label
+	}
+}
+
 // IsCritical marks if an action is critical
 func (a Base) IsCritical() bool {
 	return a.critical
@@ -64,3 +122,44 @@ func (a Base) IsAsync() bool {
 func (a Base) FailureMandate() model.ActionMandate {
 	return a.failureMandate
 }
+
+// DependsOn returns the names of the actions this action depends on
+func (a Base) DependsOn() []string {
+	return a.dependsOn
+}
+
+// Timeout returns how long the action may take to process all of its batches, zero meaning no limit
+func (a Base) Timeout() time.Duration {
+	return a.timeout
+}
+
+// Concurrency returns how many batches of the action may be processed at the same time, zero meaning no limit
+func (a Base) Concurrency() int {
+	return a.concurrency
+}
+
+// OrderKey returns the key of the business events that are processed in order, nil meaning they aren't
+func (a Base) OrderKey() model.OrderKeyFn {
+	return a.orderKey
+}
+
+// RetryPolicy returns how the failed business events are retried within the invocation, nil meaning they aren't
+func (a Base) RetryPolicy() *RetryPolicy {
+	return a.retry
+}
+
+// Label returns the label that tells the action apart from the ones with the same name
+func (a Base) Label() string {
+	return a.label
+}
+
+// Skips returns the names of the events the action skips, sorted
+func (a Base) Skips() []string {
+	skips := make([]string, 0, len(a.skips))
+	for s := range a.skips {
+		skips = append(skips, s)
+	}
+	sort.Strings(skips)
+
+	return skips
+}
@@ -2,6 +2,7 @@ package actions
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -55,3 +56,27 @@ func TestAsync(t *testing.T) {
 	Async()(base)
 	assert.Equalf(t, want.IsAsync(), base.IsAsync(), "Async()")
 }
+
+func TestDependsOn(t *testing.T) {
+	base := &Base{}
+	DependsOn("InputTransformer", "Enricher")(base)
+	assert.Equalf(t, []string{"InputTransformer", "Enricher"}, base.DependsOn(), "DependsOn()")
+}
+
+func TestTimeout(t *testing.T) {
+	base := &Base{}
+	Timeout(time.Second)(base)
+	assert.Equalf(t, time.Second, base.Timeout(), "Timeout()")
+}
+
+func TestConcurrency(t *testing.T) {
+	base := &Base{}
+	Concurrency(4)(base)
+	assert.Equalf(t, 4, base.Concurrency(), "Concurrency()")
+}
+
+func TestSkips(t *testing.T) {
+	base := &Base{}
+	Skip("UpdateProduct", "CreateProduct")(base)
+	assert.Equalf(t, []string{"CreateProduct", "UpdateProduct"}, base.Skips(), "Skips()")
+}
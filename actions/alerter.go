@@ -0,0 +1,127 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zale144/ube/model"
+)
+
+// Alert is a wrapper for injecting an alerter into a pipeline
+type Alert struct {
+	alerter IAlerter
+	Base
+}
+
+// Alerter constructs a new Alert
+func Alerter(alerter IAlerter, options ...BaseOption) *Alert {
+	a := &Alert{
+		alerter: alerter,
+		Base: Base{
+			batchSize:      100,
+			failureMandate: model.StopFurtherProcessing,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&a.Base)
+	}
+
+	return a
+}
+
+// Process implements the action interface in UBE, alerts about the business events that failed with the
+// StopAndRaiseError mandate. The events that failed at the same action with the same error are grouped
+// into a single alert, and every event is alerted about only once
+func (a *Alert) Process(ctx context.Context, bes ...model.Medium) {
+	var (
+		alerts  []*model.Alert
+		groups  = make(map[alertKey]*model.Alert)
+		alerted = make(map[string]struct{})
+		toFlag  []model.PipelineMedium
+	)
+
+	for _, iBe := range bes {
+		be, ok := iBe.(model.PipelineMedium)
+		if !ok {
+			continue
+		}
+
+		if be.GetPreviousActionMandate() != model.StopAndRaiseError || be.GetError() == nil {
+			continue
+		}
+
+		if _, ok := a.skips[be.GetEventName()]; ok {
+			continue
+		}
+
+		toFlag = append(toFlag, be)
+
+		if _, ok := alerted[be.GetID()]; ok {
+			continue
+		}
+		alerted[be.GetID()] = struct{}{}
+
+		key := alertKey{action: be.GetPreviousAction(), err: be.GetError().Error()}
+
+		alert, ok := groups[key]
+		if !ok {
+			alert = &model.Alert{
+				Action:      be.GetPreviousActionName(),
+				ActionIndex: key.action,
+				Error:       key.err,
+			}
+			groups[key] = alert
+			alerts = append(alerts, alert)
+		}
+
+		alert.Events = append(alert.Events, model.AlertedEvent{
+			ID:        be.GetID(),
+			EventName: be.GetEventName(),
+			MessageID: be.GetEventID(),
+		})
+	}
+
+	if len(alerts) == 0 {
+		return
+	}
+
+	if err := a.raise(ctx, alerts); err != nil {
+		// the events keep their mandate, so the alert is raised again after the next action
+		model.LoggerFrom(ctx).Error("Not only did your events fail, we couldn't even tell anyone about it.", "error", err)
+		return
+	}
+
+	for _, be := range toFlag {
+		// the event stays stopped, but it's not alerted about again
+		be.SetPreviousActionMandate(model.StopFurtherProcessing)
+	}
+
+	model.LoggerFrom(ctx).Info("Alerts raised. Somebody's pager is going off right about now.", "alerts", len(alerts))
+}
+
+func (a *Alert) raise(ctx context.Context, alerts []*model.Alert) error {
+	if a.alerter == nil {
+		return fmt.Errorf("alerter is not set for the pipeline")
+	}
+
+	if err := a.alerter.Alert(ctx, alerts...); err != nil {
+		return fmt.Errorf("raise alerts fail: %w", err)
+	}
+
+	return nil
+}
+
+// alertKey groups the failures of the same action with the same error
+type alertKey struct {
+	action int
+	err    string
+}
+
+func (*Alert) Name() string {
+	return "Alerter"
+}
+
+func (a Alert) DepCallNames() []string {
+	return []string{"Alert"}
+}
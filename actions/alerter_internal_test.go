@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/model"
+)
+
+func newFailedEvent(id string, actionIdx int, err error) *model.BusinessEvent {
+	return &model.BusinessEvent{
+		ID: id,
+		Event: &model.Event{
+			EventHeader: model.EventHeader{EventName: "created"},
+			ID:          "msg_" + id,
+		},
+		Error:                 err,
+		PreviousActionMandate: model.StopAndRaiseError,
+		PreviousAction:        actionIdx,
+		PreviousActionName:    "Persister",
+	}
+}
+
+func TestAlerter_groups_and_dedupes(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var raised []*model.Alert
+
+	alerter := NewMockIAlerter(ctrl)
+	alerter.EXPECT().Alert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, alerts ...*model.Alert) error {
+		raised = alerts
+		return nil
+	})
+
+	first := newFailedEvent("1", 2, errors.New("table not found"))
+	second := newFailedEvent("2", 2, errors.New("table not found"))
+	third := newFailedEvent("3", 2, errors.New("throttled"))
+	retried := newFailedEvent("4", 2, errors.New("throttled"))
+	retried.PreviousActionMandate = model.StopAndRetry
+
+	a := Alerter(alerter)
+	a.Process(context.Background(), first, second, third, retried, first)
+
+	require.Len(t, raised, 2)
+	assert.Equal(t, "Persister", raised[0].Action)
+	assert.Equal(t, 2, raised[0].ActionIndex)
+	assert.Equal(t, "table not found", raised[0].Error)
+	assert.Equal(t, []model.AlertedEvent{
+		{ID: "1", EventName: "created", MessageID: "msg_1"},
+		{ID: "2", EventName: "created", MessageID: "msg_2"},
+	}, raised[0].Events)
+	assert.Equal(t, "throttled", raised[1].Error)
+	assert.Len(t, raised[1].Events, 1)
+
+	assert.Equal(t, model.StopFurtherProcessing, first.PreviousActionMandate)
+	assert.Equal(t, model.StopAndRetry, retried.PreviousActionMandate)
+	assert.Error(t, first.Error)
+
+	// the alerted events are not alerted about again
+	a.Process(context.Background(), first, second, third)
+}
+
+func TestAlerter_AlertFails(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alerter := NewMockIAlerter(ctrl)
+	alerter.EXPECT().Alert(gomock.Any(), gomock.Any()).Return(errors.New("webhook down"))
+
+	be := newFailedEvent("1", 0, errors.New("boom"))
+
+	Alerter(alerter).Process(context.Background(), be)
+
+	assert.Equal(t, model.StopAndRaiseError, be.PreviousActionMandate)
+	assert.EqualError(t, be.Error, "boom")
+}
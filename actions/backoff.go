@@ -0,0 +1,45 @@
+package actions
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff is an exponential backoff policy with jitter
+type Backoff struct {
+	// Base is the delay before the first retry
+	Base time.Duration
+	// Max caps the delay, no limit if it's zero
+	Max time.Duration
+	// Multiplier grows the delay with every attempt, 2 if it's not set
+	Multiplier float64
+	// Jitter is the fraction of the delay, between 0 and 1, that is randomised,
+	// so that the events that failed together don't come back together
+	Jitter float64
+}
+
+// jitterFn returns a random number in [0, 1). Override it for testing
+var jitterFn = rand.Float64
+
+// Delay returns how long to wait before the attempt, counting from 1
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := float64(b.Base) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	jitter := math.Min(math.Max(b.Jitter, 0), 1)
+	delay -= delay * jitter * jitterFn()
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,33 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	defer func(fn func() float64) { jitterFn = fn }(jitterFn)
+	jitterFn = func() float64 { return 0.5 }
+
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt", backoff: Backoff{Base: time.Second}, attempt: 1, want: time.Second},
+		{name: "doubles by default", backoff: Backoff{Base: time.Second}, attempt: 3, want: 4 * time.Second},
+		{name: "multiplier", backoff: Backoff{Base: time.Second, Multiplier: 3}, attempt: 3, want: 9 * time.Second},
+		{name: "capped", backoff: Backoff{Base: time.Second, Max: 5 * time.Second}, attempt: 10, want: 5 * time.Second},
+		{name: "jitter", backoff: Backoff{Base: 4 * time.Second, Jitter: 0.5}, attempt: 1, want: 3 * time.Second},
+		{name: "no attempt yet", backoff: Backoff{Base: time.Second}, attempt: 0, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.backoff.Delay(tt.attempt))
+		})
+	}
+}
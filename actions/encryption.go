@@ -0,0 +1,226 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zale144/ube/libs/encryption"
+	"github.com/zale144/ube/model"
+)
+
+// encryptable is a business event that keeps track of whether its sensitive fields are encrypted
+type encryptable interface {
+	GetSensitiveFieldsEncrypted() bool
+	GetEncryptionKeyIDs() []string
+	SetSensitiveFieldsEncrypted(encrypted bool, keyIDs []string)
+}
+
+// Encrypt is a wrapper for envelope-encrypting the sensitive fields of the entities of the business event be,
+// for them to be persisted and published encrypted. The raw data carries them in clear, so it's encrypted as a whole
+type Encrypt struct {
+	keys encryption.KeyProvider
+	Base
+}
+
+// Encrypter constructs a new Encrypt
+func Encrypter(keys encryption.KeyProvider, options ...BaseOption) *Encrypt {
+	enc := &Encrypt{
+		keys: keys,
+		Base: Base{
+			batchSize:      100,
+			failureMandate: model.StopAndRaiseError,
+			critical:       true,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&enc.Base)
+	}
+
+	return enc
+}
+
+// Process implements the action interface in UBE, executes the underlying embedded device
+func (e Encrypt) Process(ctx context.Context, bes ...model.Medium) {
+	var counter int
+
+	for i := range bes {
+		be := bes[i]
+		if be.GetID() == "" {
+			bes[i].SetError(errors.New("encrypt can't handle empty business event"))
+			continue
+		}
+
+		if _, ok := e.skips[be.GetEventName()]; ok {
+			continue
+		}
+
+		raw := rawDataOf(be)
+		keyID, err := encryption.Encrypt(ctx, e.keys, append(entitiesOf(be), raw...)...)
+		if err != nil {
+			be.SetError(fmt.Errorf("encrypt business event '%s' fail: %w", be.GetID(), err))
+			continue
+		}
+		setRawData(be, raw)
+
+		if enc, ok := be.(encryptable); ok {
+			keyIDs := enc.GetEncryptionKeyIDs()
+			if keyID != "" && !contains(keyIDs, keyID) {
+				keyIDs = append(keyIDs, keyID)
+			}
+			enc.SetSensitiveFieldsEncrypted(len(keyIDs) > 0, keyIDs)
+		}
+
+		counter++
+	}
+
+	model.LoggerFrom(ctx).Info("entities encrypted", "size", counter)
+}
+
+func (e Encrypt) DepCallNames() []string {
+	return []string{"GenerateDataKey"}
+}
+
+func (e Encrypt) Name() string {
+	return "Encrypter"
+}
+
+// Decrypt is a wrapper for decrypting the sensitive fields of the entities of the business event be,
+// that an upstream pipeline encrypted
+type Decrypt struct {
+	keys encryption.KeyProvider
+	Base
+}
+
+// Decrypter constructs a new Decrypt
+func Decrypter(keys encryption.KeyProvider, options ...BaseOption) *Decrypt {
+	dec := &Decrypt{
+		keys: keys,
+		Base: Base{
+			batchSize:      100,
+			failureMandate: model.StopAndRaiseError,
+			critical:       true,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&dec.Base)
+	}
+
+	return dec
+}
+
+// Process implements the action interface in UBE, executes the underlying embedded device
+func (e Decrypt) Process(ctx context.Context, bes ...model.Medium) {
+	var counter int
+
+	for i := range bes {
+		be := bes[i]
+		if be.GetID() == "" {
+			bes[i].SetError(errors.New("decrypt can't handle empty business event"))
+			continue
+		}
+
+		if _, ok := e.skips[be.GetEventName()]; ok {
+			continue
+		}
+
+		enc, ok := be.(encryptable)
+		if ok && !enc.GetSensitiveFieldsEncrypted() {
+			continue
+		}
+
+		raw := rawDataOf(be)
+		if _, err := encryption.Decrypt(ctx, e.keys, append(entitiesOf(be), raw...)...); err != nil {
+			be.SetError(fmt.Errorf("decrypt business event '%s' fail: %w", be.GetID(), err))
+			continue
+		}
+		setRawData(be, raw)
+
+		if ok {
+			enc.SetSensitiveFieldsEncrypted(false, nil)
+		}
+
+		counter++
+	}
+
+	model.LoggerFrom(ctx).Info("entities decrypted", "size", counter)
+}
+
+func (e Decrypt) DepCallNames() []string {
+	return []string{"DecryptDataKey"}
+}
+
+func (e Decrypt) Name() string {
+	return "Decrypter"
+}
+
+// Decrypting returns a repository that decrypts the sensitive fields of the entities it gets from repo, e.g. for the
+// Enricher to patch the originals that were persisted encrypted
+func Decrypting(repo IRepository, keys encryption.KeyProvider) IRepository {
+	return decryptingRepository{IRepository: repo, keys: keys}
+}
+
+type decryptingRepository struct {
+	IRepository
+	keys encryption.KeyProvider
+}
+
+func (r decryptingRepository) GetEntity(ctx context.Context, key model.Key, entity interface{}) error {
+	if err := r.IRepository.GetEntity(ctx, key, entity); err != nil {
+		return err
+	}
+
+	if _, err := encryption.Decrypt(ctx, r.keys, entity); err != nil {
+		return fmt.Errorf("decrypt entity with key '%s' fail: %w", model.StringifyKey(key), err)
+	}
+
+	return nil
+}
+
+func entitiesOf(be model.Medium) []interface{} {
+	ents := be.GetEntities()
+
+	values := make([]interface{}, len(ents))
+	for i := range ents {
+		values[i] = ents[i]
+	}
+
+	return values
+}
+
+// rawData is a piece of the raw data of the business event, encrypted as a whole
+type rawData struct {
+	RawDataEvent string `sensitive:""`
+}
+
+func rawDataOf(be model.Medium) []interface{} {
+	raw := be.GetRawData()
+
+	values := make([]interface{}, len(raw))
+	for i := range raw {
+		values[i] = &rawData{RawDataEvent: string(raw[i])}
+	}
+
+	return values
+}
+
+// setRawData puts the encrypted or decrypted raw data back in the business event
+func setRawData(be model.Medium, values []interface{}) {
+	raw := be.GetRawData()
+
+	for i := range raw {
+		raw[i] = []byte(values[i].(*rawData).RawDataEvent)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,184 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/libs/encryption"
+	"github.com/zale144/ube/model"
+)
+
+type secretObj struct {
+	ID    string
+	Email string `sensitive:"mask"`
+}
+
+func (so *secretObj) GetKey() model.Key {
+	return entityObj{ID: so.ID}
+}
+
+func newKeyProvider(t *testing.T) *encryption.StaticKeyProvider {
+	kp, err := encryption.NewStaticKeyProvider("key-1", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	return kp
+}
+
+func TestEncrypter_Decrypter(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+	kp := newKeyProvider(t)
+
+	ent := &secretObj{ID: "1", Email: "jane@example.com"}
+	be := &model.BusinessEvent{ID: "BE-12345", Event: &model.Event{}, Entities: []model.Entity{ent}}
+
+	Encrypter(kp).Process(ctx, be)
+
+	require.NoError(t, be.Error)
+	assert.True(t, encryption.IsEncrypted(ent.Email))
+	assert.Equal(t, "1", ent.ID)
+	assert.True(t, be.Event.SensitiveFieldsEncrypted)
+	assert.Equal(t, []string{"key-1"}, be.Event.EncryptionKeyIDs)
+
+	// encrypting again changes nothing
+	encrypted := ent.Email
+	Encrypter(kp).Process(ctx, be)
+
+	require.NoError(t, be.Error)
+	assert.Equal(t, encrypted, ent.Email)
+	assert.Equal(t, []string{"key-1"}, be.Event.EncryptionKeyIDs)
+
+	Decrypter(kp).Process(ctx, be)
+
+	require.NoError(t, be.Error)
+	assert.Equal(t, "jane@example.com", ent.Email)
+	assert.False(t, be.Event.SensitiveFieldsEncrypted)
+	assert.Empty(t, be.Event.EncryptionKeyIDs)
+}
+
+func TestEncrypter_RawData(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+	kp := newKeyProvider(t)
+
+	raw := `{"id":"1","email":"jane@example.com"}`
+	be := &model.BusinessEvent{
+		ID:           "BE-12345",
+		Event:        &model.Event{},
+		Entities:     []model.Entity{&secretObj{ID: "1", Email: "jane@example.com"}},
+		RawDataEvent: [][]byte{[]byte(raw)},
+	}
+
+	Encrypter(kp).Process(ctx, be)
+	require.NoError(t, be.Error)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := NewMockIPublisher(ctrl)
+	mockPublisher.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msgs ...model.Input) error {
+		var published struct {
+			RawDataEvent [][]byte `json:"raw_data_event"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(msgs[0].GetBody()), &published))
+		assert.True(t, encryption.IsEncrypted(string(published.RawDataEvent[0])))
+		assert.NotContains(t, string(published.RawDataEvent[0]), "jane@example.com")
+		return nil
+	})
+
+	Publisher(mockPublisher).Process(ctx, be)
+	require.NoError(t, be.Error)
+
+	// encrypting again changes nothing
+	encrypted := string(be.RawDataEvent[0])
+	Encrypter(kp).Process(ctx, be)
+
+	require.NoError(t, be.Error)
+	assert.Equal(t, encrypted, string(be.RawDataEvent[0]))
+
+	Decrypter(kp).Process(ctx, be)
+
+	require.NoError(t, be.Error)
+	assert.Equal(t, raw, string(be.RawDataEvent[0]))
+}
+
+func TestEncrypter_Wrong_EmptyEvent(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	be := &model.BusinessEvent{}
+	Encrypter(newKeyProvider(t)).Process(context.Background(), be)
+
+	assert.Contains(t, be.Error.Error(), "encrypt can't handle empty business event")
+}
+
+func TestDecrypter_Wrong_UnknownKey(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	ent := &secretObj{ID: "1", Email: "jane@example.com"}
+	be := &model.BusinessEvent{ID: "BE-12345", Event: &model.Event{}, Entities: []model.Entity{ent}}
+
+	Encrypter(newKeyProvider(t)).Process(ctx, be)
+	require.NoError(t, be.Error)
+
+	other, err := encryption.NewStaticKeyProvider("key-2", []byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	Decrypter(other).Process(ctx, be)
+
+	assert.ErrorContains(t, be.Error, "decrypt business event 'BE-12345' fail")
+	assert.True(t, be.Event.SensitiveFieldsEncrypted)
+}
+
+func TestEnricher_PatchDecryptedOriginal(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+	kp := newKeyProvider(t)
+
+	stored := &secretObj{ID: "1", Email: "jane@example.com"}
+	_, err := encryption.Encrypt(ctx, kp, stored)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := NewMockIRepository(ctrl)
+	mockRepository.EXPECT().GetEntity(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ model.Key, entity interface{}) error {
+			*entity.(*secretObj) = *stored
+			return nil
+		})
+
+	patch := &secretObj{ID: "1"}
+	be := &model.BusinessEvent{ID: "BE-12345", Event: &model.Event{}, Entities: []model.Entity{patch}}
+
+	Enricher(WithPatchOriginal(Decrypting(mockRepository, kp))).Process(ctx, be)
+
+	require.NoError(t, be.Error)
+	assert.Equal(t, "jane@example.com", be.Entities[0].(*secretObj).Email)
+}
@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/imdario/mergo"
-	"go.uber.org/zap"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
@@ -87,7 +86,7 @@ func (e Enrich) Process(ctx context.Context, bes ...model.Medium) {
 		}
 	}
 
-	zap.L().Info("entities enriched", zap.Int("size", counter))
+	model.LoggerFrom(ctx).Info("entities enriched", "size", counter)
 }
 
 /*
@@ -186,10 +185,10 @@ func subEntity(ctx context.Context, ent model.Entity, subEntityName string, subR
 	if f.IsValid() && f.CanSet() {
 		sev := reflect.ValueOf(sub)
 		f.Set(sev)
-		zap.L().Info("enrichment WithSubEntity: set sub-Data",
-			zap.String("field", subEntityFieldName),
-			zap.String("Data", val.Type().String()),
-			zap.Any("value", sev.Interface()))
+		model.LoggerFrom(ctx).Info("enrichment WithSubEntity: set sub-Data",
+			"field", subEntityFieldName,
+			"Data", val.Type().String(),
+			"value", sev.Interface())
 	}
 
 	return ent, nil
@@ -269,10 +268,10 @@ func patchOriginal(ctx context.Context, ent model.Entity, repo IRepository, over
 		return ent, fmt.Errorf("enrich original business event fail: %w", err)
 	}
 
-	zap.L().Info("enrichment WithPatchOriginal: merged original with patch",
-		zap.String("Data", originalVal.Type().String()),
-		zap.Any("original", original),
-		zap.Any("patch", patch))
+	model.LoggerFrom(ctx).Info("enrichment WithPatchOriginal: merged original with patch",
+		"Data", originalVal.Type().String(),
+		"original", original,
+		"patch", patch)
 
 	patchV.Elem().Set(reflect.ValueOf(original).Elem())
 
@@ -304,8 +303,8 @@ func dedupe(ctx context.Context, key model.Key, repo IRepository) error {
 		return fmt.Errorf("attempt to create a duplicate for business event ID: '%s'", model.StringifyKey(key))
 	}
 
-	zap.L().Info("enrichment WithDedupe: successfully de-duplicated",
-		zap.String("Data key", model.StringifyKey(key)))
+	model.LoggerFrom(ctx).Info("enrichment WithDedupe: successfully de-duplicated",
+		"Data key", model.StringifyKey(key))
 
 	return nil
 }
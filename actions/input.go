@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"reflect"
 
-	"go.uber.org/zap"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
@@ -104,7 +103,7 @@ func (e InputTransform) Process(ctx context.Context, bes ...model.Medium) {
 		bes[i] = be
 	}
 
-	zap.L().Info("entities transformed", zap.Int("size", counter))
+	model.LoggerFrom(ctx).Info("entities transformed", "size", counter)
 }
 
 func (e InputTransform) DepCallNames() []string {
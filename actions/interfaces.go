@@ -3,6 +3,7 @@ package actions
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/zale144/ube/model"
 )
@@ -40,3 +41,26 @@ type IRepository interface {
 	EntityExists(context.Context, model.Key) (bool, error)
 	SaveEntities(ctx context.Context, entity ...model.Entity) error
 }
+
+type IParkStore interface {
+	Park(ctx context.Context, events ...*model.ParkedEvent) error
+	Parked(ctx context.Context) ([]*model.ParkedEvent, error)
+	GetParked(ctx context.Context, id string) (*model.ParkedEvent, error)
+	Unpark(ctx context.Context, ids ...string) error
+}
+
+type IAlerter interface {
+	Alert(ctx context.Context, alerts ...*model.Alert) error
+}
+
+type IDelayedPublisher interface {
+	PublishEventsWithDelay(ctx context.Context, delay time.Duration, msg ...model.Input) error
+}
+
+type IIdempotencyStore interface {
+	// Claim marks the key as in progress for the TTL, unless it's in progress or completed already,
+	// and returns the state the key was in before
+	Claim(ctx context.Context, key string, ttl time.Duration) (model.IdempotencyState, error)
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
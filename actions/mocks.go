@@ -10,6 +10,7 @@ import (
 	model "github.com/zale144/ube/model"
 	io "io"
 	reflect "reflect"
+	time "time"
 )
 
 // MockIAcker is a mock of IAcker interface
@@ -357,3 +358,244 @@ func (mr *MockIRepositoryMockRecorder) SaveEntities(ctx interface{}, entity ...i
 	varargs := append([]interface{}{ctx}, entity...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEntities", reflect.TypeOf((*MockIRepository)(nil).SaveEntities), varargs...)
 }
+
+// MockIParkStore is a mock of IParkStore interface
+type MockIParkStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIParkStoreMockRecorder
+}
+
+// MockIParkStoreMockRecorder is the mock recorder for MockIParkStore
+type MockIParkStoreMockRecorder struct {
+	mock *MockIParkStore
+}
+
+// NewMockIParkStore creates a new mock instance
+func NewMockIParkStore(ctrl *gomock.Controller) *MockIParkStore {
+	mock := &MockIParkStore{ctrl: ctrl}
+	mock.recorder = &MockIParkStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIParkStore) EXPECT() *MockIParkStoreMockRecorder {
+	return m.recorder
+}
+
+// GetParked mocks base method
+func (m *MockIParkStore) GetParked(ctx context.Context, id string) (*model.ParkedEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetParked", ctx, id)
+	ret0, _ := ret[0].(*model.ParkedEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetParked indicates an expected call of GetParked
+func (mr *MockIParkStoreMockRecorder) GetParked(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetParked", reflect.TypeOf((*MockIParkStore)(nil).GetParked), ctx, id)
+}
+
+// Park mocks base method
+func (m *MockIParkStore) Park(ctx context.Context, events ...*model.ParkedEvent) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range events {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Park", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Park indicates an expected call of Park
+func (mr *MockIParkStoreMockRecorder) Park(ctx interface{}, events ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, events...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Park", reflect.TypeOf((*MockIParkStore)(nil).Park), varargs...)
+}
+
+// Parked mocks base method
+func (m *MockIParkStore) Parked(ctx context.Context) ([]*model.ParkedEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Parked", ctx)
+	ret0, _ := ret[0].([]*model.ParkedEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Parked indicates an expected call of Parked
+func (mr *MockIParkStoreMockRecorder) Parked(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Parked", reflect.TypeOf((*MockIParkStore)(nil).Parked), ctx)
+}
+
+// Unpark mocks base method
+func (m *MockIParkStore) Unpark(ctx context.Context, ids ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range ids {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Unpark", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unpark indicates an expected call of Unpark
+func (mr *MockIParkStoreMockRecorder) Unpark(ctx interface{}, ids ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, ids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unpark", reflect.TypeOf((*MockIParkStore)(nil).Unpark), varargs...)
+}
+
+// MockIAlerter is a mock of IAlerter interface
+type MockIAlerter struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAlerterMockRecorder
+}
+
+// MockIAlerterMockRecorder is the mock recorder for MockIAlerter
+type MockIAlerterMockRecorder struct {
+	mock *MockIAlerter
+}
+
+// NewMockIAlerter creates a new mock instance
+func NewMockIAlerter(ctrl *gomock.Controller) *MockIAlerter {
+	mock := &MockIAlerter{ctrl: ctrl}
+	mock.recorder = &MockIAlerterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIAlerter) EXPECT() *MockIAlerterMockRecorder {
+	return m.recorder
+}
+
+// Alert mocks base method
+func (m *MockIAlerter) Alert(ctx context.Context, alerts ...*model.Alert) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range alerts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Alert", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Alert indicates an expected call of Alert
+func (mr *MockIAlerterMockRecorder) Alert(ctx interface{}, alerts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, alerts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Alert", reflect.TypeOf((*MockIAlerter)(nil).Alert), varargs...)
+}
+
+// MockIDelayedPublisher is a mock of IDelayedPublisher interface
+type MockIDelayedPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockIDelayedPublisherMockRecorder
+}
+
+// MockIDelayedPublisherMockRecorder is the mock recorder for MockIDelayedPublisher
+type MockIDelayedPublisherMockRecorder struct {
+	mock *MockIDelayedPublisher
+}
+
+// NewMockIDelayedPublisher creates a new mock instance
+func NewMockIDelayedPublisher(ctrl *gomock.Controller) *MockIDelayedPublisher {
+	mock := &MockIDelayedPublisher{ctrl: ctrl}
+	mock.recorder = &MockIDelayedPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIDelayedPublisher) EXPECT() *MockIDelayedPublisherMockRecorder {
+	return m.recorder
+}
+
+// PublishEventsWithDelay mocks base method
+func (m *MockIDelayedPublisher) PublishEventsWithDelay(ctx context.Context, delay time.Duration, msg ...model.Input) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, delay}
+	for _, a := range msg {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PublishEventsWithDelay", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PublishEventsWithDelay indicates an expected call of PublishEventsWithDelay
+func (mr *MockIDelayedPublisherMockRecorder) PublishEventsWithDelay(ctx, delay interface{}, msg ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, delay}, msg...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishEventsWithDelay", reflect.TypeOf((*MockIDelayedPublisher)(nil).PublishEventsWithDelay), varargs...)
+}
+
+// MockIIdempotencyStore is a mock of IIdempotencyStore interface
+type MockIIdempotencyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIIdempotencyStoreMockRecorder
+}
+
+// MockIIdempotencyStoreMockRecorder is the mock recorder for MockIIdempotencyStore
+type MockIIdempotencyStoreMockRecorder struct {
+	mock *MockIIdempotencyStore
+}
+
+// NewMockIIdempotencyStore creates a new mock instance
+func NewMockIIdempotencyStore(ctrl *gomock.Controller) *MockIIdempotencyStore {
+	mock := &MockIIdempotencyStore{ctrl: ctrl}
+	mock.recorder = &MockIIdempotencyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIIdempotencyStore) EXPECT() *MockIIdempotencyStoreMockRecorder {
+	return m.recorder
+}
+
+// Claim mocks base method
+func (m *MockIIdempotencyStore) Claim(ctx context.Context, key string, ttl time.Duration) (model.IdempotencyState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", ctx, key, ttl)
+	ret0, _ := ret[0].(model.IdempotencyState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim
+func (mr *MockIIdempotencyStoreMockRecorder) Claim(ctx, key, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockIIdempotencyStore)(nil).Claim), ctx, key, ttl)
+}
+
+// Complete mocks base method
+func (m *MockIIdempotencyStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, key, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete
+func (mr *MockIIdempotencyStoreMockRecorder) Complete(ctx, key, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIIdempotencyStore)(nil).Complete), ctx, key, ttl)
+}
+
+// Release mocks base method
+func (m *MockIIdempotencyStore) Release(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release
+func (mr *MockIIdempotencyStoreMockRecorder) Release(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIIdempotencyStore)(nil).Release), ctx, key)
+}
@@ -0,0 +1,143 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+// Park is a wrapper for injecting a park store into a pipeline
+type Park struct {
+	store IParkStore
+	Base
+}
+
+// Parker constructs a new Park
+func Parker(store IParkStore, options ...BaseOption) *Park {
+	p := &Park{
+		store: store,
+		Base: Base{
+			batchSize:      100,
+			failureMandate: model.StopFurtherProcessing,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&p.Base)
+	}
+
+	return p
+}
+
+// Process implements the action interface in UBE, parks the business events that failed with the StopAndPark mandate
+func (p *Park) Process(ctx context.Context, bes ...model.Medium) {
+	var (
+		parked []*model.ParkedEvent
+		toPark []model.PipelineMedium
+	)
+
+	for _, iBe := range bes {
+		be, ok := iBe.(model.PipelineMedium)
+		if !ok {
+			iBe.SetError(fmt.Errorf("expected PipelineMedium, got %T", iBe))
+			continue
+		}
+
+		if be.GetPreviousActionMandate() != model.StopAndPark || be.GetError() == nil {
+			continue
+		}
+
+		pe, err := toParked(be)
+		if err != nil {
+			be.SetError(fmt.Errorf("park business event '%s' fail: %w", be.GetID(), err))
+			continue
+		}
+
+		parked = append(parked, pe)
+		toPark = append(toPark, be)
+	}
+
+	if len(parked) == 0 {
+		return
+	}
+
+	if p.store == nil {
+		for _, be := range toPark {
+			be.SetError(fmt.Errorf("park store is not set for the pipeline"))
+		}
+		return
+	}
+
+	if err := p.store.Park(ctx, parked...); err != nil {
+		for _, be := range toPark {
+			be.SetError(fmt.Errorf("park business event '%s' fail: %w", be.GetID(), err))
+		}
+		return
+	}
+
+	for _, be := range toPark {
+		if pk, ok := be.(interface{ SetParked(bool) }); ok {
+			pk.SetParked(true)
+		}
+		// the event stays where it is until someone replays it, and the original message is acked as usual
+		be.SetPreviousActionMandate(model.StopFurtherProcessing)
+	}
+
+	model.LoggerFrom(ctx).Info("Parked the business events that need a human touch. They'll be waiting for you.", "size", len(parked))
+}
+
+// toParked serialises the business event so that a replay resumes at the action that failed
+func toParked(be model.PipelineMedium) (*model.ParkedEvent, error) {
+	// the replay is a new message, it has nothing to do with the one that brought the event in
+	eventID, eventRef := be.GetEventID(), be.GetEventReference()
+	be.SetEventID("")
+	be.SetEventReference("")
+
+	msg, err := toMessage(be, false)
+
+	be.SetEventID(eventID)
+	be.SetEventReference(eventRef)
+
+	if err != nil {
+		return nil, fmt.Errorf("convert business event to message fail: %w", err)
+	}
+
+	m := make(map[string]interface{})
+
+	if err = json.Unmarshal(msg.Body, &m); err != nil {
+		return nil, fmt.Errorf("convert business event to map fail: %w", err)
+	}
+
+	m["previous_action"] = be.GetPreviousAction()
+	if id := be.GetPreviousActionID(); id != "" {
+		m["previous_action_id"] = id
+	}
+	// a republish attempt makes the pipeline skip the actions before the one that failed
+	m["is_republish"] = 0
+
+	jsn, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("convert map to message fail: %w", err)
+	}
+
+	return &model.ParkedEvent{
+		ID:               be.GetID(),
+		EventName:        be.GetEventName(),
+		PreviousAction:   be.GetPreviousAction(),
+		PreviousActionID: be.GetPreviousActionID(),
+		Error:            be.GetError().Error(),
+		ParkedAt:         model.Now().UTC().Format(time.RFC3339Nano),
+		Event:            jsn,
+	}, nil
+}
+
+func (*Park) Name() string {
+	return "Parker"
+}
+
+func (p Park) DepCallNames() []string {
+	return []string{"Park"}
+}
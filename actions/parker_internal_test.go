@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/model"
+)
+
+func newParkEvent(id string, mandate model.ActionMandate, err error) *model.BusinessEvent {
+	return &model.BusinessEvent{
+		ID: id,
+		Event: &model.Event{
+			EventHeader: model.EventHeader{EventName: "created", EventCategory: "product"},
+			ID:          "msg_" + id,
+			Reference:   "ref_" + id,
+		},
+		Error:                 err,
+		PreviousActionMandate: mandate,
+		PreviousAction:        2,
+	}
+}
+
+func TestParker_Good(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var parked []*model.ParkedEvent
+
+	store := NewMockIParkStore(ctrl)
+	store.EXPECT().Park(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, events ...*model.ParkedEvent) error {
+		parked = events
+		return nil
+	})
+
+	bad := newParkEvent("1", model.StopAndPark, errors.New("bad data"))
+	retried := newParkEvent("2", model.StopAndRetry, errors.New("throttled"))
+	good := newParkEvent("3", model.StopAndPark, nil)
+
+	Parker(store).Process(context.Background(), bad, retried, good)
+
+	require.Len(t, parked, 1)
+	assert.Equal(t, "1", parked[0].ID)
+	assert.Equal(t, "created", parked[0].EventName)
+	assert.Equal(t, 2, parked[0].PreviousAction)
+	assert.Equal(t, "bad data", parked[0].Error)
+	assert.NotEmpty(t, parked[0].ParkedAt)
+
+	m := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(parked[0].Event, &m))
+	assert.EqualValues(t, 2, m["previous_action"])
+	assert.EqualValues(t, 0, m["is_republish"])
+	assert.NotContains(t, m["event"], "id", "the replay must not be mistaken for the original message")
+
+	// the original message is still acked, but the event is parked only once
+	assert.Equal(t, "msg_1", bad.GetEventID())
+	assert.Equal(t, model.StopFurtherProcessing, bad.PreviousActionMandate)
+	assert.True(t, bad.Metadata.IsParked.Bool())
+	assert.EqualError(t, bad.Error, "bad data")
+
+	assert.Equal(t, model.StopAndRetry, retried.PreviousActionMandate)
+	assert.Nil(t, good.Metadata)
+}
+
+func TestParker_StoreFails(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockIParkStore(ctrl)
+	store.EXPECT().Park(gomock.Any(), gomock.Any()).Return(errors.New("no space left"))
+
+	be := newParkEvent("1", model.StopAndPark, errors.New("bad data"))
+
+	Parker(store).Process(context.Background(), be)
+
+	assert.ErrorContains(t, be.Error, "no space left")
+	assert.Equal(t, model.StopAndPark, be.PreviousActionMandate)
+}
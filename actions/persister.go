@@ -5,8 +5,6 @@ import (
 	"errors"
 	"fmt"
 
-	"go.uber.org/zap"
-
 	"github.com/zale144/ube/model"
 )
 
@@ -63,7 +61,7 @@ func (e Persist) Process(ctx context.Context, bes ...model.Medium) {
 		}
 	}
 
-	zap.L().Info("entities persisted", zap.Int("size", len(ents)))
+	model.LoggerFrom(ctx).Info("entities persisted", "size", len(ents))
 }
 
 func (e Persist) DepCallNames() []string {
@@ -3,7 +3,9 @@ package actions
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/zale144/ube/model"
 )
@@ -11,6 +13,8 @@ import (
 // Republish is a wrapper for injecting a retrier into a pipeline
 type Republish struct {
 	republisher IRepublisher
+	deadLetter  IPublisher
+	backoff     *Backoff
 	maxAttempts int
 	Base
 }
@@ -33,6 +37,19 @@ func Republisher(republisher IRepublisher, maxAttempts int, options ...BaseOptio
 	return r
 }
 
+// WithDeadLetter makes the Republisher publish the business events that ran out of attempts to the dead letter publisher
+func (r *Republish) WithDeadLetter(publisher IPublisher) *Republish {
+	r.deadLetter = publisher
+	return r
+}
+
+// WithBackoff makes the Republisher delay every retry attempt by the backoff policy. The delay is sent along with the
+// message if the republisher is an IDelayedPublisher, and the business event is not processed before it's over either way
+func (r *Republish) WithBackoff(backoff Backoff) *Republish {
+	r.backoff = &backoff
+	return r
+}
+
 // Process implements the action interface in UBE, executes the underlying embedded device
 func (r *Republish) Process(ctx context.Context, bes ...model.Medium) {
 	for i, iBe := range bes {
@@ -42,28 +59,78 @@ func (r *Republish) Process(ctx context.Context, bes ...model.Medium) {
 			continue
 		}
 		if be.GetPreviousActionMandate() == model.StopAndRetry {
-			if be.GetRepublishAttempt() == nil || (be.GetRepublishAttempt() != nil && *be.GetRepublishAttempt() >= r.maxAttempts) {
-				// TODO: off to the dead letter queue!
+			if errors.Is(be.GetError(), model.ErrNotRepublished) {
+				// the Republisher runs after every action, but it had its go at the event already
+				continue
+			}
+
+			// events that ran out of time, or came too early, didn't really get their chance
+			deferred := errors.Is(be.GetError(), model.ErrDeadlineExceeded) || errors.Is(be.GetError(), model.ErrTooEarly)
+
+			if !deferred && (be.GetRepublishAttempt() == nil || *be.GetRepublishAttempt() >= r.maxAttempts) {
 				// you had your chance
+				if r.deadLetter == nil {
+					model.LoggerFrom(ctx).Warn("Out of attempts, and there's no dead letter queue to catch the event. It's gone.",
+						"id", be.GetID(), "error", be.GetError())
+					countRepublished(ctx, "dropped", be)
+					// the event keeps its error, but it's dropped only once
+					be.SetPreviousActionMandate(model.StopFurtherProcessing)
+					continue
+				}
+
+				if err := r.sendToDeadLetter(ctx, be); err != nil {
+					bes[i].SetError(fmt.Errorf("dead-letter business event fail: %w", err))
+					continue
+				}
+				countRepublished(ctx, "dead_lettered", be)
+				continue
+			}
+
+			if err := r.republish(ctx, be, deferred); err != nil {
+				bes[i].SetError(fmt.Errorf("execute business service fail: %w: %w", model.ErrNotRepublished, err))
 				continue
 			}
 
-			if err := r.republish(ctx, be); err != nil {
-				bes[i].SetError(fmt.Errorf("execute business service fail: %w", err))
+			outcome := "republished"
+			if deferred {
+				outcome = "deferred"
 			}
+			countRepublished(ctx, outcome, be)
 		}
 	}
 }
 
-func (r *Republish) republish(ctx context.Context, be model.PipelineMedium) error {
+// countRepublished reports how the business event left through the Republisher, tagged with the action it failed at
+func countRepublished(ctx context.Context, outcome string, be model.PipelineMedium) {
+	model.MetricsFrom(ctx).Count(model.MetricRepublished, 1, map[string]string{
+		"outcome": outcome,
+		"action":  be.GetPreviousActionID(),
+	})
+}
+
+func (r *Republish) republish(ctx context.Context, be model.PipelineMedium, deferred bool) (err error) {
 	if r.republisher == nil {
 		return fmt.Errorf("re-publisher is not set for the pipeline")
 	}
 
-	if be.GetRepublishAttempt() != nil {
-		be.IncrementRepublishAttempt()
+	// the business event is left as it was if it doesn't make it back to the queue, its message being redelivered instead
+	attempt, notBefore := be.GetRepublishAttempt(), be.GetNotBefore()
+	defer func() {
+		if err != nil {
+			be.SetRepublishAttempt(attempt)
+			be.SetNotBefore(notBefore)
+			be.SetPreviousActionMandate(model.StopAndRetry)
+		}
+	}()
+
+	if attempt != nil && !deferred {
+		next := *attempt + 1
+		be.SetRepublishAttempt(&next)
 	}
 
+	delay := r.delay(be, deferred)
+
+	// the message goes out with a clean slate, but the business event stays up for a retry until it's back in the queue
 	be.SetPreviousActionMandate(0)
 
 	msg, err := toMessage(be, false)
@@ -78,6 +145,9 @@ func (r *Republish) republish(ctx context.Context, be model.PipelineMedium) erro
 	}
 
 	m["previous_action"] = be.GetPreviousAction()
+	if id := be.GetPreviousActionID(); id != "" {
+		m["previous_action_id"] = id
+	}
 
 	jsn, err := json.Marshal(m)
 	if err != nil {
@@ -86,8 +156,7 @@ func (r *Republish) republish(ctx context.Context, be model.PipelineMedium) erro
 
 	msg.Body = jsn
 
-	// TODO: find a way to wait until picking up this message
-	if err = r.republisher.PublishEvents(ctx, msg); err != nil {
+	if err = r.publish(ctx, msg, delay); err != nil {
 		return fmt.Errorf("re-publish business event '%s' fail: %w", be.GetID(), err)
 	}
 
@@ -108,6 +177,83 @@ func (r *Republish) republish(ctx context.Context, be model.PipelineMedium) erro
 	return nil
 }
 
+// delay returns how long the business event should wait before it's retried, and stamps it with the time it's due
+func (r *Republish) delay(be model.PipelineMedium, deferred bool) time.Duration {
+	if deferred {
+		// the event that came too early keeps waiting for its time, the one that ran out of time doesn't wait at all
+		if wait := be.GetNotBefore().Sub(model.Now()); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+
+	if r.backoff == nil || be.GetRepublishAttempt() == nil {
+		return 0
+	}
+
+	delay := r.backoff.Delay(*be.GetRepublishAttempt())
+	be.SetNotBefore(model.Now().Add(delay))
+
+	return delay
+}
+
+// publish sends the message back to the queue, delayed if the republisher is capable of it
+func (r *Republish) publish(ctx context.Context, msg *model.Message, delay time.Duration) error {
+	if delay <= 0 {
+		return r.republisher.PublishEvents(ctx, msg)
+	}
+
+	if dp, ok := r.republisher.(IDelayedPublisher); ok {
+		return dp.PublishEventsWithDelay(ctx, delay, msg)
+	}
+
+	model.LoggerFrom(ctx).Warn("The republisher can't hold the message back, so it will be deferred again until it's due.",
+		"id", msg.ID, "delay", delay)
+
+	return r.republisher.PublishEvents(ctx, msg)
+}
+
+// sendToDeadLetter publishes the business event that ran out of attempts to the dead letter publisher
+func (r *Republish) sendToDeadLetter(ctx context.Context, be model.PipelineMedium) error {
+	msg, err := toMessage(be, false)
+	if err != nil {
+		return fmt.Errorf("convert business event to message '%s' fail: %w", be.GetID(), err)
+	}
+
+	dl := &model.DeadLetter{
+		ID:             be.GetID(),
+		EventName:      be.GetEventName(),
+		Action:         be.GetPreviousActionName(),
+		ActionIndex:    be.GetPreviousAction(),
+		ActionID:       be.GetPreviousActionID(),
+		Error:          be.GetError().Error(),
+		DeadLetteredAt: model.Now().UTC().Format(time.RFC3339Nano),
+		RawData:        be.GetRawData(),
+		Event:          msg.Body,
+	}
+	if be.GetRepublishAttempt() != nil {
+		dl.Attempts = *be.GetRepublishAttempt()
+	}
+
+	body, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("marshal dead letter '%s' fail: %w", be.GetID(), err)
+	}
+
+	if err = r.deadLetter.PublishEvents(ctx, &model.Message{ID: be.GetID(), Body: body}); err != nil {
+		return fmt.Errorf("publish dead letter '%s' fail: %w", be.GetID(), err)
+	}
+
+	model.LoggerFrom(ctx).Warn("Out of attempts. The event is off to the dead letter queue.",
+		"id", be.GetID(), "attempts", dl.Attempts)
+
+	be.SetError(fmt.Errorf("%w: %w", model.ErrDeadLettered, be.GetError()))
+	// the event is dead-lettered only once
+	be.SetPreviousActionMandate(model.StopFurtherProcessing)
+
+	return nil
+}
+
 func (*Republish) Name() string {
 	return "Republisher"
 }
@@ -0,0 +1,239 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/libs/metrics"
+	"github.com/zale144/ube/model"
+)
+
+func newRetryEvent(id string, attempt int, err error) *model.BusinessEvent {
+	return &model.BusinessEvent{
+		ID:                    id,
+		Event:                 &model.Event{ID: "msg_" + id, Reference: "ref_" + id},
+		Error:                 err,
+		PreviousActionMandate: model.StopAndRetry,
+		RepublishAttempt:      &attempt,
+	}
+}
+
+func TestRepublisher_Good(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rep := NewMockIRepublisher(ctrl)
+	rep.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).Return(nil)
+	rep.EXPECT().AckMessages(gomock.Any(), gomock.Any()).Return(nil)
+
+	be := newRetryEvent("1", 1, errors.New("throttled"))
+
+	Republisher(rep, 3).Process(context.Background(), be)
+
+	require.NotNil(t, be.RepublishAttempt)
+	assert.Equal(t, 2, *be.RepublishAttempt)
+	assert.Equal(t, model.StopFurtherProcessing, be.PreviousActionMandate)
+	assert.Empty(t, be.GetEventID())
+}
+
+func TestRepublisher_MaxAttempts(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rep := NewMockIRepublisher(ctrl)
+	reg := metrics.NewRegistry()
+
+	be := newRetryEvent("1", 3, errors.New("throttled"))
+
+	republisher := Republisher(rep, 3)
+	republisher.Process(model.WithMetrics(context.Background(), reg), be)
+
+	assert.Equal(t, 3, *be.RepublishAttempt)
+	assert.Equal(t, "msg_1", be.GetEventID())
+	assert.Equal(t, model.StopFurtherProcessing, be.PreviousActionMandate)
+	assert.EqualError(t, be.Error, "throttled")
+	assert.NotErrorIs(t, be.Error, model.ErrDeadLettered)
+
+	// the Republisher runs after every action, though the event is dropped only once
+	republisher.Process(model.WithMetrics(context.Background(), reg), be)
+
+	// with no dead letter queue, the event is dropped rather than dead-lettered
+	assert.Equal(t, float64(1), reg.Value(model.MetricRepublished, map[string]string{"outcome": "dropped", "action": ""}))
+	assert.Zero(t, reg.Value(model.MetricRepublished, map[string]string{"outcome": "dead_lettered", "action": ""}))
+}
+
+func TestRepublisher_DeadlineExceeded(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rep := NewMockIRepublisher(ctrl)
+	rep.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).Return(nil)
+	rep.EXPECT().AckMessages(gomock.Any(), gomock.Any()).Return(nil)
+
+	// postponed events don't use up the attempts, even when out of them
+	be := newRetryEvent("1", 3, model.ErrDeadlineExceeded)
+
+	Republisher(rep, 3).Process(context.Background(), be)
+
+	assert.Equal(t, 3, *be.RepublishAttempt)
+	assert.Empty(t, be.GetEventID())
+}
+
+func TestRepublisher_DeadLetter(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var dl model.DeadLetter
+
+	rep := NewMockIRepublisher(ctrl)
+	dlq := NewMockIPublisher(ctrl)
+	dlq.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msgs ...model.Input) error {
+		require.Len(t, msgs, 1)
+		return json.Unmarshal([]byte(msgs[0].GetBody()), &dl)
+	})
+
+	be := newRetryEvent("1", 3, errors.New("throttled"))
+	be.PreviousAction = 2
+	be.PreviousActionName = "Persister"
+	be.RawDataEvent = [][]byte{[]byte(`{"sku":"1"}`)}
+
+	r := Republisher(rep, 3).WithDeadLetter(dlq)
+	r.Process(context.Background(), be)
+
+	assert.Equal(t, "1", dl.ID)
+	assert.Equal(t, "Persister", dl.Action)
+	assert.Equal(t, 2, dl.ActionIndex)
+	assert.Equal(t, 3, dl.Attempts)
+	assert.Equal(t, "throttled", dl.Error)
+	assert.Equal(t, [][]byte{[]byte(`{"sku":"1"}`)}, dl.RawData)
+	assert.NotEmpty(t, dl.Event)
+
+	assert.ErrorIs(t, be.Error, model.ErrDeadLettered)
+	assert.ErrorContains(t, be.Error, "throttled")
+	assert.Equal(t, model.StopFurtherProcessing, be.PreviousActionMandate)
+
+	// the event is dead-lettered only once
+	r.Process(context.Background(), be)
+}
+
+// delayedRepublisher is a republisher capable of delaying the messages
+type delayedRepublisher struct {
+	*MockIRepublisher
+	*MockIDelayedPublisher
+}
+
+func TestRepublisher_Backoff(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	defer func(now func() time.Time) { model.Now = now }(model.Now)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	model.Now = func() time.Time { return now }
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rep := delayedRepublisher{NewMockIRepublisher(ctrl), NewMockIDelayedPublisher(ctrl)}
+	rep.MockIDelayedPublisher.EXPECT().PublishEventsWithDelay(gomock.Any(), 4*time.Second, gomock.Any()).Return(nil)
+	rep.MockIRepublisher.EXPECT().AckMessages(gomock.Any(), gomock.Any()).Return(nil)
+
+	be := newRetryEvent("1", 2, errors.New("throttled"))
+
+	Republisher(rep, 5).WithBackoff(Backoff{Base: time.Second}).Process(context.Background(), be)
+
+	assert.Equal(t, 3, *be.RepublishAttempt)
+	assert.Equal(t, now.Add(4*time.Second), be.GetNotBefore())
+}
+
+func TestRepublisher_AckFail(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	defer func(now func() time.Time) { model.Now = now }(model.Now)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	model.Now = func() time.Time { return now }
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rep := delayedRepublisher{NewMockIRepublisher(ctrl), NewMockIDelayedPublisher(ctrl)}
+	rep.MockIDelayedPublisher.EXPECT().PublishEventsWithDelay(gomock.Any(), 4*time.Second, gomock.Any()).Return(nil)
+	rep.MockIRepublisher.EXPECT().AckMessages(gomock.Any(), gomock.Any()).Return(errors.New("receipt expired"))
+
+	be := newRetryEvent("1", 2, errors.New("throttled"))
+	be.SetNotBefore(now.Add(-time.Second))
+
+	republisher := Republisher(rep, 5).WithBackoff(Backoff{Base: time.Second})
+	republisher.Process(context.Background(), be)
+
+	// the event is left for its message to be redelivered, as it was
+	assert.ErrorIs(t, be.Error, model.ErrNotRepublished)
+	assert.Equal(t, model.StopAndRetry, be.PreviousActionMandate)
+	assert.Equal(t, 2, *be.RepublishAttempt)
+	assert.Equal(t, now.Add(-time.Second), be.GetNotBefore())
+	assert.Equal(t, "msg_1", be.GetEventID())
+
+	// the Republisher runs after every action, though the event is republished only once
+	republisher.Process(context.Background(), be)
+
+	assert.Equal(t, 2, *be.RepublishAttempt)
+}
+
+func TestRepublisher_TooEarly(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	defer func(now func() time.Time) { model.Now = now }(model.Now)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	model.Now = func() time.Time { return now }
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rep := delayedRepublisher{NewMockIRepublisher(ctrl), NewMockIDelayedPublisher(ctrl)}
+	rep.MockIDelayedPublisher.EXPECT().PublishEventsWithDelay(gomock.Any(), 3*time.Second, gomock.Any()).Return(nil)
+	rep.MockIRepublisher.EXPECT().AckMessages(gomock.Any(), gomock.Any()).Return(nil)
+
+	// the early event waits out the rest of its delay, and it's not an attempt
+	be := newRetryEvent("1", 5, model.ErrTooEarly)
+	be.SetNotBefore(now.Add(3 * time.Second))
+
+	Republisher(rep, 5).WithBackoff(Backoff{Base: time.Second}).Process(context.Background(), be)
+
+	assert.Equal(t, 5, *be.RepublishAttempt)
+	assert.Equal(t, now.Add(3*time.Second), be.GetNotBefore())
+}
@@ -7,8 +7,6 @@ import (
 	"errors"
 	"fmt"
 
-	"go.uber.org/zap"
-
 	"github.com/zale144/ube/model"
 )
 
@@ -60,7 +58,7 @@ func (e Publish) Process(ctx context.Context, bes ...model.Medium) {
 			msg *model.Message
 			err error
 		)
-		msg, err = toMessage(be, false)
+		msg, err = toMessage(e.redacted(be), false)
 		if err != nil {
 			be.SetError(fmt.Errorf("convert business event %s to message fail: %w", be.GetID(), err))
 			continue
@@ -80,7 +78,7 @@ func (e Publish) Process(ctx context.Context, bes ...model.Medium) {
 		}
 	}
 
-	zap.L().Info("messages published", zap.Int("size", counter))
+	model.LoggerFrom(ctx).Info("messages published", "size", counter)
 }
 
 // asMsg converts the business event into a message taking a bool to
@@ -112,7 +110,19 @@ func toMessage(be model.Medium, compressed bool) (*model.Message, error) {
 		body = buf.Bytes()
 	}
 
-	return &model.Message{ID: be.GetID(), Body: body}, nil
+	msg := &model.Message{ID: be.GetID(), Body: body}
+
+	// the trace context goes along with the message, for the consumer to continue the trace
+	if tc, ok := be.(model.TraceCarrier); ok {
+		msg.TraceParent = tc.GetTraceParent()
+	}
+
+	// so does the group, for the consumer to keep the order within it
+	if gc, ok := be.(model.GroupCarrier); ok {
+		msg.GroupID = gc.GetGroupID()
+	}
+
+	return msg, nil
 }
 
 func (Publish) Name() string {
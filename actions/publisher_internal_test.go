@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/zale144/ube/libs/redact"
 	"github.com/zale144/ube/model"
 )
 
@@ -116,3 +117,74 @@ func TestPublisher_Good(t *testing.T) {
 
 	assert.NoError(t, be.Error)
 }
+
+func TestPublisher_TraceParent(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	ctx := context.Background()
+	be := &model.BusinessEvent{ID: "BE-12345", Event: &model.Event{}, RawDataEvent: [][]byte{[]byte(`Zale144`)}, TraceParent: traceParent}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := NewMockIPublisher(ctrl)
+	mockPublisher.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msgs ...model.Input) error {
+		msg := msgs[0].(*model.Message)
+		assert.Equal(t, traceParent, msg.TraceParent)
+		assert.Contains(t, msg.GetBody(), `"trace_parent"`)
+		return nil
+	})
+
+	action := Publish{publisher: mockPublisher}
+	action.Process(ctx, be)
+
+	assert.NoError(t, be.Error)
+}
+
+func TestPublisher_Redact(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+	be := &model.BusinessEvent{
+		ID:       "BE-12345",
+		Event:    &model.Event{SensitiveDataFieldNames: []string{"owner"}, Data: map[string]string{"owner": "Jane Doe"}},
+		Entities: []model.Entity{&secretObj{ID: "s-1", Email: "jane"}},
+		RawDataEvent: [][]byte{
+			[]byte(`{"id":"s-1","email":"jane","contacts":[{"Email":"john","owner":"John Doe"}],"note":"jane@example.com"}`),
+			[]byte(`from jane@example.com`),
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := NewMockIPublisher(ctrl)
+	mockPublisher.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msgs ...model.Input) error {
+		var published struct {
+			Event        model.Event `json:"event"`
+			RawDataEvent [][]byte    `json:"raw_data_event"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(msgs[0].GetBody()), &published))
+		assert.Equal(t, "****", published.Event.Data["owner"])
+		// the raw JSON fields are redacted by the sensitive field names and tags, and the rest by the rules
+		assert.JSONEq(t, `{"id":"s-1","email":"****","contacts":[{"Email":"****","owner":"****"}],"note":"****"}`,
+			string(published.RawDataEvent[0]))
+		assert.Equal(t, `from ****`, string(published.RawDataEvent[1]))
+		return nil
+	})
+
+	action := Publisher(mockPublisher, Redact(redact.Default()))
+	action.Process(ctx, be)
+
+	assert.NoError(t, be.Error)
+	assert.Equal(t, "Jane Doe", be.Event.Data["owner"])
+	assert.Contains(t, string(be.RawDataEvent[0]), `"owner":"John Doe"`)
+}
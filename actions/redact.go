@@ -0,0 +1,27 @@
+package actions
+
+import (
+	"github.com/zale144/ube/libs/redact"
+	"github.com/zale144/ube/model"
+)
+
+// Redact makes the Publisher redact the sensitive data out of the business events before they're published,
+// for the consumers that have no business seeing it. The business events themselves are left as they are
+func Redact(r *redact.Redactor) BaseOption {
+	return func(a *Base) {
+		a.redactor = r
+	}
+}
+
+// redacted returns a copy of the business event with its sensitive data redacted, if the action redacts it
+func (a Base) redacted(be model.Medium) model.Medium {
+	if a.redactor == nil {
+		return be
+	}
+
+	if cp, ok := model.Redact(a.redactor, be).(model.Medium); ok {
+		return cp
+	}
+
+	return be
+}
@@ -0,0 +1,42 @@
+package actions
+
+import "errors"
+
+// RetryPolicy makes the pipeline retry the business events that failed at an action within the same invocation,
+// before the failure mandate of the action takes over
+type RetryPolicy struct {
+	// Attempts is how many times a business event is processed at most, the first time included
+	Attempts int
+	// Backoff is how long to wait before each retry
+	Backoff Backoff
+	// Retryable decides which errors are worth another attempt, all of them if it's not set
+	Retryable func(err error) bool
+}
+
+// Retry makes the pipeline retry the business events that failed at the action by the policy
+func Retry(policy RetryPolicy) BaseOption {
+	return func(a *Base) {
+		a.retry = &policy
+	}
+}
+
+// RetryOn returns a classifier that finds the errors retryable if they are, or wrap, any of the errs
+func RetryOn(errs ...error) func(err error) bool {
+	return func(err error) bool {
+		for _, e := range errs {
+			if errors.Is(err, e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsRetryable tells whether the error is worth another attempt
+func (p RetryPolicy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return p.Retryable == nil || p.Retryable(err)
+}
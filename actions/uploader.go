@@ -5,8 +5,6 @@ import (
 	"context"
 	"fmt"
 
-	"go.uber.org/zap"
-
 	"github.com/zale144/ube/model"
 )
 
@@ -54,13 +52,13 @@ func (e Upload) Process(ctx context.Context, bes ...model.Medium) {
 				break
 			}
 
-			zap.L().Info("upload", zap.String("file", key))
+			model.LoggerFrom(ctx).Info("upload", "file", key)
 
 			counter++
 		}
 	}
 
-	zap.L().Info("files uploaded", zap.Int("size", counter))
+	model.LoggerFrom(ctx).Info("files uploaded", "size", counter)
 }
 
 func (e Upload) DepCallNames() []string {
@@ -10,18 +10,32 @@ import (
 	"github.com/zale144/ube/libs/aws/lambda"
 	"github.com/zale144/ube/libs/aws/s3"
 	"github.com/zale144/ube/libs/aws/sqs"
+	"github.com/zale144/ube/libs/config"
 )
 
+type Config struct {
+	Region       string `lookup:"AWS_REGION"`
+	UploadBucket string `lookup:"S3_UPLOAD_BUCKET"`
+	CarTable     string `lookup:"DB_CAR_TABLE_NAME"`
+	BqQueueURL   string `lookup:"SQS_BQ_QUEUE_URL"`
+	QueueURL     string `lookup:"SQS_QUEUE_URL"`
+}
+
 func main() {
 	logger, _ := zap.NewProduction()
 	defer func() { _ = logger.Sync() }()
 	undo := zap.ReplaceGlobals(logger)
 	defer undo()
 
-	uploader := s3.NewUploaderFromEnv("AWS_REGION", "S3_UPLOAD_BUCKET")
-	carRepo := dynamodb.NewDynamoDBFromEnv("DB_CAR_TABLE_NAME")
-	publisher := sqs.NewQueueFromEnv("SQS_BQ_QUEUE_URL")
-	ack := sqs.NewQueueFromEnv("SQS_QUEUE_URL")
+	var cfg Config
+	if err := config.Load(&cfg); err != nil {
+		logger.Fatal("The car lambda is missing a few parts", zap.Error(err))
+	}
+
+	uploader := s3.NewUploader(cfg.Region, cfg.UploadBucket)
+	carRepo := dynamodb.NewDynamoDB(cfg.CarTable)
+	publisher := sqs.NewQueue(cfg.BqQueueURL)
+	ack := sqs.NewQueue(cfg.QueueURL)
 
 	carMdl := &car.UBEModel{}
 	pl := carMdl.Pipeline(uploader, carRepo, publisher)
@@ -10,20 +10,36 @@ import (
 	"github.com/zale144/ube/libs/aws/lambda"
 	"github.com/zale144/ube/libs/aws/s3"
 	"github.com/zale144/ube/libs/aws/sqs"
+	"github.com/zale144/ube/libs/config"
 )
 
+type Config struct {
+	Region         string `lookup:"AWS_REGION"`
+	UploadBucket   string `lookup:"S3_UPLOAD_BUCKET"`
+	DownloadBucket string `lookup:"S3_DOWNLOAD_BUCKET"`
+	ProductTable   string `lookup:"DB_PRODUCT_TABLE_NAME"`
+	StoreTable     string `lookup:"DB_STORE_TABLE_NAME"`
+	BqQueueURL     string `lookup:"SQS_BQ_QUEUE_URL"`
+	QueueURL       string `lookup:"SQS_QUEUE_URL"`
+}
+
 func main() {
 	logger, _ := zap.NewProduction()
 	defer func() { _ = logger.Sync() }()
 	undo := zap.ReplaceGlobals(logger)
 	defer undo()
 
-	uploader := s3.NewUploaderFromEnv("AWS_REGION", "S3_UPLOAD_BUCKET")
-	downloader := s3.NewDownloaderFromEnv("AWS_REGION", "S3_DOWNLOAD_BUCKET")
-	prodRepo := dynamodb.NewDynamoDBFromEnv("DB_PRODUCT_TABLE_NAME")
-	storeRepo := dynamodb.NewDynamoDBFromEnv("DB_STORE_TABLE_NAME")
-	publisher := sqs.NewQueueFromEnv("SQS_BQ_QUEUE_URL")
-	queue := sqs.NewQueueFromEnv("SQS_QUEUE_URL")
+	var cfg Config
+	if err := config.Load(&cfg); err != nil {
+		logger.Fatal("The product lambda came without assembly instructions", zap.Error(err))
+	}
+
+	uploader := s3.NewUploader(cfg.Region, cfg.UploadBucket)
+	downloader := s3.NewDownloader(cfg.Region, cfg.DownloadBucket)
+	prodRepo := dynamodb.NewDynamoDB(cfg.ProductTable)
+	storeRepo := dynamodb.NewDynamoDB(cfg.StoreTable)
+	publisher := sqs.NewQueue(cfg.BqQueueURL)
+	queue := sqs.NewQueue(cfg.QueueURL)
 
 	prod := &product.Product{}
 	pl := prod.Pipeline(uploader, downloader, prodRepo, storeRepo, publisher, queue)
@@ -10,18 +10,31 @@ import (
 	"github.com/zale144/ube/libs/aws/lambda"
 	"github.com/zale144/ube/libs/aws/s3"
 	"github.com/zale144/ube/libs/aws/sqs"
+	"github.com/zale144/ube/libs/config"
 )
 
+type Config struct {
+	S3                  s3.Config
+	WarehouseStockTable string `lookup:"DB_WAREHOUSESTOCK_TABLE_NAME"`
+	BqQueueURL          string `lookup:"SQS_BQ_QUEUE_URL"`
+	Queue               sqs.Config
+}
+
 func main() {
 	logger, _ := zap.NewProduction()
 	defer func() { _ = logger.Sync() }()
 	undo := zap.ReplaceGlobals(logger)
 	defer undo()
 
-	uploader := s3.NewUploaderFromEnv("AWS_REGION", "S3_BUCKET")
-	warehouseStockRepo := dynamodb.NewDynamoDBFromEnv("DB_WAREHOUSESTOCK_TABLE_NAME")
-	publisher := sqs.NewQueueFromEnv("SQS_BQ_QUEUE_URL")
-	queue := sqs.NewQueueFromEnv("SQS_QUEUE_URL")
+	var cfg Config
+	if err := config.Load(&cfg); err != nil {
+		logger.Fatal("The warehouse stock lambda can't find its stock of config", zap.Error(err))
+	}
+
+	uploader := s3.NewUploaderFromConfig(cfg.S3)
+	warehouseStockRepo := dynamodb.NewDynamoDB(cfg.WarehouseStockTable)
+	publisher := sqs.NewQueue(cfg.BqQueueURL)
+	queue := sqs.NewQueueFromConfig(cfg.Queue)
 
 	whs := &warehousestock.UBEModel{}
 	pl := whs.Pipeline(uploader, warehouseStockRepo, publisher, queue)
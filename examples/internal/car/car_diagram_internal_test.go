@@ -0,0 +1,22 @@
+//go:build generatediagrams
+
+package car
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+/*
+Regenerates the pipeline activity diagram from the actual pipeline:
+
+	go test -tags generatediagrams -run TestCarDiagramGenerate ./examples/internal/car
+*/
+func TestCarDiagramGenerate(t *testing.T) {
+	entity := &UBEModel{}
+	d := entity.Pipeline(nil, nil, nil).Describe()
+
+	require.NoError(t, os.WriteFile("example pipeline activity diagram.puml", []byte(d.PlantUML()), 0o644))
+}
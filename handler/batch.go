@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/zale144/ube/model"
+	pl "github.com/zale144/ube/pipeline"
+)
+
+// BatchHandler is an event handler that reports the messages that failed, instead of acknowledging the ones that
+// didn't. The queue then deletes the rest of the messages on its own, and redelivers the failed ones
+type BatchHandler struct {
+	pipeline iPipeline
+	result   pl.EventProcessingResult
+}
+
+// NewBatchHandler creates a batch event handler built with the injected dependencies
+func NewBatchHandler(pipeline iPipeline) BatchHandler {
+	return BatchHandler{pipeline: pipeline}
+}
+
+// Handle handles an event and returns the IDs of the messages that failed and should be redelivered
+func (p *BatchHandler) Handle(ctx context.Context, ev *model.InputEvent) ([]string, error) {
+	ins := ev.Inputs()
+
+	resPre, err := p.pipeline.InvokePipeline(ctx, ins...)
+	if err != nil {
+		model.LoggerFrom(ctx).Error("error while processing record", "error", err)
+	}
+
+	p.result = resPre
+
+	// the messages skipped as duplicates or in flight never became business events
+	_, ins = splitInputs(ins, append(append([]string{}, resPre.Duplicates...), resPre.InFlight...))
+
+	if len(resPre.BusinessEvents) == 0 && err != nil {
+		// the inputs didn't even make it into the pipeline, so all of them get another chance
+		failed := make([]string, 0, len(ins)+len(resPre.InFlight))
+		for _, in := range ins {
+			failed = append(failed, in.GetID())
+		}
+		return append(failed, resPre.InFlight...), nil
+	}
+
+	failed := failedMessages(ins, resPre.BusinessEvents)
+	// the ones in flight are redelivered, in case the invocation processing them doesn't make it
+	failed = append(failed, resPre.InFlight...)
+	if len(failed) > 0 {
+		model.LoggerFrom(ctx).Info("Some messages are going back to the queue for another round.", "failed", len(failed))
+	}
+
+	return failed, nil
+}
+
+func (p BatchHandler) GetResult() pl.EventProcessingResult {
+	return p.result
+}
+
+// failedMessages maps the business events that should be redelivered back to the IDs of their messages.
+// Every input makes a single business event, so they're matched by their position, or by the event ID otherwise
+func failedMessages(ins []model.Input, bes []model.PipelineMedium) []string {
+	var (
+		failed     []string
+		positional = len(ins) == len(bes)
+	)
+
+	for i, be := range bes {
+		if !redeliverable(be) {
+			continue
+		}
+
+		id := be.GetEventID()
+		if positional {
+			id = ins[i].GetID()
+		}
+
+		if id != "" {
+			failed = append(failed, id)
+		}
+	}
+
+	return failed
+}
+
+// redeliverable tells whether the business event failed in a way that asks for a retry or for the error to be raised.
+// The events that were republished, parked, dead-lettered or alerted about were taken care of already, and the ones
+// that failed an action meant to be logged, or skipped up to the critical actions, were never meant to be retried.
+// Everything else that failed, like the event that couldn't be republished, goes back to the queue
+func redeliverable(be model.PipelineMedium) bool {
+	if be.GetError() == nil {
+		return false
+	}
+
+	switch be.GetPreviousActionMandate() {
+	case model.StopFurtherProcessing, model.LogFailureAndContinue, model.ProcessOnlyCriticalActions:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+	pl "github.com/zale144/ube/pipeline"
+)
+
+func TestBatchHandler_Handle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	failedWith := func(mandate model.ActionMandate) *model.BusinessEvent {
+		return &model.BusinessEvent{Event: &model.Event{}, Error: errors.New("boom"), PreviousActionMandate: mandate}
+	}
+
+	mp := NewMockPipeline(ctrl)
+	mp.EXPECT().InvokePipeline(gomock.Any(), gomock.Any()).Return(pl.EventProcessingResult{
+		BusinessEvents: []model.PipelineMedium{
+			&model.BusinessEvent{Event: &model.Event{}},
+			failedWith(model.StopAndRetry),
+			failedWith(model.StopAndRaiseError),
+			// republished, parked, dead-lettered or alerted about
+			failedWith(model.StopFurtherProcessing),
+		},
+	}, errors.New("boom"))
+
+	h := NewBatchHandler(mp)
+
+	ev := model.NewInputEvent([]model.Input{
+		&model.Message{ID: "1"}, &model.Message{ID: "2"}, &model.Message{ID: "3"}, &model.Message{ID: "4"},
+	})
+
+	failed, err := h.Handle(context.Background(), ev)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, failed)
+}
+
+func TestBatchHandler_Handle_invalid_inputs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mp := NewMockPipeline(ctrl)
+	mp.EXPECT().InvokePipeline(gomock.Any(), gomock.Any()).Return(pl.EventProcessingResult{}, errors.New("bad inputs"))
+
+	h := NewBatchHandler(mp)
+
+	failed, err := h.Handle(context.Background(), model.NewInputEvent([]model.Input{&model.Message{ID: "1"}, &model.Message{ID: "2"}}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, failed)
+}
+
+func TestBatchHandler_Handle_skipped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mp := NewMockPipeline(ctrl)
+	mp.EXPECT().InvokePipeline(gomock.Any(), gomock.Any()).Return(pl.EventProcessingResult{
+		Duplicates: []string{"1"},
+		InFlight:   []string{"3"},
+		BusinessEvents: []model.PipelineMedium{
+			&model.BusinessEvent{Event: &model.Event{}, Error: errors.New("boom"), PreviousActionMandate: model.StopAndRetry},
+			&model.BusinessEvent{Event: &model.Event{}},
+		},
+	}, errors.New("boom"))
+
+	h := NewBatchHandler(mp)
+
+	ev := model.NewInputEvent([]model.Input{
+		&model.Message{ID: "1"}, &model.Message{ID: "2"}, &model.Message{ID: "3"}, &model.Message{ID: "4"},
+	})
+
+	failed, err := h.Handle(context.Background(), ev)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, failed)
+}
+
+type failingAction struct {
+	actions.Base
+	err error
+}
+
+func (a failingAction) Name() string           { return "failing" }
+func (a failingAction) DepCallNames() []string { return nil }
+
+func (a failingAction) Process(_ context.Context, bes ...model.Medium) {
+	for _, be := range bes {
+		be.SetError(a.err)
+	}
+}
+
+func TestBatchHandler_Handle_republish_fail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	act := failingAction{err: fmt.Errorf("out of time: %w", model.ErrDeadlineExceeded)}
+	actions.FailureMandate(model.StopAndRetry)(&act.Base)
+
+	rp := actions.NewMockIRepublisher(ctrl)
+	// the Republisher runs after both actions, though it tries every event only once
+	rp.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).Return(errors.New("queue is down")).Times(2)
+
+	next := failingAction{}
+	actions.Label("next")(&next.Base)
+
+	p := pl.NewPipeline(&Model{}, pl.Action(act), pl.Action(next), pl.Republisher(rp, 3))
+
+	h := NewBatchHandler(p)
+
+	ev := model.NewInputEvent([]model.Input{
+		&model.Message{ID: "1", Body: json.RawMessage("{}")}, &model.Message{ID: "2", Body: json.RawMessage("{}")},
+	})
+
+	failed, err := h.Handle(context.Background(), ev)
+	require.NoError(t, err)
+	// the events that didn't make it back to the queue are not lost
+	assert.Equal(t, []string{"1", "2"}, failed)
+
+	for _, be := range h.GetResult().BusinessEvents {
+		assert.Equal(t, model.StopAndRetry, be.GetPreviousActionMandate())
+	}
+}
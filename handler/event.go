@@ -6,7 +6,6 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/go-multierror"
-	"go.uber.org/zap"
 
 	"github.com/zale144/ube/actions"
 	"github.com/zale144/ube/model"
@@ -18,6 +17,8 @@ type EventHandler struct {
 	pipeline iPipeline
 	acker    actions.IAcker
 	result   pl.EventProcessingResult
+	metrics  model.Metrics
+	logger   model.Logger
 }
 
 // NewEventHandler creates an event handler built with the injected dependencies
@@ -28,16 +29,36 @@ func NewEventHandler(pipeline iPipeline, acker actions.IAcker) EventHandler {
 	}
 }
 
+// WithMetrics makes the handler report the messages it acknowledges. Otherwise, they're reported
+// to the metrics of the context, if there are any
+func (p *EventHandler) WithMetrics(metrics model.Metrics) *EventHandler {
+	p.metrics = metrics
+	return p
+}
+
+// WithLogger makes the handler, and the pipeline it invokes, log through the logger,
+// unless the pipeline was given its own
+func (p *EventHandler) WithLogger(logger model.Logger) *EventHandler {
+	p.logger = logger
+	return p
+}
+
 // Handle handles an event by iterating over its messages
 func (p *EventHandler) Handle(ctx context.Context, ev *model.InputEvent) error {
+	if p.logger != nil {
+		ctx = model.WithLogger(ctx, p.logger)
+	}
+
 	var (
 		ackMsgs   []model.Input
 		resultErr error
 	)
 
-	resPre, err := p.pipeline.InvokePipeline(ctx, ev.Inputs()...)
+	ins := ev.Inputs()
+
+	resPre, err := p.pipeline.InvokePipeline(ctx, ins...)
 	if err != nil {
-		zap.L().Error("error while processing record", zap.Error(err))
+		model.LoggerFrom(ctx).Error("error while processing record", "error", err)
 	}
 
 	p.result = resPre
@@ -45,7 +66,7 @@ func (p *EventHandler) Handle(ctx context.Context, ev *model.InputEvent) error {
 	for _, e := range resPre.Errors {
 		// TODO: make test for this
 		resultErr = multierror.Append(resultErr, errors.New(e))
-		zap.L().Error("error while processing record", zap.String("error", e))
+		model.LoggerFrom(ctx).Error("error while processing record", "error", e)
 	}
 
 	// TODO: do we always ack messages, or just when we have a retry mechanism, or when there is no point?
@@ -60,15 +81,54 @@ func (p *EventHandler) Handle(ctx context.Context, ev *model.InputEvent) error {
 		ackMsgs = append(ackMsgs, msg)
 	}
 
+	// the duplicates were processed already, so they only need to leave the queue
+	duplicates, _ := splitInputs(ins, resPre.Duplicates)
+	for _, in := range duplicates {
+		ackMsgs = append(ackMsgs, &model.Message{ID: in.GetID(), Reference: in.GetReference()})
+	}
+
 	if len(ackMsgs) > 0 {
 		if err = p.acker.AckMessages(ctx, ackMsgs...); err != nil {
+			p.countAcks(ctx, "failed", len(ackMsgs))
 			return fmt.Errorf("acknowledge message fail: %w", err)
 		}
+		p.countAcks(ctx, "acked", len(ackMsgs))
 	}
 
 	return resultErr
 }
 
+func (p *EventHandler) countAcks(ctx context.Context, status string, n int) {
+	m := model.MetricsFrom(ctx)
+	if p.metrics != nil {
+		m = p.metrics
+	}
+
+	m.Count(model.MetricAcks, float64(n), map[string]string{"status": status})
+}
+
 func (p EventHandler) GetResult() pl.EventProcessingResult {
 	return p.result
 }
+
+// splitInputs splits the inputs into the ones with the given IDs and the rest
+func splitInputs(ins []model.Input, ids []string) (matched, rest []model.Input) {
+	if len(ids) == 0 {
+		return nil, ins
+	}
+
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+
+	for _, in := range ins {
+		if _, ok := set[in.GetID()]; ok {
+			matched = append(matched, in)
+			continue
+		}
+		rest = append(rest, in)
+	}
+
+	return matched, rest
+}
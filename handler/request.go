@@ -7,7 +7,6 @@ import (
 	"net/http"
 
 	"github.com/hashicorp/go-multierror"
-	"go.uber.org/zap"
 
 	"github.com/zale144/ube/model"
 	pl "github.com/zale144/ube/pipeline"
@@ -36,7 +35,7 @@ func NewRequestHandler(pipeline iPipeline, outputTransform APIOUTTransform) Requ
 func (p RequestHandler) Handle(ctx context.Context, req *model.Request) (*model.Response, error) {
 	resPre, err := p.pipeline.InvokePipeline(ctx, req)
 	if err != nil {
-		zap.L().Error("error while processing record", zap.Error(err))
+		model.LoggerFrom(ctx).Error("error while processing record", "error", err)
 		err = fmt.Errorf("process record fail: %w", err)
 		return &model.Response{
 			StatusCode: http.StatusInternalServerError,
@@ -48,9 +47,9 @@ func (p RequestHandler) Handle(ctx context.Context, req *model.Request) (*model.
 	var resultErr error
 	for _, e := range resPre.Errors {
 		resultErr = multierror.Append(resultErr, errors.New(e))
-		zap.L().Error("error while processing record",
-			zap.String("id", req.GetID()),
-			zap.String("error", e))
+		model.LoggerFrom(ctx).Error("error while processing record",
+			"id", req.GetID(),
+			"error", e)
 	}
 
 	resp, err := p.outputTransform(&resPre)
@@ -0,0 +1,3 @@
+// Package alert provides the sinks that the pipeline Alerter raises the
+// alerts about the failed business events to.
+package alert
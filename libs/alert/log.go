@@ -0,0 +1,33 @@
+package alert
+
+import (
+	"context"
+
+	"github.com/zale144/ube/model" // TODO: decouple
+)
+
+// Log writes the alerts to the log, one error entry per alert
+type Log struct{}
+
+// NewLog creates a new log sink
+func NewLog() Log {
+	return Log{}
+}
+
+// Alert logs the alerts
+func (Log) Alert(ctx context.Context, alerts ...*model.Alert) error {
+	for _, a := range alerts {
+		ids := make([]string, len(a.Events))
+		for i, ev := range a.Events {
+			ids[i] = ev.ID
+		}
+
+		model.LoggerFrom(ctx).Error("ALERT: failed to execute action",
+			"action", a.Action,
+			"action index", a.ActionIndex,
+			"error", a.Error,
+			"business events", ids)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zale144/ube/model" // TODO: decouple
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Webhook posts the alerts as a JSON array to a URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a new webhook sink posting to the URL
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: defaultTimeout}}
+}
+
+// Alert posts the alerts in a single request
+func (w *Webhook) Alert(ctx context.Context, alerts ...*model.Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("marshal alerts fail: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request fail: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post alerts fail: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("post alerts fail: webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
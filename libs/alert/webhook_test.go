@@ -0,0 +1,40 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zale144/ube/model"
+)
+
+func TestWebhook_Alert(t *testing.T) {
+	var received []*model.Alert
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	alert := &model.Alert{Action: "Persister", Error: "boom", Events: []model.AlertedEvent{{ID: "1"}}}
+
+	require.NoError(t, NewWebhook(srv.URL).Alert(context.Background(), alert))
+	assert.Equal(t, []*model.Alert{alert}, received)
+}
+
+func TestWebhook_Alert_rejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := NewWebhook(srv.URL).Alert(context.Background(), &model.Alert{})
+	assert.ErrorContains(t, err, "500")
+}
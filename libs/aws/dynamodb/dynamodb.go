@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -13,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 
+	"github.com/zale144/ube/libs/config"
 	"github.com/zale144/ube/model" // TODO: decouple
 )
 
@@ -30,6 +29,11 @@ const (
 	TTLKey       = "ttl"
 )
 
+// Config is the configuration of the repository, to be loaded with config.Load.
+type Config struct {
+	TableName string `lookup:"DB_TABLE_NAME"`
+}
+
 // DynamoDB defines the repository for storing DynamoDB information.
 type DynamoDB struct {
 	db        dynamoDB
@@ -43,14 +47,20 @@ func NewDynamoDB(tableName string) DynamoDB {
 	return DynamoDB{db: db, tableName: tableName}
 }
 
-// NewDynamoDBFromEnv creates a new entity dynamoDB repository.
-func NewDynamoDBFromEnv(tableNameEnv string) DynamoDB {
-	tableName := os.Getenv(tableNameEnv)
-	if tableName == "" {
-		log.Fatalf("DynamoDB: environment variable '%s' is not set", tableNameEnv)
+// NewDynamoDBFromEnv creates a new entity dynamoDB repository, with the table name from the env var.
+// It returns an error, rather than exiting, if the env var is not set
+func NewDynamoDBFromEnv(tableNameEnv string) (DynamoDB, error) {
+	values, err := config.Values(config.Env(), tableNameEnv)
+	if err != nil {
+		return DynamoDB{}, fmt.Errorf("DynamoDB: %w", err)
 	}
 
-	return NewDynamoDB(tableName)
+	return NewDynamoDB(values[0]), nil
+}
+
+// NewDynamoDBFromConfig creates a new entity dynamoDB repository, with the table name from the config.
+func NewDynamoDBFromConfig(cfg Config) DynamoDB {
+	return NewDynamoDB(cfg.TableName)
 }
 
 // NewDynamoDBWithTable creates a new entity table with the given dependencies.
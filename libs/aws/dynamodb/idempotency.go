@@ -0,0 +1,109 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/zale144/ube/model" // TODO: decouple
+)
+
+const (
+	idempotencyKey      = "id"
+	idempotencyStateKey = "state"
+)
+
+// IdempotencyStore keeps the states of the messages in a DynamoDB table, keyed by their 'id'. The 'ttl' should be set
+// as the TTL attribute of the table, so that DynamoDB removes the expired records
+type IdempotencyStore struct {
+	db        dynamoDB
+	tableName string
+}
+
+// NewIdempotencyStore creates a new DynamoDB idempotency store.
+func NewIdempotencyStore(tableName string) IdempotencyStore {
+	db := dynamodb.New(session.Must(session.NewSession()), aws.NewConfig())
+
+	return IdempotencyStore{db: db, tableName: tableName}
+}
+
+// Claim marks the message as in progress, unless it's known already, in which case its state is returned.
+// The condition makes sure only one of the concurrent claims succeeds
+func (s IdempotencyStore) Claim(ctx context.Context, key string, ttl time.Duration) (model.IdempotencyState, error) {
+	now := model.Now()
+
+	_, err := s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      idempotencyItem(key, model.IdempotencyInProgress, now.Add(ttl)),
+		// DynamoDB removes the expired records eventually, so they're checked here too
+		ConditionExpression:      aws.String("attribute_not_exists(#id) OR #ttl < :now"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String(idempotencyKey), "#ttl": aws.String(TTLKey)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+	})
+	if err == nil {
+		return model.IdempotencyNew, nil
+	}
+
+	var aErr awserr.Error
+	if !errors.As(err, &aErr) || aErr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		return "", fmt.Errorf("claim message '%s' fail: %w", key, err)
+	}
+
+	out, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName),
+		Key:            map[string]*dynamodb.AttributeValue{idempotencyKey: {S: aws.String(key)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("get message '%s' state fail: %w", key, err)
+	}
+
+	state, ok := out.Item[idempotencyStateKey]
+	if !ok || state.S == nil {
+		// the record was released in the meantime, so it's in progress as far as this claim goes
+		return model.IdempotencyInProgress, nil
+	}
+
+	return model.IdempotencyState(*state.S), nil
+}
+
+// Complete marks the message as processed successfully
+func (s IdempotencyStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	if _, err := s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      idempotencyItem(key, model.IdempotencyCompleted, model.Now().Add(ttl)),
+	}); err != nil {
+		return fmt.Errorf("complete message '%s' fail: %w", key, err)
+	}
+
+	return nil
+}
+
+// Release removes the message from the table, so that it can be claimed again
+func (s IdempotencyStore) Release(ctx context.Context, key string) error {
+	if _, err := s.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key:       map[string]*dynamodb.AttributeValue{idempotencyKey: {S: aws.String(key)}},
+	}); err != nil {
+		return fmt.Errorf("release message '%s' fail: %w", key, err)
+	}
+
+	return nil
+}
+
+func idempotencyItem(key string, state model.IdempotencyState, expiresAt time.Time) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		idempotencyKey:      {S: aws.String(key)},
+		idempotencyStateKey: {S: aws.String(string(state))},
+		TTLKey:              {N: aws.String(strconv.FormatInt(expiresAt.Unix(), 10))},
+	}
+}
@@ -0,0 +1,52 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zale144/ube/model"
+)
+
+func TestIdempotencyStore_Claim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conflict := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "taken", nil)
+
+	d := NewMockdynamoDB(ctrl)
+	gomock.InOrder(
+		d.EXPECT().PutItemWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ aws.Context, in *dynamodb.PutItemInput, _ ...interface{}) (*dynamodb.PutItemOutput, error) {
+				assert.Equal(t, string(model.IdempotencyInProgress), *in.Item["state"].S)
+				assert.NotNil(t, in.ConditionExpression)
+				return &dynamodb.PutItemOutput{}, nil
+			}),
+		d.EXPECT().PutItemWithContext(gomock.Any(), gomock.Any()).Return(nil, conflict),
+		d.EXPECT().GetItemWithContext(gomock.Any(), gomock.Any()).Return(&dynamodb.GetItemOutput{
+			Item: idempotencyItem("1", model.IdempotencyCompleted, time.Now()),
+		}, nil),
+		d.EXPECT().PutItemWithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("throttled")),
+	)
+
+	s := IdempotencyStore{db: d, tableName: "idempotency"}
+
+	state, err := s.Claim(context.Background(), "1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, model.IdempotencyNew, state)
+
+	state, err = s.Claim(context.Background(), "1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, model.IdempotencyCompleted, state)
+
+	_, err = s.Claim(context.Background(), "1", time.Minute)
+	assert.Error(t, err)
+}
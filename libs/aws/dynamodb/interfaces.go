@@ -30,4 +30,19 @@ type dynamoDB interface {
 		*dynamodb.CreateTableInput,
 		...request.Option,
 	) (*dynamodb.CreateTableOutput, error)
+	PutItemWithContext(
+		aws.Context,
+		*dynamodb.PutItemInput,
+		...request.Option,
+	) (*dynamodb.PutItemOutput, error)
+	ScanWithContext(
+		aws.Context,
+		*dynamodb.ScanInput,
+		...request.Option,
+	) (*dynamodb.ScanOutput, error)
+	DeleteItemWithContext(
+		aws.Context,
+		*dynamodb.DeleteItemInput,
+		...request.Option,
+	) (*dynamodb.DeleteItemOutput, error)
 }
@@ -0,0 +1,126 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+
+	"github.com/zale144/ube/model" // TODO: decouple
+)
+
+const parkIDKey = "id"
+
+// ParkStore keeps the parked business events in a DynamoDB table, keyed by their 'id'
+type ParkStore struct {
+	db        dynamoDB
+	tableName string
+}
+
+// NewParkStore creates a new DynamoDB park store.
+func NewParkStore(tableName string) ParkStore {
+	db := dynamodb.New(session.Must(session.NewSession()), aws.NewConfig())
+
+	return ParkStore{db: db, tableName: tableName}
+}
+
+// Park stores the parked business events, replacing the ones with the same ID
+func (s ParkStore) Park(ctx context.Context, events ...*model.ParkedEvent) error {
+	for _, ev := range events {
+		item, err := dynamodbattribute.MarshalMap(ev)
+		if err != nil {
+			return fmt.Errorf("marshal parked event '%s' fail: %w", ev.ID, err)
+		}
+
+		if _, err = s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(s.tableName),
+			Item:      item,
+		}); err != nil {
+			return fmt.Errorf("put parked event '%s' fail: %w", ev.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Parked returns all the parked business events, the ones parked first coming first
+func (s ParkStore) Parked(ctx context.Context) ([]*model.ParkedEvent, error) {
+	var (
+		parked []*model.ParkedEvent
+		start  map[string]*dynamodb.AttributeValue
+	)
+
+	for {
+		out, err := s.db.ScanWithContext(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(s.tableName),
+			ExclusiveStartKey: start,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan parked events fail: %w", err)
+		}
+
+		var page []*model.ParkedEvent
+		if err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, fmt.Errorf("unmarshal parked events fail: %w", err)
+		}
+		parked = append(parked, page...)
+
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		start = out.LastEvaluatedKey
+	}
+
+	// a scan doesn't keep any order
+	sort.SliceStable(parked, func(i, j int) bool {
+		return parked[i].ParkedAt < parked[j].ParkedAt
+	})
+
+	return parked, nil
+}
+
+// GetParked returns the parked business event
+func (s ParkStore) GetParked(ctx context.Context, id string) (*model.ParkedEvent, error) {
+	out, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key:       parkKey(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get parked event '%s' fail: %w", id, err)
+	}
+
+	if out.Item == nil {
+		return nil, fmt.Errorf("'%s': %w", id, model.ErrNotParked)
+	}
+
+	ev := &model.ParkedEvent{}
+	if err = dynamodbattribute.UnmarshalMap(out.Item, ev); err != nil {
+		return nil, fmt.Errorf("unmarshal parked event '%s' fail: %w", id, err)
+	}
+
+	return ev, nil
+}
+
+// Unpark removes the business events from the table
+func (s ParkStore) Unpark(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if _, err := s.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(s.tableName),
+			Key:       parkKey(id),
+		}); err != nil {
+			return fmt.Errorf("delete parked event '%s' fail: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+func parkKey(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		parkIDKey: {S: aws.String(id)},
+	}
+}
@@ -0,0 +1,66 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zale144/ube/model"
+)
+
+func parkedItem(id, parkedAt string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":        {S: aws.String(id)},
+		"parked_at": {S: aws.String(parkedAt)},
+		"event":     {B: []byte(`{}`)},
+	}
+}
+
+func TestParkStore_Parked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	d := NewMockdynamoDB(ctrl)
+	gomock.InOrder(
+		d.EXPECT().ScanWithContext(gomock.Any(), gomock.Any()).Return(&dynamodb.ScanOutput{
+			Items:            []map[string]*dynamodb.AttributeValue{parkedItem("2", "2026-01-02T00:00:00Z")},
+			LastEvaluatedKey: parkKey("2"),
+		}, nil),
+		d.EXPECT().ScanWithContext(gomock.Any(), gomock.Any()).Return(&dynamodb.ScanOutput{
+			Items: []map[string]*dynamodb.AttributeValue{parkedItem("1", "2026-01-01T00:00:00Z")},
+		}, nil),
+	)
+
+	s := ParkStore{db: d, tableName: "parked"}
+
+	parked, err := s.Parked(context.Background())
+	require.NoError(t, err)
+	require.Len(t, parked, 2)
+	assert.Equal(t, "1", parked[0].ID)
+	assert.Equal(t, "2", parked[1].ID)
+}
+
+func TestParkStore_GetParked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	d := NewMockdynamoDB(ctrl)
+	d.EXPECT().GetItemWithContext(gomock.Any(), gomock.Any()).Return(&dynamodb.GetItemOutput{Item: parkedItem("1", "")}, nil)
+	d.EXPECT().GetItemWithContext(gomock.Any(), gomock.Any()).Return(&dynamodb.GetItemOutput{}, nil)
+
+	s := ParkStore{db: d, tableName: "parked"}
+
+	parked, err := s.GetParked(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", parked.ID)
+	assert.JSONEq(t, `{}`, string(parked.Event))
+
+	_, err = s.GetParked(context.Background(), "2")
+	assert.True(t, errors.Is(err, model.ErrNotParked))
+}
@@ -31,6 +31,11 @@ func ToAPILambda(handle requestHandler) APIGatewayLambda {
 			Body:      request.Body,
 		}
 
+		// the request continues the trace of its caller, if it came with one
+		if sc, ok := model.ParseTraceParent(innerRequest.GetTraceParent()); ok {
+			ctx = model.WithRemoteSpanContext(ctx, sc)
+		}
+
 		innerResponse, err := handle(ctx, innerRequest)
 		if err != nil {
 			return events.APIGatewayProxyResponse{}, err
@@ -13,26 +13,60 @@ type (
 	SQSLambdaFn func(context.Context, events.SQSEvent) error
 	// eventHandler provides a standard event handler signature
 	eventHandler func(context.Context, *model.InputEvent) error
+	// SQSBatchLambdaFn is the AWS SQS lambda handler signature reporting the failed messages of the batch
+	SQSBatchLambdaFn func(context.Context, events.SQSEvent) (events.SQSEventResponse, error)
+	// batchEventHandler provides an event handler signature returning the IDs of the failed messages
+	batchEventHandler func(context.Context, *model.InputEvent) ([]string, error)
 )
 
 // SQSLambda wraps the pipeline.EventHandler signature with an AWS SQS Lambda handler signature
 func SQSLambda(handle eventHandler) SQSLambdaFn {
 	return func(ctx context.Context, event events.SQSEvent) error {
-		var messages []model.Input
+		return handle(ctx, toInputEvent(event))
+	}
+}
 
-		for i := range event.Records {
-			m := &event.Records[i]
+// SQSBatchLambda wraps the handler.BatchHandler signature with an AWS SQS Lambda handler signature that reports
+// the failed messages as batch item failures. The event source mapping must have ReportBatchItemFailures enabled,
+// otherwise the response is ignored and the whole batch is deleted
+func SQSBatchLambda(handle batchEventHandler) SQSBatchLambdaFn {
+	return func(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+		failed, err := handle(ctx, toInputEvent(event))
+		if err != nil {
+			return events.SQSEventResponse{}, err
+		}
 
-			messages = append(messages, &model.Message{
-				ID:        m.MessageId,
-				Reference: m.ReceiptHandle,
-				Body:      []byte(m.Body),
-				SourceURI: m.EventSourceARN,
-			})
+		response := events.SQSEventResponse{BatchItemFailures: make([]events.SQSBatchItemFailure, len(failed))}
+		for i, id := range failed {
+			response.BatchItemFailures[i] = events.SQSBatchItemFailure{ItemIdentifier: id}
 		}
 
-		innerEvent := model.NewInputEvent(messages)
+		return response, nil
+	}
+}
+
+func toInputEvent(event events.SQSEvent) *model.InputEvent {
+	var messages []model.Input
+
+	for i := range event.Records {
+		m := &event.Records[i]
 
-		return handle(ctx, innerEvent)
+		msg := &model.Message{
+			ID:        m.MessageId,
+			Reference: m.ReceiptHandle,
+			Body:      []byte(m.Body),
+			SourceURI: m.EventSourceARN,
+		}
+
+		if attr, ok := m.MessageAttributes[model.TraceParentHeader]; ok && attr.StringValue != nil {
+			msg.TraceParent = *attr.StringValue
+		}
+
+		// the messages of a FIFO queue come with their group, which the ordered actions keep the order of
+		msg.GroupID = m.Attributes[model.MessageGroupIDAttribute]
+
+		messages = append(messages, msg)
 	}
+
+	return model.NewInputEvent(messages)
 }
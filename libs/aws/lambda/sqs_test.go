@@ -56,3 +56,71 @@ func (entity *testModel) Pipeline(publisher actions.IPublisher) *pipeline.Pipeli
 		pipeline.Publisher(publisher),
 	)
 }
+
+func TestSQSBatchLambda(t *testing.T) {
+	lfunc := lambda.SQSBatchLambda(func(_ context.Context, ev *model.InputEvent) ([]string, error) {
+		assert.Len(t, ev.Inputs(), 2)
+		return []string{"msg-2"}, nil
+	})
+
+	resp, err := lfunc(context.Background(), events.SQSEvent{
+		Records: []events.SQSMessage{{MessageId: "msg-1", Body: "one"}, {MessageId: "msg-2", Body: "two"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "msg-2"}}, resp.BatchItemFailures)
+}
+
+func TestSQSBatchLambda_TraceParent(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	lfunc := lambda.SQSBatchLambda(func(_ context.Context, ev *model.InputEvent) ([]string, error) {
+		tc, ok := ev.Inputs()[0].(model.TraceCarrier)
+		assert.True(t, ok)
+		assert.Equal(t, traceParent, tc.GetTraceParent())
+		return nil, nil
+	})
+
+	_, err := lfunc(context.Background(), events.SQSEvent{
+		Records: []events.SQSMessage{{
+			MessageId: "msg-1",
+			Body:      "one",
+			MessageAttributes: map[string]events.SQSMessageAttribute{
+				model.TraceParentHeader: {DataType: "String", StringValue: &[]string{traceParent}[0]},
+			},
+		}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestSQSBatchLambda_GroupID(t *testing.T) {
+	lfunc := lambda.SQSBatchLambda(func(_ context.Context, ev *model.InputEvent) ([]string, error) {
+		gc, ok := ev.Inputs()[0].(model.GroupCarrier)
+		assert.True(t, ok)
+		assert.Equal(t, "product-1", gc.GetGroupID())
+		return nil, nil
+	})
+
+	_, err := lfunc(context.Background(), events.SQSEvent{
+		Records: []events.SQSMessage{{
+			MessageId:  "msg-1",
+			Body:       "one",
+			Attributes: map[string]string{model.MessageGroupIDAttribute: "product-1"},
+		}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestAPILambda_TraceParent(t *testing.T) {
+	lfunc := lambda.ToAPILambda(func(ctx context.Context, _ *model.Request) (*model.Response, error) {
+		sc, ok := model.ParentSpanContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+		return &model.Response{StatusCode: 200}, nil
+	})
+
+	resp, err := lfunc(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
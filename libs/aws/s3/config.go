@@ -0,0 +1,7 @@
+package s3
+
+// Config is the configuration of the uploader and the downloader, to be loaded with config.Load
+type Config struct {
+	Region string `lookup:"AWS_REGION"`
+	Bucket string `lookup:"S3_BUCKET"`
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
-	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/zale144/ube/libs/config"
 )
 
 // Downloader is the AWS S3 downloader wrapper
@@ -32,19 +32,20 @@ func NewDownloader(region, bucket string) *Downloader {
 	}
 }
 
-// NewDownloaderFromEnv returns a new downloader for provided env vars
-func NewDownloaderFromEnv(regionEnv, bucketEnv string) *Downloader {
-	region := os.Getenv(regionEnv)
-	if region == "" {
-		log.Fatalf("s3: environment variable '%s' is not set", regionEnv)
+// NewDownloaderFromEnv returns a new downloader for provided env vars.
+// It returns an error, rather than exiting, if any of them is not set
+func NewDownloaderFromEnv(regionEnv, bucketEnv string) (*Downloader, error) {
+	values, err := config.Values(config.Env(), regionEnv, bucketEnv)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
 	}
 
-	bucket := os.Getenv(bucketEnv)
-	if bucket == "" {
-		log.Fatalf("s3: environment variable '%s' is not set", bucketEnv)
-	}
+	return NewDownloader(values[0], values[1]), nil
+}
 
-	return NewDownloader(region, bucket)
+// NewDownloaderFromConfig returns a new downloader for the config
+func NewDownloaderFromConfig(cfg Config) *Downloader {
+	return NewDownloader(cfg.Region, cfg.Bucket)
 }
 
 /*
@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
-	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/zale144/ube/libs/config"
 )
 
 // Uploader is the AWS S3 uploader wrapper
@@ -31,19 +31,20 @@ func NewUploader(region, bucket string) *Uploader {
 	}
 }
 
-// NewUploaderFromEnv returns a new uploader for provided env vars
-func NewUploaderFromEnv(regionEnv, bucketEnv string) *Uploader {
-	region := os.Getenv(regionEnv)
-	if region == "" {
-		log.Fatalf("s3: environment variable '%s' is not set", regionEnv)
+// NewUploaderFromEnv returns a new uploader for provided env vars.
+// It returns an error, rather than exiting, if any of them is not set
+func NewUploaderFromEnv(regionEnv, bucketEnv string) (*Uploader, error) {
+	values, err := config.Values(config.Env(), regionEnv, bucketEnv)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
 	}
 
-	bucket := os.Getenv(bucketEnv)
-	if bucket == "" {
-		log.Fatalf("s3: environment variable '%s' is not set", bucketEnv)
-	}
+	return NewUploader(values[0], values[1]), nil
+}
 
-	return NewUploader(region, bucket)
+// NewUploaderFromConfig returns a new uploader for the config
+func NewUploaderFromConfig(cfg Config) *Uploader {
+	return NewUploader(cfg.Region, cfg.Bucket)
 }
 
 /*
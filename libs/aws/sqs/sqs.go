@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
 
+	"github.com/zale144/ube/libs/config"
 	"github.com/zale144/ube/model" // TODO: decouple
 )
 
@@ -20,8 +21,14 @@ const (
 	defaultTimeoutSec          = 15
 	defaultMaxNumberOfMessages = 10
 	defaultWaitTimeSec         = 2
+	maxDelaySec                = 900
 )
 
+// Config is the configuration of the Queue, to be loaded with config.Load
+type Config struct {
+	QueueURL string `lookup:"SQS_QUEUE_URL"`
+}
+
 // Queue allows for the publishing of ens.Payload event onto an SQS
 // queue. This performs message grouping based on the payload.ClientID,
 // allowing for fifo to take place across clients, if so desired.
@@ -37,16 +44,20 @@ func NewQueue(url string) *Queue {
 	return NewQueueWithService(url, svc)
 }
 
-// NewQueueFromEnv creates a Queue service with the provided queueURL
-func NewQueueFromEnv(env string) *Queue {
-	url := os.Getenv(env)
-	if url == "" {
-		log.Fatalf("SQS: environment variable '%s' is not set", env)
+// NewQueueFromEnv creates a Queue service with the queueURL from the env var.
+// It returns an error, rather than exiting, if the env var is not set
+func NewQueueFromEnv(env string) (*Queue, error) {
+	values, err := config.Values(config.Env(), env)
+	if err != nil {
+		return nil, fmt.Errorf("SQS: %w", err)
 	}
 
-	svc := sqs.New(session.Must(session.NewSession()), aws.NewConfig())
+	return NewQueue(values[0]), nil
+}
 
-	return NewQueueWithService(url, svc)
+// NewQueueFromConfig creates a Queue service with the queueURL from the config
+func NewQueueFromConfig(cfg Config) *Queue {
+	return NewQueue(cfg.QueueURL)
 }
 
 // NewQueueWithService creates a Queue service with the provided queueURL,
@@ -84,11 +95,49 @@ const errQueueAlreadyExists = `queue already exists` // TODO ?
 // context to determine its running duration. If the events could not be
 // event, then an error is returned.
 func (q *Queue) PublishEvents(ctx context.Context, messages ...model.Input) error {
+	return q.publish(ctx, nil, messages)
+}
+
+// PublishEventsWithDelay publishes a batch of events to the queue, keeping them hidden from the consumers
+// for the delay. SQS can't delay a message for more than 15 minutes, so longer delays are cut down to that.
+// The FIFO queues only take a delay for the whole queue, so their messages go out right away
+func (q *Queue) PublishEventsWithDelay(ctx context.Context, delay time.Duration, messages ...model.Input) error {
+	if q.isFIFO() {
+		model.LoggerFrom(ctx).Warn("FIFO queues don't hold single messages back, so they're going out right away.",
+			"queue", q.queueURL, "delay", delay)
+		return q.publish(ctx, nil, messages)
+	}
+
+	seconds := int64(math.Ceil(delay.Seconds()))
+	if seconds > maxDelaySec {
+		seconds = maxDelaySec
+	}
+
+	return q.publish(ctx, aws.Int64(seconds), messages)
+}
+
+func (q *Queue) publish(ctx context.Context, delaySeconds *int64, messages []model.Input) error {
 	entries := make([]*sqs.SendMessageBatchRequestEntry, len(messages))
 	for i := range messages {
 		entries[i] = &sqs.SendMessageBatchRequestEntry{
-			Id:          aws.String(messages[i].GetID()),
-			MessageBody: aws.String(messages[i].GetBody()),
+			Id:           aws.String(messages[i].GetID()),
+			MessageBody:  aws.String(messages[i].GetBody()),
+			DelaySeconds: delaySeconds,
+		}
+
+		// the trace context rides along as a message attribute, for the consumer to continue the trace
+		if tc, ok := messages[i].(model.TraceCarrier); ok && tc.GetTraceParent() != "" {
+			entries[i].MessageAttributes = map[string]*sqs.MessageAttributeValue{
+				model.TraceParentHeader: {
+					DataType:    aws.String("String"),
+					StringValue: aws.String(tc.GetTraceParent()),
+				},
+			}
+		}
+
+		// only the FIFO queues take the message group ID, and they keep the order of the messages within the group
+		if gc, ok := messages[i].(model.GroupCarrier); ok && gc.GetGroupID() != "" && q.isFIFO() {
+			entries[i].MessageGroupId = aws.String(gc.GetGroupID())
 		}
 	}
 
@@ -109,6 +158,11 @@ func (q *Queue) PublishEvents(ctx context.Context, messages ...model.Input) erro
 	return nil
 }
 
+// isFIFO tells whether the queue is a FIFO one, which their names end with .fifo
+func (q *Queue) isFIFO() bool {
+	return strings.HasSuffix(q.queueURL, ".fifo")
+}
+
 // AckMessages deletes multiple messages from a sqs once they have been successfully processed otherwise returns an error
 func (q *Queue) AckMessages(ctx context.Context, messages ...model.Input) error {
 	entries := make([]*sqs.DeleteMessageBatchRequestEntry, len(messages))
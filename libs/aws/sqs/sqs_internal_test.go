@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
@@ -26,6 +27,19 @@ func TestCreatingNewPublisher(t *testing.T) {
 	assert.IsType(t, (*awssqs.SQS)(nil), publisher.client, "should have a service that is an *sqs.SQS")
 }
 
+func TestNewQueueFromEnv(t *testing.T) {
+	t.Setenv("QUEUE_URL", "")
+
+	_, err := NewQueueFromEnv("QUEUE_URL")
+	assert.ErrorContains(t, err, "'QUEUE_URL' is not set")
+
+	t.Setenv("QUEUE_URL", "some_queue")
+
+	publisher, err := NewQueueFromEnv("QUEUE_URL")
+	assert.NoError(t, err)
+	assert.Equal(t, "some_queue", publisher.queueURL)
+}
+
 func TestPublisherIsPublisherCompliant(t *testing.T) {
 	assert.Implements(
 		t,
@@ -86,6 +100,70 @@ func TestPublish(t *testing.T) {
 					},
 				).Return(&awssqs.SendMessageBatchOutput{}, nil)
 			},
+		}, {
+			name:        "a message with a trace parent",
+			queueURL:    "queuebar",
+			events:      []model.Input{&model.Message{ID: "5", Body: []byte("traced body"), TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			expectedErr: nil,
+			mockExpectation: func(m *Mockclient) {
+				m.EXPECT().SendMessageBatchWithContext(
+					gomock.Any(),
+					&awssqs.SendMessageBatchInput{
+						Entries: []*awssqs.SendMessageBatchRequestEntry{
+							{
+								Id:          aws.String("5"),
+								MessageBody: aws.String("traced body"),
+								MessageAttributes: map[string]*awssqs.MessageAttributeValue{
+									"traceparent": {
+										DataType:    aws.String("String"),
+										StringValue: aws.String("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+									},
+								},
+							},
+						},
+						QueueUrl: aws.String("queuebar"),
+					},
+				).Return(&awssqs.SendMessageBatchOutput{}, nil)
+			},
+		}, {
+			name:        "a message with a group to a FIFO queue",
+			queueURL:    "queuebar.fifo",
+			events:      []model.Input{&model.Message{ID: "6", Body: []byte("grouped body"), GroupID: "product-1"}},
+			expectedErr: nil,
+			mockExpectation: func(m *Mockclient) {
+				m.EXPECT().SendMessageBatchWithContext(
+					gomock.Any(),
+					&awssqs.SendMessageBatchInput{
+						Entries: []*awssqs.SendMessageBatchRequestEntry{
+							{
+								Id:             aws.String("6"),
+								MessageBody:    aws.String("grouped body"),
+								MessageGroupId: aws.String("product-1"),
+							},
+						},
+						QueueUrl: aws.String("queuebar.fifo"),
+					},
+				).Return(&awssqs.SendMessageBatchOutput{}, nil)
+			},
+		}, {
+			name:        "a message with a group to a standard queue",
+			queueURL:    "queuebar",
+			events:      []model.Input{&model.Message{ID: "7", Body: []byte("grouped body"), GroupID: "product-1"}},
+			expectedErr: nil,
+			mockExpectation: func(m *Mockclient) {
+				m.EXPECT().SendMessageBatchWithContext(
+					gomock.Any(),
+					&awssqs.SendMessageBatchInput{
+						Entries: []*awssqs.SendMessageBatchRequestEntry{
+							{
+								Id:          aws.String("7"),
+								MessageBody: aws.String("grouped body"),
+							},
+						},
+						QueueUrl: aws.String("queuebar"),
+					},
+				).Return(&awssqs.SendMessageBatchOutput{}, nil)
+			},
 		}, {
 			name:          "a failed publish",
 			publishOutput: nil,
@@ -140,6 +218,46 @@ func TestPublish(t *testing.T) {
 	}
 }
 
+func TestPublishWithDelay(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		queueURL     string
+		delay        time.Duration
+		delaySeconds *int64
+	}{
+		{name: "rounded up to a second", queueURL: "queuebar", delay: 1500 * time.Millisecond, delaySeconds: aws.Int64(2)},
+		{name: "cut down to the SQS limit", queueURL: "queuebar", delay: time.Hour, delaySeconds: aws.Int64(900)},
+		// FIFO queues reject the per-message delay
+		{name: "not on a FIFO queue", queueURL: "queuebar.fifo", delay: time.Minute},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mock := NewMockclient(mockCtrl)
+			mock.EXPECT().SendMessageBatchWithContext(
+				gomock.Any(),
+				&awssqs.SendMessageBatchInput{
+					Entries: []*awssqs.SendMessageBatchRequestEntry{
+						{
+							Id:           aws.String("1"),
+							MessageBody:  aws.String("some body"),
+							DelaySeconds: test.delaySeconds,
+						},
+					},
+					QueueUrl: aws.String(test.queueURL),
+				},
+			).Return(&awssqs.SendMessageBatchOutput{}, nil)
+
+			publisher := NewQueueWithService(test.queueURL, mock)
+
+			err := publisher.PublishEventsWithDelay(context.Background(), test.delay, &model.Message{ID: "1", Body: []byte("some body")})
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestAckMessages(t *testing.T) {
 	deletedMessages := []struct {
 		Name            string
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/zale144/ube/libs/converter"
+)
+
+const (
+	// Tag is the struct tag with the key a field is looked up by, e.g. `lookup:"AWS_REGION"`, and `lookup:"AWS_REGION,optional"`
+	// for the field that may be missing
+	Tag = "lookup"
+	// DefaultTag is the struct tag with the value of a field that's missing, which makes it optional, e.g. `default:"eu-west-1"`
+	DefaultTag = "default"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Load fills the fields of the config struct that have the lookup tag with the values from the sources,
+// which are tried in the order they're given, the environment being the only one if there are none.
+// The nested structs are loaded as well. All the required fields that are missing, and the values that can't be
+// converted to the types of the fields, are reported together
+func Load(cfg interface{}, sources ...Source) error {
+	val := reflect.ValueOf(cfg)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return errors.New("config must be a non-nil pointer to a struct")
+	}
+
+	if len(sources) == 0 {
+		sources = []Source{Env()}
+	}
+
+	if err := load(val.Elem(), sources); err != nil {
+		return fmt.Errorf("load config fail: %w", err)
+	}
+
+	return nil
+}
+
+// Values looks up the values of the keys, all of which are required, reporting all the missing ones together
+func Values(src Source, keys ...string) ([]string, error) {
+	var (
+		values = make([]string, len(keys))
+		result error
+	)
+
+	for i, key := range keys {
+		v, ok := src.Lookup(key)
+		if !ok || v == "" {
+			result = multierror.Append(result, fmt.Errorf("'%s' is not set", key))
+			continue
+		}
+
+		values[i] = v
+	}
+
+	if result != nil {
+		return nil, result
+	}
+
+	return values, nil
+}
+
+func load(val reflect.Value, sources []Source) error {
+	var result error
+
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fld := val.Field(i)
+
+		tag, ok := f.Tag.Lookup(Tag)
+		if !ok {
+			if fld.Kind() == reflect.Struct && fld.Type() != timeType {
+				if err := load(fld, sources); err != nil {
+					result = multierror.Append(result, err)
+				}
+			}
+			continue
+		}
+
+		key, opts, _ := strings.Cut(tag, ",")
+		optional := opts == "optional"
+
+		raw, found := lookup(sources, key)
+		if !found {
+			if def, ok := f.Tag.Lookup(DefaultTag); ok {
+				raw, found = def, true
+			}
+		}
+
+		if !found {
+			if !optional {
+				result = multierror.Append(result, fmt.Errorf("'%s' is not set", key))
+			}
+			continue
+		}
+
+		if err := set(fld, key, raw); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result
+}
+
+// lookup returns the first value of the key that isn't empty
+func lookup(sources []Source, key string) (string, bool) {
+	for _, src := range sources {
+		if v, ok := src.Lookup(key); ok && v != "" {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// set converts the raw value to the type of the field and sets it
+func set(fld reflect.Value, key, raw string) error {
+	switch {
+	case fld.Type() == durationType:
+		d, err := converter.ConvertDuration(key, raw)
+		if err != nil {
+			return err
+		}
+		fld.SetInt(int64(d))
+		return nil
+	case fld.Type() == timeType:
+		// the times are parsed whatever their format, not only as RFC 3339 like their UnmarshalText does
+		t, err := converter.ConvertDateTime(key, raw)
+		if err != nil {
+			return err
+		}
+		if t != nil {
+			fld.Set(reflect.ValueOf(*t))
+		}
+		return nil
+	case fld.CanAddr() && fld.Addr().Type().Implements(textUnmarshalerType):
+		if err := fld.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%s: value '%s' is not valid: %w", key, raw, err)
+		}
+		return nil
+	}
+
+	switch fld.Kind() {
+	case reflect.String:
+		fld.SetString(raw)
+	case reflect.Bool:
+		b, err := converter.ConvertBool(key, raw)
+		if err != nil {
+			return err
+		}
+		fld.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := converter.ConvertInt(key, raw)
+		if err != nil {
+			return err
+		}
+		if fld.OverflowInt(int64(n)) {
+			return fmt.Errorf("%s: value '%s' overflows %s", key, raw, fld.Type())
+		}
+		fld.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := converter.ConvertInt(key, raw)
+		if err != nil {
+			return err
+		}
+		if n < 0 || fld.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%s: value '%s' overflows %s", key, raw, fld.Type())
+		}
+		fld.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := converter.ConvertFloat64(key, raw)
+		if err != nil {
+			return err
+		}
+		fld.SetFloat(f)
+	case reflect.Ptr:
+		ptr := reflect.New(fld.Type().Elem())
+		if err := set(ptr.Elem(), key, raw); err != nil {
+			return err
+		}
+		fld.Set(ptr)
+	case reflect.Slice:
+		parts := strings.Split(raw, ",")
+		slice := reflect.MakeSlice(fld.Type(), len(parts), len(parts))
+		for i := range parts {
+			if err := set(slice.Index(i), key, strings.TrimSpace(parts[i])); err != nil {
+				return err
+			}
+		}
+		fld.Set(slice)
+	default:
+		return fmt.Errorf("%s: type %s is not supported", key, fld.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	testConfig struct {
+		Region    string        `lookup:"AWS_REGION"`
+		QueueURL  string        `lookup:"SQS_QUEUE_URL"`
+		BatchSize int           `lookup:"BATCH_SIZE" default:"100"`
+		Timeout   time.Duration `lookup:"TIMEOUT,optional"`
+		Debug     *bool         `lookup:"DEBUG,optional"`
+		Ratio     float64       `lookup:"RATIO,optional"`
+		Since     time.Time     `lookup:"SINCE,optional"`
+		Tables    []string      `lookup:"TABLES,optional"`
+		Retries   []uint8       `lookup:"RETRIES,optional"`
+		DB        dbConfig
+		ignored   string
+	}
+	dbConfig struct {
+		TableName string `lookup:"DB_TABLE_NAME"`
+	}
+)
+
+func TestLoad(t *testing.T) {
+	src := Map{
+		"AWS_REGION":    "eu-west-1",
+		"SQS_QUEUE_URL": "https://sqs/queue",
+		"TIMEOUT":       "1m30s",
+		"DEBUG":         "true",
+		"RATIO":         "0.5",
+		"SINCE":         "2023-01-02",
+		"TABLES":        "products, stores",
+		"RETRIES":       "1,2,3",
+		"DB_TABLE_NAME": "products",
+	}
+
+	var cfg testConfig
+	require.NoError(t, Load(&cfg, src))
+
+	debug := true
+	assert.Equal(t, testConfig{
+		Region:    "eu-west-1",
+		QueueURL:  "https://sqs/queue",
+		BatchSize: 100,
+		Timeout:   90 * time.Second,
+		Debug:     &debug,
+		Ratio:     0.5,
+		Since:     time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		Tables:    []string{"products", "stores"},
+		Retries:   []uint8{1, 2, 3},
+		DB:        dbConfig{TableName: "products"},
+	}, cfg)
+}
+
+func TestLoad_Env(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("SQS_QUEUE_URL", "https://sqs/queue")
+	t.Setenv("DB_TABLE_NAME", "products")
+	t.Setenv("BATCH_SIZE", "10")
+
+	var cfg testConfig
+	require.NoError(t, Load(&cfg))
+
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, 10, cfg.BatchSize)
+	assert.Nil(t, cfg.Debug)
+}
+
+func TestLoad_Sources(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"AWS_REGION":"eu-west-1","BATCH_SIZE":5,"TABLES":["a","b"]}`), 0o600))
+
+	envPath := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(envPath, []byte("# queue\nexport SQS_QUEUE_URL=\"https://sqs/queue\"\n\nDB_TABLE_NAME=products\nAWS_REGION=us-east-1\n"), 0o600))
+
+	jsonSrc, err := JSONFile(jsonPath)
+	require.NoError(t, err)
+
+	envSrc, err := EnvFile(envPath)
+	require.NoError(t, err)
+
+	var cfg testConfig
+	require.NoError(t, Load(&cfg, jsonSrc, envSrc))
+
+	// the sources are tried in order
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, "https://sqs/queue", cfg.QueueURL)
+	assert.Equal(t, 5, cfg.BatchSize)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tables)
+	assert.Equal(t, "products", cfg.DB.TableName)
+}
+
+func TestLoad_Wrong(t *testing.T) {
+	src := Map{
+		"SQS_QUEUE_URL": "https://sqs/queue",
+		"BATCH_SIZE":    "many",
+		"RETRIES":       "1,1000",
+	}
+
+	var cfg testConfig
+	err := Load(&cfg, src)
+	require.Error(t, err)
+
+	// everything that's wrong is reported at once
+	assert.Contains(t, err.Error(), "'AWS_REGION' is not set")
+	assert.Contains(t, err.Error(), "'DB_TABLE_NAME' is not set")
+	assert.Contains(t, err.Error(), "BATCH_SIZE: value 'many' is not int")
+	assert.Contains(t, err.Error(), "RETRIES: value '1000' overflows uint8")
+
+	assert.Error(t, Load(cfg, src))
+}
+
+func TestValues(t *testing.T) {
+	values, err := Values(Map{"A": "a", "B": "b"}, "A", "B")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	_, err = Values(Map{"A": "a", "B": ""}, "A", "B", "C")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'B' is not set")
+	assert.Contains(t, err.Error(), "'C' is not set")
+}
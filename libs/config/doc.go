@@ -0,0 +1,18 @@
+/*
+Package config loads the configuration into a struct, by the `lookup` tags of its fields, from the environment,
+or from the other sources, e.g. a JSON file. The values are converted to the types of the fields,
+and everything that's missing or can't be converted is reported at once:
+
+	type Config struct {
+		Region    string        `lookup:"AWS_REGION"`
+		QueueURL  string        `lookup:"SQS_QUEUE_URL"`
+		BatchSize int           `lookup:"BATCH_SIZE" default:"100"`
+		Timeout   time.Duration `lookup:"TIMEOUT,optional"`
+	}
+
+	var cfg Config
+	if err := config.Load(&cfg); err != nil {
+		...
+	}
+*/
+package config
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type (
+	// Source looks up the raw values of the configuration by their keys
+	Source interface {
+		Lookup(key string) (string, bool)
+	}
+	// SourceFunc is a func type abstraction of the Source
+	SourceFunc func(key string) (string, bool)
+	// Map is a source that's a map of keys to values, e.g. for tests
+	Map map[string]string
+)
+
+// Lookup calls f(key)
+func (f SourceFunc) Lookup(key string) (string, bool) {
+	return f(key)
+}
+
+// Lookup looks up the key in the map
+func (m Map) Lookup(key string) (string, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+// Env returns the source that looks the keys up in the environment variables
+func Env() Source {
+	return SourceFunc(os.LookupEnv)
+}
+
+// JSON returns the source that looks the keys up in the top level of the JSON object. Values that aren't strings
+// are taken as they're written in the JSON, except for the arrays, which are joined with commas, the way the slices are split
+func JSON(data []byte) (Source, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal config fail: %w", err)
+	}
+
+	m := make(Map, len(raw))
+
+	for k, v := range raw {
+		m[k] = jsonValue(v)
+	}
+
+	return m, nil
+}
+
+func jsonValue(v json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s
+	}
+
+	var arr []json.RawMessage
+	if err := json.Unmarshal(v, &arr); err == nil {
+		elems := make([]string, len(arr))
+		for i := range arr {
+			elems[i] = jsonValue(arr[i])
+		}
+		return strings.Join(elems, ",")
+	}
+
+	return string(bytes.TrimSpace(v))
+}
+
+// JSONFile returns the source that looks the keys up in the JSON file, see JSON
+func JSONFile(path string) (Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file fail: %w", err)
+	}
+
+	return JSON(data)
+}
+
+// EnvFile returns the source that looks the keys up in the file of KEY=VALUE lines, the way the .env files have them.
+// Empty lines and the ones starting with # are skipped, and the values may be quoted
+func EnvFile(path string) (Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file fail: %w", err)
+	}
+
+	m := make(Map)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		k, v, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d of config file '%s' is not KEY=VALUE", n, path)
+		}
+
+		v = strings.TrimSpace(v)
+		if len(v) > 1 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+
+		m[strings.TrimSpace(k)] = v
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan config file fail: %w", err)
+	}
+
+	return m, nil
+}
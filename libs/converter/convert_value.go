@@ -87,3 +87,21 @@ func ConvertDateTime(field, value string) (objValuePtr *time.Time, err error) {
 
 	return objValuePtr, err
 }
+
+/*
+ConvertDuration makes a time.Duration from a string, e.g. "1m30s"
+*/
+func ConvertDuration(field, value string) (objValue time.Duration, err error) {
+	// make it a zero value on zero value input
+	if value == "" {
+		return
+	}
+
+	objValue, err = time.ParseDuration(value)
+	if err != nil {
+		err = fmt.Errorf("%s: value '%s' is not duration", field, value)
+		return
+	}
+
+	return objValue, err
+}
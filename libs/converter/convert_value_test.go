@@ -4,6 +4,7 @@ package converter
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -126,3 +127,15 @@ func Test_ConvertDateTime_Good(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "2021-12-10 05:30:01", result.Format("2006-01-02 15:04:05"))
 }
+
+func Test_ConvertDuration_Wrong(t *testing.T) {
+	result, err := ConvertDuration("myDuration", "soon")
+	assert.Equal(t, `myDuration: value 'soon' is not duration`, err.Error())
+	assert.Zero(t, result)
+}
+
+func Test_ConvertDuration_Good(t *testing.T) {
+	result, err := ConvertDuration("myDuration", "1m30s")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, result)
+}
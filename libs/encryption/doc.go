@@ -0,0 +1,4 @@
+// Package encryption envelope-encrypts the sensitive fields of the entities, the ones tagged with the
+// `sensitive` struct tag. The fields are encrypted with AES-GCM data keys, which are wrapped by a KeyProvider,
+// e.g. a KMS key, and stored along with the ciphertext, so that whoever has access to the key provider can decrypt them.
+package encryption
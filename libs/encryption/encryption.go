@@ -0,0 +1,275 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Tag is the struct tag that marks a field as sensitive, the same one the redaction goes by
+const Tag = "sensitive"
+
+// Prefix is what the encrypted values start with
+const Prefix = "enc:v1:"
+
+// maxDepth keeps the encryption from following a cycle of pointers forever
+const maxDepth = 32
+
+var b64 = base64.RawURLEncoding
+
+// ErrNoKeyProvider is returned when there's nothing to generate or unwrap the data keys with
+var ErrNoKeyProvider = errors.New("no key provider")
+
+// IsEncrypted tells whether the value is one that Encrypt encrypted
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Encrypt encrypts the sensitive fields of the values in place, with a single data key, and returns the ID of the key
+// that wrapped it, or an empty one if there was nothing to encrypt. Only the strings, and the pointers to and the slices
+// of them, are encrypted, and the values have to be pointers for their fields to be set. The values encrypted already
+// are left as they are, so encrypting twice is the same as encrypting once
+func Encrypt(ctx context.Context, kp KeyProvider, values ...interface{}) (string, error) {
+	if kp == nil {
+		return "", ErrNoKeyProvider
+	}
+
+	e := &encrypter{ctx: ctx, kp: kp}
+
+	for _, v := range values {
+		if err := walk(reflect.ValueOf(v), e.encrypt, 0); err != nil {
+			return "", err
+		}
+	}
+
+	if e.key == nil {
+		return "", nil
+	}
+
+	return e.key.KeyID, nil
+}
+
+// Decrypt decrypts the sensitive fields of the values in place, and returns the IDs of the keys that wrapped
+// their data keys. The values that aren't encrypted are left as they are
+func Decrypt(ctx context.Context, kp KeyProvider, values ...interface{}) ([]string, error) {
+	if kp == nil {
+		return nil, ErrNoKeyProvider
+	}
+
+	d := &decrypter{ctx: ctx, kp: kp, keys: make(map[string]cipher.AEAD)}
+
+	for _, v := range values {
+		if err := walk(reflect.ValueOf(v), d.decrypt, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return d.keyIDs, nil
+}
+
+type encrypter struct {
+	ctx context.Context
+	kp  KeyProvider
+	key *DataKey
+	gcm cipher.AEAD
+}
+
+func (e *encrypter) encrypt(s, field string) (string, error) {
+	if s == "" || IsEncrypted(s) {
+		return s, nil
+	}
+
+	if e.key == nil {
+		key, err := e.kp.GenerateDataKey(e.ctx)
+		if err != nil {
+			return "", fmt.Errorf("generate data key fail: %w", err)
+		}
+
+		gcm, err := newGCM(key.Plain)
+		if err != nil {
+			return "", fmt.Errorf("create data key cipher fail: %w", err)
+		}
+
+		e.key, e.gcm = key, gcm
+	}
+
+	sealed, err := seal(e.gcm, []byte(s), []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("encrypt field '%s' fail: %w", field, err)
+	}
+
+	return Prefix + b64.EncodeToString([]byte(e.key.KeyID)) + ":" + b64.EncodeToString(e.key.Wrapped) + ":" +
+		b64.EncodeToString(sealed), nil
+}
+
+type decrypter struct {
+	ctx context.Context
+	kp  KeyProvider
+	// keys are the unwrapped data keys by their wrapped form, so that each is unwrapped only once
+	keys   map[string]cipher.AEAD
+	keyIDs []string
+}
+
+func (d *decrypter) decrypt(s, field string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(s, Prefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("field '%s' is not a valid encrypted value", field)
+	}
+
+	gcm, err := d.dataKey(parts[0], parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decrypt field '%s' fail: %w", field, err)
+	}
+
+	sealed, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("decode field '%s' fail: %w", field, err)
+	}
+
+	plain, err := open(gcm, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypt field '%s' fail: %w", field, err)
+	}
+
+	return string(plain), nil
+}
+
+func (d *decrypter) dataKey(encodedKeyID, encodedWrapped string) (cipher.AEAD, error) {
+	if gcm, ok := d.keys[encodedWrapped]; ok {
+		return gcm, nil
+	}
+
+	keyID, err := b64.DecodeString(encodedKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("decode key ID fail: %w", err)
+	}
+
+	wrapped, err := b64.DecodeString(encodedWrapped)
+	if err != nil {
+		return nil, fmt.Errorf("decode data key fail: %w", err)
+	}
+
+	plain, err := d.kp.DecryptDataKey(d.ctx, string(keyID), wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data key fail: %w", err)
+	}
+
+	gcm, err := newGCM(plain)
+	if err != nil {
+		return nil, fmt.Errorf("create data key cipher fail: %w", err)
+	}
+
+	d.keys[encodedWrapped] = gcm
+	d.addKeyID(string(keyID))
+
+	return gcm, nil
+}
+
+func (d *decrypter) addKeyID(keyID string) {
+	for _, id := range d.keyIDs {
+		if id == keyID {
+			return
+		}
+	}
+
+	d.keyIDs = append(d.keyIDs, keyID)
+}
+
+// transform turns the value of the sensitive field into another one, the field name being the additional data
+// that ties the ciphertext to the field
+type transform func(s, field string) (string, error)
+
+// walk applies the transform to the sensitive strings of the value in place, wherever they can be set
+func walk(v reflect.Value, fn transform, depth int) error {
+	if depth > maxDepth || !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return walk(v.Elem(), fn, depth+1)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			var err error
+			if _, ok := f.Tag.Lookup(Tag); ok {
+				err = setStrings(v.Field(i), f.Name, fn, depth+1)
+			} else {
+				err = walk(v.Field(i), fn, depth+1)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walk(v.Index(i), fn, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// the map values can't be set in place, so only the ones they point to are walked
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := walk(iter.Value(), fn, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setStrings transforms the strings of the sensitive field, be it a string, a pointer to one, or a slice of them
+func setStrings(v reflect.Value, field string, fn transform, depth int) error {
+	if depth > maxDepth {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return fmt.Errorf("field '%s' can't be set", field)
+		}
+
+		s, err := fn(v.String(), field)
+		if err != nil {
+			return err
+		}
+
+		v.SetString(s)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return setStrings(v.Elem(), field, fn, depth+1)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := setStrings(v.Index(i), field, fn, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		// there's no telling how to encrypt anything else in place, so the structs in it are looked into instead
+		return walk(v, fn, depth)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	customer struct {
+		ID       string   `json:"id"`
+		Name     string   `json:"name" sensitive:"mask"`
+		Email    *string  `json:"email" sensitive:"hash"`
+		Phones   []string `json:"phones" sensitive:"mask"`
+		Age      int      `json:"age" sensitive:"mask"`
+		Address  *address `json:"address"`
+		Accounts []*account
+	}
+	address struct {
+		Street string `json:"street" sensitive:""`
+		City   string `json:"city"`
+	}
+	account struct {
+		IBAN string `sensitive:""`
+	}
+)
+
+func newCustomer() *customer {
+	email := "jane@example.com"
+
+	return &customer{
+		ID:       "c-1",
+		Name:     "Jane Doe",
+		Email:    &email,
+		Phones:   []string{"+44 20 7946 0958"},
+		Age:      42,
+		Address:  &address{Street: "1 Main St", City: "London"},
+		Accounts: []*account{{IBAN: "GB33BUKB20201555555555"}},
+	}
+}
+
+func newProvider(t *testing.T) *StaticKeyProvider {
+	kp, err := NewStaticKeyProvider("key-1", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	return kp
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	ctx := context.Background()
+	kp := newProvider(t)
+
+	c := newCustomer()
+
+	keyID, err := Encrypt(ctx, kp, c)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", keyID)
+
+	assert.Equal(t, "c-1", c.ID)
+	assert.Equal(t, "London", c.Address.City)
+	assert.Equal(t, 42, c.Age)
+
+	for _, s := range []string{c.Name, *c.Email, c.Phones[0], c.Address.Street, c.Accounts[0].IBAN} {
+		assert.True(t, IsEncrypted(s), s)
+	}
+
+	// encrypting again leaves the encrypted values as they are
+	name := c.Name
+	_, err = Encrypt(ctx, kp, c)
+	require.NoError(t, err)
+	assert.Equal(t, name, c.Name)
+
+	keyIDs, err := Decrypt(ctx, kp, c)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-1"}, keyIDs)
+	assert.Equal(t, newCustomer(), c)
+}
+
+func TestDecrypt_Rotated(t *testing.T) {
+	ctx := context.Background()
+	kp := newProvider(t)
+
+	old := newCustomer()
+	_, err := Encrypt(ctx, kp, old)
+	require.NoError(t, err)
+
+	require.NoError(t, kp.Rotate("key-2", []byte("fedcba9876543210")))
+
+	cur := newCustomer()
+	keyID, err := Encrypt(ctx, kp, cur)
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", keyID)
+
+	keyIDs, err := Decrypt(ctx, kp, old, cur)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-1", "key-2"}, keyIDs)
+	assert.Equal(t, newCustomer(), old)
+	assert.Equal(t, newCustomer(), cur)
+}
+
+func TestDecrypt_Fail(t *testing.T) {
+	ctx := context.Background()
+
+	c := newCustomer()
+	_, err := Encrypt(ctx, newProvider(t), c)
+	require.NoError(t, err)
+
+	other, err := NewStaticKeyProvider("key-3", []byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	_, err = Decrypt(ctx, other, c)
+	assert.ErrorContains(t, err, "key 'key-1' is unknown")
+
+	// the ciphertext is tied to the field it was encrypted for
+	c.Address.Street = c.Name
+	_, err = Decrypt(ctx, newProvider(t), c.Address)
+	assert.ErrorContains(t, err, "decrypt field 'Street' fail")
+
+	_, err = Decrypt(ctx, nil, c)
+	assert.ErrorIs(t, err, ErrNoKeyProvider)
+}
+
+func TestEncrypt_NothingToEncrypt(t *testing.T) {
+	keyID, err := Encrypt(context.Background(), newProvider(t), &address{City: "London"})
+	require.NoError(t, err)
+	assert.Empty(t, keyID)
+}
+
+func TestNewStaticKeyProvider_InvalidKey(t *testing.T) {
+	_, err := NewStaticKeyProvider("key-1", []byte("short"))
+	assert.Error(t, err)
+
+	_, err = NewStaticKeyProvider("", []byte("0123456789abcdef"))
+	assert.Error(t, err)
+}
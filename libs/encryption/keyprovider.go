@@ -0,0 +1,138 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DataKeySize is the size of the data keys, which makes them AES-256 keys
+const DataKeySize = 32
+
+type (
+	// KeyProvider generates the data keys and unwraps them, the way KMS does, so that only the wrapped
+	// data keys are ever stored along with the data
+	KeyProvider interface {
+		GenerateDataKey(ctx context.Context) (*DataKey, error)
+		DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+	}
+	// DataKey is a data key in the clear, along with the ID of the key that wrapped it and its wrapped form
+	DataKey struct {
+		KeyID   string
+		Plain   []byte
+		Wrapped []byte
+	}
+)
+
+var _ KeyProvider = (*StaticKeyProvider)(nil)
+
+// StaticKeyProvider wraps the data keys with AES-GCM keys it's given, e.g. for tests or local development.
+// The keys it was given before the current one are still there to unwrap the data keys wrapped with them
+type StaticKeyProvider struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewStaticKeyProvider creates a new static key provider that wraps the data keys with the key,
+// which has to be 16, 24 or 32 bytes long
+func NewStaticKeyProvider(keyID string, key []byte) (*StaticKeyProvider, error) {
+	p := &StaticKeyProvider{keys: make(map[string]cipher.AEAD)}
+
+	if err := p.Rotate(keyID, key); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Rotate makes the key the one that wraps the data keys from now on, keeping the previous ones for unwrapping
+func (p *StaticKeyProvider) Rotate(keyID string, key []byte) error {
+	if keyID == "" {
+		return errors.New("key ID is empty")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return fmt.Errorf("create cipher for key '%s' fail: %w", keyID, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys[keyID] = gcm
+	p.current = keyID
+
+	return nil
+}
+
+// GenerateDataKey generates a new data key, wrapped with the current key
+func (p *StaticKeyProvider) GenerateDataKey(_ context.Context) (*DataKey, error) {
+	p.mu.RLock()
+	keyID, gcm := p.current, p.keys[p.current]
+	p.mu.RUnlock()
+
+	plain := make([]byte, DataKeySize)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, fmt.Errorf("generate data key fail: %w", err)
+	}
+
+	wrapped, err := seal(gcm, plain, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("wrap data key fail: %w", err)
+	}
+
+	return &DataKey{KeyID: keyID, Plain: plain, Wrapped: wrapped}, nil
+}
+
+// DecryptDataKey unwraps the data key with the key it was wrapped with
+func (p *StaticKeyProvider) DecryptDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	p.mu.RLock()
+	gcm, ok := p.keys[keyID]
+	p.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("key '%s' is unknown", keyID)
+	}
+
+	plain, err := open(gcm, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key fail: %w", err)
+	}
+
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce, which it puts in front of the ciphertext
+func seal(gcm cipher.AEAD, plain, aad []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+// open decrypts what seal encrypted
+func open(gcm cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, ct := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ct, aad)
+}
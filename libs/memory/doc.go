@@ -0,0 +1,3 @@
+// Package memory provides in-memory implementations of the stores that UBE
+// depends on. They're meant for tests, local runs and single instance setups.
+package memory
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+// IdempotencyStore keeps the states of the messages in memory, until they expire
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]idempotencyRecord
+}
+
+type idempotencyRecord struct {
+	state     model.IdempotencyState
+	expiresAt time.Time
+}
+
+// NewIdempotencyStore creates a new, empty, in-memory idempotency store
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{records: make(map[string]idempotencyRecord)}
+}
+
+// Claim marks the message as in progress, unless it's known already, in which case its state is returned
+func (s *IdempotencyStore) Claim(_ context.Context, key string, ttl time.Duration) (model.IdempotencyState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := model.Now()
+
+	if rec, ok := s.records[key]; ok && now.Before(rec.expiresAt) {
+		return rec.state, nil
+	}
+
+	s.records[key] = idempotencyRecord{state: model.IdempotencyInProgress, expiresAt: now.Add(ttl)}
+
+	return model.IdempotencyNew, nil
+}
+
+// Complete marks the message as processed successfully
+func (s *IdempotencyStore) Complete(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = idempotencyRecord{state: model.IdempotencyCompleted, expiresAt: model.Now().Add(ttl)}
+
+	return nil
+}
+
+// Release forgets the message, so that it can be claimed again
+func (s *IdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zale144/ube/model"
+)
+
+// ParkStore keeps the parked business events in memory, in the order they were first parked
+type ParkStore struct {
+	mu     sync.Mutex
+	ids    []string
+	parked map[string]*model.ParkedEvent
+}
+
+// NewParkStore creates a new, empty, in-memory park store
+func NewParkStore() *ParkStore {
+	return &ParkStore{parked: make(map[string]*model.ParkedEvent)}
+}
+
+// Park stores the parked business events, replacing the ones with the same ID
+func (s *ParkStore) Park(_ context.Context, events ...*model.ParkedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ev := range events {
+		if _, ok := s.parked[ev.ID]; !ok {
+			s.ids = append(s.ids, ev.ID)
+		}
+		cp := *ev
+		s.parked[ev.ID] = &cp
+	}
+
+	return nil
+}
+
+// Parked returns all the parked business events
+func (s *ParkStore) Parked(_ context.Context) ([]*model.ParkedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parked := make([]*model.ParkedEvent, 0, len(s.ids))
+	for _, id := range s.ids {
+		cp := *s.parked[id]
+		parked = append(parked, &cp)
+	}
+
+	return parked, nil
+}
+
+// GetParked returns the parked business event
+func (s *ParkStore) GetParked(_ context.Context, id string) (*model.ParkedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ev, ok := s.parked[id]
+	if !ok {
+		return nil, fmt.Errorf("'%s': %w", id, model.ErrNotParked)
+	}
+
+	cp := *ev
+
+	return &cp, nil
+}
+
+// Unpark removes the business events from the store
+func (s *ParkStore) Unpark(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.parked, id)
+	}
+
+	kept := s.ids[:0]
+	for _, id := range s.ids {
+		if _, ok := s.parked[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	s.ids = kept
+
+	return nil
+}
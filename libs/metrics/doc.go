@@ -0,0 +1,3 @@
+// Package metrics provides an in-memory registry for the metrics that UBE reports,
+// along with the exporters that make them available to Prometheus and CloudWatch.
+package metrics
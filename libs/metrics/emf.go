@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zale144/ube/model"
+)
+
+// EMF writes the metrics as CloudWatch embedded metric format log lines, which CloudWatch turns into metrics
+// on its own, with the tags as dimensions. Written by a Lambda to its standard output, that's all it takes
+type EMF struct {
+	namespace string
+	w         io.Writer
+}
+
+// NewEMF creates a new CloudWatch EMF exporter, writing to w, or to the standard output if it's nil
+func NewEMF(namespace string, w io.Writer) *EMF {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &EMF{namespace: namespace, w: w}
+}
+
+type (
+	emfMetadata struct {
+		Timestamp         int64          `json:"Timestamp"`
+		CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+	}
+	emfDirective struct {
+		Namespace  string      `json:"Namespace"`
+		Dimensions [][]string  `json:"Dimensions"`
+		Metrics    []emfMetric `json:"Metrics"`
+	}
+	emfMetric struct {
+		Name string `json:"Name"`
+		Unit string `json:"Unit"`
+	}
+)
+
+// Export writes a log line for every set of tags, with all the metrics that have it. CloudWatch has no histograms
+// to speak of, so a histogram goes out as its sum and count, e.g. for the average to be calculated on a dashboard
+func (e *EMF) Export(series []Series) error {
+	var (
+		order []string
+		lines = make(map[string]map[string]interface{})
+		dims  = make(map[string][]string)
+		specs = make(map[string][]emfMetric)
+	)
+
+	for _, s := range series {
+		k := key("", s.Tags)
+
+		line, ok := lines[k]
+		if !ok {
+			line = make(map[string]interface{}, len(s.Tags))
+			for t, v := range s.Tags {
+				line[t] = v
+			}
+			lines[k] = line
+			dims[k] = tagNames(s.Tags)
+			order = append(order, k)
+		}
+
+		if s.Kind == KindCounter {
+			line[s.Name] = s.Value
+			specs[k] = append(specs[k], emfMetric{Name: s.Name, Unit: unit(s.Name)})
+			continue
+		}
+
+		line[s.Name+"_sum"] = s.Sum
+		line[s.Name+"_count"] = s.Count
+		specs[k] = append(specs[k],
+			emfMetric{Name: s.Name + "_sum", Unit: unit(s.Name)},
+			emfMetric{Name: s.Name + "_count", Unit: "Count"},
+		)
+	}
+
+	now := model.Now().UnixMilli()
+
+	for _, k := range order {
+		line := lines[k]
+
+		line["_aws"] = emfMetadata{
+			Timestamp: now,
+			CloudWatchMetrics: []emfDirective{{
+				Namespace:  e.namespace,
+				Dimensions: [][]string{dims[k]},
+				Metrics:    specs[k],
+			}},
+		}
+
+		jsn, err := json.Marshal(line)
+		if err != nil {
+			return fmt.Errorf("marshal EMF log line fail: %w", err)
+		}
+
+		if _, err = fmt.Fprintln(e.w, string(jsn)); err != nil {
+			return fmt.Errorf("write EMF log line fail: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Flush exports the metrics of the registry and resets it, e.g. at the end of every Lambda invocation
+func (e *EMF) Flush(r *Registry) error {
+	return e.Export(r.drain())
+}
+
+// unit derives the CloudWatch unit of the metric from its name, the way the UBE metrics are named
+func unit(name string) string {
+	switch {
+	case strings.HasSuffix(name, "_seconds"):
+		return "Seconds"
+	case strings.HasSuffix(name, "_total"):
+		return "Count"
+	}
+
+	return "None"
+}
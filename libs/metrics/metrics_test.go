@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zale144/ube/model"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry(Buckets(1, 0.1))
+
+	r.Count(model.MetricEventsIn, 2, map[string]string{"pipeline": "products"})
+	r.Count(model.MetricEventsIn, 1, map[string]string{"pipeline": "products"})
+	r.Observe(model.MetricActionDuration, 0.05, map[string]string{"action": `Persister:"stock"`})
+	r.Observe(model.MetricActionDuration, 0.5, map[string]string{"action": `Persister:"stock"`})
+
+	assert.Equal(t, float64(3), r.Value(model.MetricEventsIn, map[string]string{"pipeline": "products"}))
+
+	b := &bytes.Buffer{}
+	require.NoError(t, WritePrometheus(b, r.Snapshot()))
+
+	assert.Equal(t, `# TYPE ube_action_duration_seconds histogram
+ube_action_duration_seconds_bucket{action="Persister:\"stock\"",le="0.1"} 1
+ube_action_duration_seconds_bucket{action="Persister:\"stock\"",le="1"} 2
+ube_action_duration_seconds_bucket{action="Persister:\"stock\"",le="+Inf"} 2
+ube_action_duration_seconds_sum{action="Persister:\"stock\""} 0.55
+ube_action_duration_seconds_count{action="Persister:\"stock\""} 2
+# TYPE ube_events_in_total counter
+ube_events_in_total{pipeline="products"} 3
+`, b.String())
+}
+
+func TestEMF_Flush(t *testing.T) {
+	now := model.Now
+	defer func() { model.Now = now }()
+	model.Now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	r := NewRegistry()
+
+	r.Count(model.MetricEventsIn, 3, map[string]string{"pipeline": "products"})
+	r.Count(model.MetricEventsOut, 3, map[string]string{"pipeline": "products"})
+	r.Observe(model.MetricActionBatchSize, 10, map[string]string{"pipeline": "products", "action": "Persister"})
+
+	b := &bytes.Buffer{}
+	require.NoError(t, NewEMF("UBE", b).Flush(r))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+
+	assert.JSONEq(t, `{
+		"_aws": {"Timestamp": 1767225600000, "CloudWatchMetrics": [{
+			"Namespace": "UBE",
+			"Dimensions": [["action", "pipeline"]],
+			"Metrics": [{"Name": "ube_action_batch_size_sum", "Unit": "None"}, {"Name": "ube_action_batch_size_count", "Unit": "Count"}]
+		}]},
+		"action": "Persister",
+		"pipeline": "products",
+		"ube_action_batch_size_sum": 10,
+		"ube_action_batch_size_count": 1
+	}`, lines[0])
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &line))
+	assert.Equal(t, float64(3), line[model.MetricEventsIn])
+	assert.Equal(t, float64(3), line[model.MetricEventsOut])
+
+	// flushed
+	assert.Empty(t, r.Snapshot())
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zale144/ube/model"
+)
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func WritePrometheus(w io.Writer, series []Series) error {
+	b := &strings.Builder{}
+
+	var last string
+
+	for _, s := range series {
+		if s.Name != last {
+			fmt.Fprintf(b, "# TYPE %s %s\n", s.Name, s.Kind)
+			last = s.Name
+		}
+
+		if s.Kind == KindCounter {
+			fmt.Fprintf(b, "%s%s %s\n", s.Name, labels(s.Tags, ""), formatFloat(s.Value))
+			continue
+		}
+
+		for _, bucket := range s.Buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", s.Name, labels(s.Tags, formatFloat(bucket.UpperBound)), bucket.Count)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", s.Name, labels(s.Tags, "+Inf"), s.Count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", s.Name, labels(s.Tags, ""), formatFloat(s.Sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", s.Name, labels(s.Tags, ""), s.Count)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("write prometheus metrics fail: %w", err)
+	}
+
+	return nil
+}
+
+// PrometheusHandler serves the metrics of the registry for Prometheus to scrape
+func PrometheusHandler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		if err := WritePrometheus(w, r.Snapshot()); err != nil {
+			model.LoggerFrom(req.Context()).Error("Prometheus came for the metrics and left empty-handed.", "error", err)
+		}
+	})
+}
+
+// labels formats the tags as Prometheus labels, with the 'le' label of a histogram bucket if there is one
+func labels(tags map[string]string, le string) string {
+	var pairs []string
+
+	for _, t := range tagNames(tags) {
+		pairs = append(pairs, t+`="`+labelEscaper.Replace(tags[t])+`"`)
+	}
+
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes the label values the way the exposition format wants them
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
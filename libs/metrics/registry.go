@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zale144/ube/model"
+)
+
+var _ model.Metrics = (*Registry)(nil)
+
+// DefaultBuckets are the upper bounds of the histogram buckets, good enough for durations in seconds and batch sizes
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// Kind is the kind of metric
+type Kind string
+
+const (
+	KindCounter   Kind = "counter"
+	KindHistogram Kind = "histogram"
+)
+
+type (
+	// Series is the state of a single metric with a single set of tags
+	Series struct {
+		Name string
+		Tags map[string]string
+		Kind Kind
+		// Value is the value of a counter
+		Value float64
+		// Count, Sum and Buckets are the number, the sum and the distribution of the values observed by a histogram
+		Count   uint64
+		Sum     float64
+		Buckets []Bucket
+	}
+	// Bucket holds how many of the observed values were less than or equal to its upper bound
+	Bucket struct {
+		UpperBound float64
+		Count      uint64
+	}
+	// Option is a func type abstraction of the registry configuration
+	Option func(*Registry)
+)
+
+// Registry keeps the metrics in memory, to be exported or inspected
+type Registry struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[string]*Series
+}
+
+// NewRegistry creates a new, empty, in-memory metrics registry
+func NewRegistry(options ...Option) *Registry {
+	r := &Registry{
+		buckets: DefaultBuckets,
+		series:  make(map[string]*Series),
+	}
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return r
+}
+
+// Buckets sets the upper bounds of the histogram buckets
+func Buckets(bounds ...float64) Option {
+	return func(r *Registry) {
+		r.buckets = append([]float64(nil), bounds...)
+		sort.Float64s(r.buckets)
+	}
+}
+
+// Count adds the value to the counter with the given tags
+func (r *Registry) Count(name string, value float64, tags map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.get(name, tags, KindCounter).Value += value
+}
+
+// Observe records the value in the histogram with the given tags
+func (r *Registry) Observe(name string, value float64, tags map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.get(name, tags, KindHistogram)
+	s.Count++
+	s.Sum += value
+
+	for i := range s.Buckets {
+		if value <= s.Buckets[i].UpperBound {
+			s.Buckets[i].Count++
+		}
+	}
+}
+
+// Value returns the value of the counter, or the number of values observed by the histogram, with the given tags
+func (r *Registry) Value(name string, tags map[string]string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.series[key(name, tags)]
+	if !ok {
+		return 0
+	}
+
+	if s.Kind == KindHistogram {
+		return float64(s.Count)
+	}
+
+	return s.Value
+}
+
+// Snapshot returns a copy of all the metrics, sorted by their name and tags
+func (r *Registry) Snapshot() []Series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snapshot()
+}
+
+// drain returns all the metrics and resets the registry, so that nothing reported in between is lost
+func (r *Registry) drain() []Series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := r.snapshot()
+	r.series = make(map[string]*Series)
+
+	return snapshot
+}
+
+func (r *Registry) snapshot() []Series {
+	keys := make([]string, 0, len(r.series))
+	for k := range r.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	snapshot := make([]Series, len(keys))
+	for i, k := range keys {
+		s := *r.series[k]
+		s.Buckets = append([]Bucket(nil), s.Buckets...)
+		snapshot[i] = s
+	}
+
+	return snapshot
+}
+
+// Reset forgets all the metrics, e.g. once they were exported at the end of an invocation
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.series = make(map[string]*Series)
+}
+
+func (r *Registry) get(name string, tags map[string]string, kind Kind) *Series {
+	k := key(name, tags)
+
+	s, ok := r.series[k]
+	if !ok {
+		s = &Series{Name: name, Tags: copyTags(tags), Kind: kind}
+		if kind == KindHistogram {
+			s.Buckets = make([]Bucket, len(r.buckets))
+			for i, b := range r.buckets {
+				s.Buckets[i].UpperBound = b
+			}
+		}
+		r.series[k] = s
+	}
+
+	return s
+}
+
+// key identifies the series by the metric name and its tags, in the order of their names
+func key(name string, tags map[string]string) string {
+	b := &strings.Builder{}
+	b.WriteString(name)
+
+	for _, t := range tagNames(tags) {
+		b.WriteString("\x00" + t + "=" + tags[t])
+	}
+
+	return b.String()
+}
+
+func tagNames(tags map[string]string) []string {
+	names := make([]string, 0, len(tags))
+	for t := range tags {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	cp := make(map[string]string, len(tags))
+	for k, v := range tags {
+		cp[k] = v
+	}
+
+	return cp
+}
@@ -0,0 +1,4 @@
+// Package redact keeps the sensitive data out of the logs and the outbound payloads.
+// The fields are marked as sensitive with the `sensitive:"mask|hash|drop"` struct tag, or by their names,
+// and the rules take care of the sensitive strings wherever they are, e.g. the email addresses and the phone numbers.
+package redact
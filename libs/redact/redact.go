@@ -0,0 +1,444 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Tag is the struct tag that marks a field as sensitive, e.g. `sensitive:"mask"`
+const Tag = "sensitive"
+
+// Mode is how a sensitive value is redacted
+type Mode string
+
+const (
+	// Mask replaces the value with Masked
+	Mask Mode = "mask"
+	// Hash replaces the value with its SHA-256 hash, so that the same values can still be told apart from the others
+	Hash Mode = "hash"
+	// Drop replaces the value with its zero value, which leaves it out of the JSON if it's omitempty
+	Drop Mode = "drop"
+)
+
+// Masked is what the masked values are replaced with
+const Masked = "****"
+
+// maxDepth keeps the redaction from following a cycle of pointers forever
+const maxDepth = 32
+
+// Rule redacts the parts of the strings that match the pattern, wherever the strings are
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Mode    Mode
+}
+
+var (
+	// EmailRule masks the email addresses
+	EmailRule = Rule{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+		Mode:    Mask,
+	}
+	// PhoneRule masks the phone numbers in the international format, or with the area code and separators
+	PhoneRule = Rule{
+		Name:    "phone",
+		Pattern: regexp.MustCompile(`\+\d{1,3}[\s.\-]?\(?\d{1,4}\)?(?:[\s.\-]?\d{2,4}){2,4}|\(?\b\d{3}\)?[\s.\-]\d{3}[\s.\-]\d{4}\b`),
+		Mode:    Mask,
+	}
+)
+
+type (
+	// Redactor makes copies of values with their sensitive data redacted
+	Redactor struct {
+		rules  []Rule
+		fields map[string]Mode
+		salt   string
+	}
+	// Option is a func type abstraction of the redactor configuration
+	Option func(*Redactor)
+)
+
+// New creates a new redactor, that redacts the fields tagged as sensitive, and whatever the options add to that
+func New(options ...Option) *Redactor {
+	r := &Redactor{fields: make(map[string]Mode)}
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return r
+}
+
+// Default creates a new redactor that masks the email addresses and the phone numbers on top of the tagged fields
+func Default(options ...Option) *Redactor {
+	return New(append([]Option{Rules(EmailRule, PhoneRule)}, options...)...)
+}
+
+// Rules registers the rules for the strings, on top of the ones registered already
+func Rules(rules ...Rule) Option {
+	return func(r *Redactor) {
+		r.rules = append(r.rules, rules...)
+	}
+}
+
+// Fields redacts the fields and the map entries with the names, regardless of their tags.
+// A struct field goes by its Go name and its JSON name, and the names are case-insensitive
+func Fields(mode Mode, names ...string) Option {
+	return func(r *Redactor) {
+		for _, n := range names {
+			r.fields[strings.ToLower(n)] = mode
+		}
+	}
+}
+
+// Salt is added to the values before they're hashed, for the short ones not to be guessed by hashing them all
+func Salt(salt string) Option {
+	return func(r *Redactor) {
+		r.salt = salt
+	}
+}
+
+// Redact returns a copy of the value, of the same type, with the sensitive data redacted. The value itself is left as is.
+// Unexported fields are copied over without being redacted, so the value is only as safe as its exported fields
+func (r *Redactor) Redact(v interface{}) interface{} {
+	return r.RedactFields(v)
+}
+
+// RedactFields is Redact, with the fields and the map entries with the names masked as well,
+// e.g. the ones a business event says are sensitive
+func (r *Redactor) RedactFields(v interface{}, names ...string) interface{} {
+	if r == nil || v == nil {
+		return v
+	}
+
+	fields := make(map[string]Mode, len(r.fields)+len(names))
+	// the raw JSON in the value has no tags, so it's redacted by the names of the fields tagged anywhere in the value
+	tagged(reflect.ValueOf(v), 0, fields)
+	for n, m := range r.fields {
+		fields[n] = m
+	}
+	for _, n := range names {
+		fields[strings.ToLower(n)] = Mask
+	}
+
+	w := walker{Redactor: r, fields: fields}
+
+	return w.walk(reflect.ValueOf(v), 0).Interface()
+}
+
+// String redacts the parts of the string that match the rules
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+
+	for _, rule := range r.rules {
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			return r.redactString(m, rule.Mode)
+		})
+	}
+
+	return s
+}
+
+func (r *Redactor) redactString(s string, mode Mode) string {
+	switch mode {
+	case Hash:
+		sum := sha256.Sum256([]byte(r.salt + s))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	case Drop:
+		return ""
+	}
+
+	return Masked
+}
+
+type walker struct {
+	*Redactor
+	fields map[string]Mode
+}
+
+// walk copies the value, redacting what's sensitive in it
+func (w walker) walk(v reflect.Value, depth int) reflect.Value {
+	if depth > maxDepth {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if len(w.rules) == 0 {
+			return v
+		}
+		return reflect.ValueOf(w.String(v.String())).Convert(v.Type())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(w.walk(v.Elem(), depth+1))
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(w.walk(v.Elem(), depth+1))
+		return cp
+	case reflect.Struct:
+		return w.walkStruct(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			val := iter.Value()
+			if iter.Key().Kind() == reflect.String {
+				if mode, ok := w.fields[strings.ToLower(iter.Key().String())]; ok {
+					cp.SetMapIndex(iter.Key(), w.apply(val, mode))
+					continue
+				}
+			}
+			cp.SetMapIndex(iter.Key(), w.walk(val, depth+1))
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return w.walkBytes(v, depth)
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(w.walk(v.Index(i), depth+1))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(w.walk(v.Index(i), depth+1))
+		}
+		return cp
+	}
+
+	return v
+}
+
+// walkBytes redacts the raw bytes, which are usually JSON. The JSON fields are redacted by their names,
+// and anything else is left to the rules to make sense of
+func (w walker) walkBytes(v reflect.Value, depth int) reflect.Value {
+	var doc interface{}
+	if err := json.Unmarshal(v.Bytes(), &doc); err == nil {
+		switch doc.(type) {
+		case map[string]interface{}, []interface{}:
+			if jsn, err := json.Marshal(w.walkJSON(doc, depth+1)); err == nil {
+				return reflect.ValueOf(jsn).Convert(v.Type())
+			}
+		}
+	}
+
+	if len(w.rules) == 0 {
+		return v
+	}
+
+	return reflect.ValueOf([]byte(w.String(string(v.Bytes())))).Convert(v.Type())
+}
+
+// walkJSON redacts the decoded JSON in place
+func (w walker) walkJSON(doc interface{}, depth int) interface{} {
+	if depth > maxDepth {
+		return doc
+	}
+
+	switch d := doc.(type) {
+	case string:
+		return w.String(d)
+	case map[string]interface{}:
+		for k, val := range d {
+			if mode, ok := w.fields[strings.ToLower(k)]; ok {
+				d[k] = w.applyJSON(val, mode)
+				continue
+			}
+			d[k] = w.walkJSON(val, depth+1)
+		}
+	case []interface{}:
+		for i := range d {
+			d[i] = w.walkJSON(d[i], depth+1)
+		}
+	}
+
+	return doc
+}
+
+// applyJSON redacts the sensitive decoded JSON the way apply redacts the values
+func (w walker) applyJSON(doc interface{}, mode Mode) interface{} {
+	if mode == Drop {
+		return nil
+	}
+
+	switch d := doc.(type) {
+	case string:
+		return w.redactString(d, mode)
+	case map[string]interface{}:
+		for k, val := range d {
+			d[k] = w.applyJSON(val, mode)
+		}
+		return d
+	case []interface{}:
+		for i := range d {
+			d[i] = w.applyJSON(d[i], mode)
+		}
+		return d
+	}
+
+	return nil
+}
+
+// tagged adds the Go and JSON names of the fields tagged as sensitive anywhere in the value to the fields
+func tagged(v reflect.Value, depth int, fields map[string]Mode) {
+	if depth > maxDepth || !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			tagged(v.Elem(), depth+1, fields)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			if tag, ok := f.Tag.Lookup(Tag); ok {
+				mode := Mode(tag)
+				if mode != Hash && mode != Drop {
+					mode = Mask
+				}
+				fields[strings.ToLower(f.Name)] = mode
+				if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+					fields[strings.ToLower(name)] = mode
+				}
+			}
+
+			tagged(v.Field(i), depth+1, fields)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			tagged(v.Index(i), depth+1, fields)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			tagged(iter.Value(), depth+1, fields)
+		}
+	}
+}
+
+func (w walker) walkStruct(v reflect.Value, depth int) reflect.Value {
+	t := v.Type()
+
+	cp := reflect.New(t).Elem()
+	cp.Set(v)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if mode, ok := w.modeOf(f); ok {
+			cp.Field(i).Set(w.apply(v.Field(i), mode))
+			continue
+		}
+
+		cp.Field(i).Set(w.walk(v.Field(i), depth+1))
+	}
+
+	return cp
+}
+
+// modeOf returns how the field is redacted, by its tag, or by its name
+func (w walker) modeOf(f reflect.StructField) (Mode, bool) {
+	if tag, ok := f.Tag.Lookup(Tag); ok {
+		switch mode := Mode(tag); mode {
+		case Hash, Drop:
+			return mode, true
+		default:
+			return Mask, true
+		}
+	}
+
+	if mode, ok := w.fields[strings.ToLower(f.Name)]; ok {
+		return mode, true
+	}
+
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" {
+		if mode, ok := w.fields[strings.ToLower(name)]; ok {
+			return mode, true
+		}
+	}
+
+	return "", false
+}
+
+// apply redacts the sensitive value. The strings, and the strings in whatever holds them, are masked or hashed,
+// and anything else is dropped, since there's no telling how to mask it
+func (w walker) apply(v reflect.Value, mode Mode) reflect.Value {
+	if mode == Drop {
+		return reflect.Zero(v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(w.redactString(v.String(), mode)).Convert(v.Type())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(w.apply(v.Elem(), mode))
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(w.apply(v.Elem(), mode))
+		return cp
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return reflect.Zero(v.Type())
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(w.apply(v.Index(i), mode))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), w.apply(iter.Value(), mode))
+		}
+		return cp
+	}
+
+	return reflect.Zero(v.Type())
+}
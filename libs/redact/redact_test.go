@@ -0,0 +1,111 @@
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	customer struct {
+		ID       string            `json:"id"`
+		Name     string            `json:"name" sensitive:"mask"`
+		Email    string            `json:"email" sensitive:"hash"`
+		Password string            `json:"password,omitempty" sensitive:"drop"`
+		Phones   []string          `json:"phones" sensitive:"mask"`
+		Age      int               `json:"age" sensitive:"mask"`
+		Notes    string            `json:"notes"`
+		Address  *address          `json:"address"`
+		Data     map[string]string `json:"data"`
+		secret   string
+	}
+	address struct {
+		Street string `json:"street" sensitive:""`
+		City   string `json:"city"`
+	}
+)
+
+func newCustomer() *customer {
+	return &customer{
+		ID:       "c-1",
+		Name:     "Jane Doe",
+		Email:    "jane@example.com",
+		Password: "hunter2",
+		Phones:   []string{"+44 20 7946 0958"},
+		Age:      42,
+		Notes:    "call me at +44 20 7946 0958 or write to jane@example.com",
+		Address:  &address{Street: "1 Main St", City: "London"},
+		Data:     map[string]string{"iban": "GB33BUKB20201555555555", "tier": "gold"},
+		secret:   "shh",
+	}
+}
+
+func TestRedact(t *testing.T) {
+	c := newCustomer()
+
+	r := Default(Fields(Drop, "iban"))
+	red, ok := r.Redact(c).(*customer)
+	require.True(t, ok)
+
+	assert.Equal(t, "c-1", red.ID)
+	assert.Equal(t, Masked, red.Name)
+	assert.Contains(t, red.Email, "sha256:")
+	assert.Empty(t, red.Password)
+	assert.Equal(t, []string{Masked}, red.Phones)
+	assert.Zero(t, red.Age)
+	assert.Equal(t, "call me at "+Masked+" or write to "+Masked, red.Notes)
+	assert.Equal(t, &address{Street: Masked, City: "London"}, red.Address)
+	assert.Equal(t, map[string]string{"iban": "", "tier": "gold"}, red.Data)
+	assert.Equal(t, "shh", red.secret)
+
+	// the value itself is left as it is
+	assert.Equal(t, newCustomer(), c)
+
+	// the same values hash the same
+	assert.Equal(t, red.Email, r.Redact(newCustomer()).(*customer).Email)
+	assert.NotEqual(t, red.Email, New(Salt("pepper")).Redact(newCustomer()).(*customer).Email)
+}
+
+func TestRedactFields(t *testing.T) {
+	r := New()
+
+	red := r.RedactFields(map[string]interface{}{"Name": "Jane", "city": "London"}, "name")
+	assert.Equal(t, map[string]interface{}{"Name": Masked, "city": "London"}, red)
+
+	// without rules, the strings are left as they are
+	assert.Equal(t, "jane@example.com", r.Redact("jane@example.com"))
+}
+
+func TestRedact_RawJSON(t *testing.T) {
+	raw := json.RawMessage(`{"email":"jane@example.com","phone":"(555) 123-4567","at":"2021-11-22T03:04:05+01:00"}`)
+
+	red := Default().Redact(raw).(json.RawMessage)
+	assert.JSONEq(t, `{"email":"****","phone":"****","at":"2021-11-22T03:04:05+01:00"}`, string(red))
+}
+
+func TestRedact_RawJSON_fields(t *testing.T) {
+	type event struct {
+		Customer *customer
+		Raw      []byte
+	}
+	e := event{
+		Customer: newCustomer(),
+		Raw:      []byte(`{"id":"c-2","name":"John","password":"hunter3","age":7,"data":{"iban":"GB33","tier":"gold"}}`),
+	}
+
+	red := New(Fields(Drop, "iban")).Redact(e).(event)
+	assert.JSONEq(t, `{"id":"c-2","name":"****","password":null,"age":null,"data":{"iban":null,"tier":"gold"}}`,
+		string(red.Raw))
+	assert.Equal(t, newCustomer(), e.Customer)
+}
+
+func TestRedact_Nil(t *testing.T) {
+	var r *Redactor
+
+	c := newCustomer()
+	assert.Same(t, c, r.Redact(c))
+	assert.Nil(t, Default().Redact(nil))
+	assert.Equal(t, (*customer)(nil), Default().Redact((*customer)(nil)))
+}
@@ -33,8 +33,6 @@ const (
 	depRepublisher = "Republisher"
 )
 
-// add 'when_to_process' timestamp to business event input, and pass and republish if it's not >= now
-
 type Test struct {
 	Name         string        `json:"test_name" yaml:"test_name"`
 	SourceURI    string        `json:"source_uri" yaml:"source_uri,omitempty"`
@@ -78,8 +76,8 @@ func eventHandler(t *testing.T, testData []byte, ent model.Entity, idx ...int) {
 	logger := zap.NewExample()
 	defer func() { _ = logger.Sync() }()
 
-	undo := zap.ReplaceGlobals(logger)
-	defer undo()
+	// every test file logs through its own logger, so the tests don't fight over the global one
+	ctx := model.WithLogger(context.Background(), model.NewZapLogger(logger))
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -91,12 +89,12 @@ func eventHandler(t *testing.T, testData []byte, ent model.Entity, idx ...int) {
 		i := i
 		test := tests[i]
 		t.Run(test.Name, func(t *testing.T) {
-			eventHandlerInnerTest(t, test, ent, ctrl, i, idx)
+			eventHandlerInnerTest(ctx, t, test, ent, ctrl, i, idx)
 		})
 	}
 }
 
-func eventHandlerInnerTest(t *testing.T, test *Test, ent model.Entity, ctrl *gomock.Controller, i int, idx []int) bool {
+func eventHandlerInnerTest(ctx context.Context, t *testing.T, test *Test, ent model.Entity, ctrl *gomock.Controller, i int, idx []int) bool {
 	if len(test.Inputs) == 0 {
 		t.Fatal("cannot run test without inputs")
 	}
@@ -124,11 +122,12 @@ func eventHandlerInnerTest(t *testing.T, test *Test, ent model.Entity, ctrl *gom
 
 	wg.Add(1)
 
-	handleTest(t, pipeline, ack, wg, test, inChan)
+	handleTest(ctx, t, pipeline, ack, wg, test, inChan)
 	return true
 }
 
 func handleTest(
+	ctx context.Context,
 	t *testing.T,
 	pipeline *pl.Pipeline,
 	ack *actions.MockIAcker,
@@ -154,12 +153,12 @@ func handleTest(
 	}()
 
 	for ins := range inChan {
-		if err := h.Handle(context.Background(), model.NewInputEvent(ins)); err != nil {
+		if err := h.Handle(ctx, model.NewInputEvent(ins)); err != nil {
 			t.Logf("error from the pipeline: %s", err)
 		}
 
 		result := h.GetResult()
-		zap.L().Info("pipeline result", zap.Any("result", result))
+		model.LoggerFrom(ctx).Info("pipeline result", "result", result)
 	}
 }
 
@@ -256,7 +255,7 @@ func MockAcker(t *testing.T, ctrl *gomock.Controller, dep *dependency) *actions.
 				for j, msg := range msgs {
 					jsn, err := msg.MarshalJSON()
 					require.NoError(t, err)
-					zap.L().Info("acknowledging message", zap.Any("msg", json.RawMessage(jsn)))
+					model.LoggerFrom(ctx).Info("acknowledging message", "msg", json.RawMessage(jsn))
 					if len(depCall.ExpectInputs) > 0 {
 						assert.JSONEqf(t, depCall.ExpectInputs[j].(string), string(jsn), "AckMessages() got: %s", string(jsn))
 					}
@@ -306,7 +305,7 @@ func mockAckMessages(t *testing.T, rep *actions.MockIRepublisher, depCall call,
 		for j, msg := range msgs {
 			jsn, err := msg.MarshalJSON()
 			require.NoError(t, err)
-			zap.L().Info("pre-acknowledging message", zap.Any("msg", json.RawMessage(jsn)))
+			model.LoggerFrom(ctx).Info("pre-acknowledging message", "msg", json.RawMessage(jsn))
 			if len(depCall.ExpectInputs) > 0 {
 				assert.JSONEqf(t, depCall.ExpectInputs[j].(string), string(jsn), "AckMessages() got: %s", string(jsn))
 			}
@@ -326,9 +325,9 @@ func mockPublishEvents(
 	ch chan []model.Input,
 ) *actions.MockIRepublisher {
 	rep.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).Do(
-		func(_ context.Context, msgs ...*model.Message) error {
+		func(ctx context.Context, msgs ...*model.Message) error {
 			for j, msg := range msgs {
-				zap.L().Info("re-publishing message", zap.Any("msg", json.RawMessage(msg.GetBody())))
+				model.LoggerFrom(ctx).Info("re-publishing message", "msg", json.RawMessage(msg.GetBody()))
 				if len(depCall.ExpectInputs) > 0 {
 					assert.JSONEqf(t, depCall.ExpectInputs[j].(string), msg.GetBody(), "PublishEvents() got: %s", msg.GetBody())
 				}
@@ -382,7 +381,7 @@ func MockDownloader(t *testing.T, ctrl *gomock.Controller, dep *dependency) *act
 
 		pub.EXPECT().DownloadFileFromBucket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			DoAndReturn(
-				func(_ context.Context, bucket, key string, body io.Writer) error {
+				func(ctx context.Context, bucket, key string, body io.Writer) error {
 					err := mockDownloaderCheckInputs(depCall, bucket, key)
 					if err != nil {
 						return err
@@ -392,7 +391,7 @@ func MockDownloader(t *testing.T, ctrl *gomock.Controller, dep *dependency) *act
 						jsn := []byte(depCall.ExpectOutputs[0].(string))
 						_, err = body.Write(jsn)
 						require.NoError(t, err)
-						zap.L().Info("got file", zap.String("key", key), zap.Any("file", json.RawMessage(jsn)))
+						model.LoggerFrom(ctx).Info("got file", "key", key, "file", json.RawMessage(jsn))
 					}
 
 					if depCall.ExpectError != "" {
@@ -428,9 +427,9 @@ func MockPublisher(t *testing.T, ctrl *gomock.Controller, dep *dependency) *acti
 
 		pub.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).
 			DoAndReturn(
-				func(_ context.Context, msgs ...*model.Message) error {
+				func(ctx context.Context, msgs ...*model.Message) error {
 					for i, msg := range msgs {
-						zap.L().Info("publishing message", zap.Any("msg", json.RawMessage(msg.GetBody())))
+						model.LoggerFrom(ctx).Info("publishing message", "msg", json.RawMessage(msg.GetBody()))
 						if len(depCall.ExpectInputs) == len(msgs) {
 							assert.JSONEqf(t, depCall.ExpectInputs[i].(string), msg.GetBody(), "PublishEvents() got: %s", msg.GetBody())
 						}
@@ -469,13 +468,13 @@ func MockRepository(t *testing.T, ctrl *gomock.Controller, dep *dependency) *act
 func mockGetEntity(t *testing.T, pub *actions.MockIRepository, depCall call) *actions.MockIRepository {
 	pub.EXPECT().GetEntity(gomock.Any(), gomock.Any(), gomock.Any()).
 		DoAndReturn(
-			func(_ context.Context, key model.Key, i interface{}) error {
+			func(ctx context.Context, key model.Key, i interface{}) error {
 				err := mockGetEntityCheckInputs(depCall, key)
 				if err != nil {
 					return err
 				}
 
-				mockGetEntityCheckOutputs(t, depCall, key, i)
+				mockGetEntityCheckOutputs(ctx, t, depCall, key, i)
 
 				if depCall.ExpectError != "" {
 					err = errors.New(depCall.ExpectError)
@@ -511,12 +510,12 @@ func mockGetEntityCheckInputs(depCall call, key model.Key) error {
 	return nil
 }
 
-func mockGetEntityCheckOutputs(t *testing.T, depCall call, key model.Key, i interface{}) {
+func mockGetEntityCheckOutputs(ctx context.Context, t *testing.T, depCall call, key model.Key, i interface{}) {
 	jsn, ok := depCall.ExpectOutputs[0].(string)
 	require.Truef(t, ok, "the output should be a string")
 	err := json.Unmarshal([]byte(jsn), i)
 	require.NoErrorf(t, err, "the output is not a proper json")
-	zap.L().Info("got entity", zap.String("key", model.StringifyKey(key)), zap.Any("entity", json.RawMessage(jsn)))
+	model.LoggerFrom(ctx).Info("got entity", "key", model.StringifyKey(key), "entity", json.RawMessage(jsn))
 }
 
 func mockEntityExists(t *testing.T, pub *actions.MockIRepository, depCall call) *actions.MockIRepository {
@@ -572,7 +571,7 @@ func mockEntityExistsCheckInputs(depCall call, key model.Key) (bool, error) {
 func mockSaveEntities(t *testing.T, pub *actions.MockIRepository, depCall call) *actions.MockIRepository {
 	pub.EXPECT().SaveEntities(gomock.Any(), gomock.Any()).
 		DoAndReturn(
-			func(_ context.Context, entities ...model.Entity) error {
+			func(ctx context.Context, entities ...model.Entity) error {
 				require.Equalf(
 					t,
 					len(depCall.ExpectInputs),
@@ -583,7 +582,7 @@ func mockSaveEntities(t *testing.T, pub *actions.MockIRepository, depCall call)
 					entity := entities[i]
 					jsn, err := json.MarshalIndent(entity, "", "	")
 					require.NoError(t, err)
-					zap.L().Info("saving entity", zap.String("key", model.StringifyKey(entity.GetKey())), zap.Any("entity", json.RawMessage(jsn)))
+					model.LoggerFrom(ctx).Info("saving entity", "key", model.StringifyKey(entity.GetKey()), "entity", json.RawMessage(jsn))
 					assert.JSONEqf(t, depCall.ExpectInputs[i].(string), string(jsn), "SaveEntities() got: %s", string(jsn))
 				}
 
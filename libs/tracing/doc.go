@@ -0,0 +1,3 @@
+// Package tracing provides an in-memory tracer that records the spans of UBE pipelines,
+// to inspect them in tests, or to export them to a tracing backend of choice.
+package tracing
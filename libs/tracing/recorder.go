@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+var _ model.Tracer = (*Recorder)(nil)
+
+// RecordedSpan is a span that ended
+type RecordedSpan struct {
+	Name         string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Attributes   map[string]interface{}
+	Links        []model.SpanContext
+	Err          error
+	Start        time.Time
+	End          time.Time
+}
+
+// Recorder is a tracer that keeps the spans in memory once they end
+type Recorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// NewRecorder creates a new, empty, in-memory span recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start starts a span as the child of the span of the context, or of the remote span context, or as the root of a new trace
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, model.Span) {
+	s := &span{
+		recorder: r,
+		rec: RecordedSpan{
+			Name:       name,
+			SpanID:     randomHex(8),
+			Attributes: make(map[string]interface{}),
+			Start:      model.Now(),
+		},
+	}
+
+	if parent, ok := model.ParentSpanContext(ctx); ok {
+		s.rec.TraceID = parent.TraceID
+		s.rec.ParentSpanID = parent.SpanID
+	} else {
+		s.rec.TraceID = randomHex(16)
+	}
+
+	return model.WithSpan(ctx, s), s
+}
+
+// Spans returns the spans that ended, in the order they ended
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RecordedSpan(nil), r.spans...)
+}
+
+// Reset forgets all the spans
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = nil
+}
+
+func (r *Recorder) record(rec RecordedSpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, rec)
+}
+
+type span struct {
+	recorder *Recorder
+	mu       sync.Mutex
+	rec      RecordedSpan
+	ended    bool
+}
+
+func (s *span) SpanContext() model.SpanContext {
+	return model.SpanContext{TraceID: s.rec.TraceID, SpanID: s.rec.SpanID, Sampled: true}
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rec.Attributes[key] = value
+}
+
+func (s *span) AddLink(sc model.SpanContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rec.Links = append(s.rec.Links, sc)
+}
+
+// End records the span, only the first time it's called
+func (s *span) End(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.rec.Err = err
+	s.rec.End = model.Now()
+
+	rec := s.rec
+	rec.Attributes = make(map[string]interface{}, len(s.rec.Attributes))
+	for k, v := range s.rec.Attributes {
+		rec.Attributes[k] = v
+	}
+	s.mu.Unlock()
+
+	s.recorder.record(rec)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("the system is out of randomness: " + err.Error())
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zale144/ube/model"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+
+	ctx, root := r.Start(context.Background(), "root")
+	root.SetAttribute("ube.events", 2)
+
+	_, child := r.Start(ctx, "child")
+	child.AddLink(model.SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	child.End(errors.New("boom"))
+	child.End(nil)
+	root.End(nil)
+
+	spans := r.Spans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.EqualError(t, spans[0].Err, "boom")
+	assert.Len(t, spans[0].Links, 1)
+
+	assert.Equal(t, "root", spans[1].Name)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Equal(t, 2, spans[1].Attributes["ube.events"])
+	assert.Len(t, spans[1].TraceID, 32)
+	assert.Len(t, spans[1].SpanID, 16)
+
+	r.Reset()
+	assert.Empty(t, r.Spans())
+}
+
+func TestRecorder_RemoteParent(t *testing.T) {
+	r := NewRecorder()
+
+	sc, ok := model.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+
+	_, s := r.Start(model.WithRemoteSpanContext(context.Background(), sc), "consumer")
+	s.End(nil)
+
+	spans := r.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, sc.TraceID, spans[0].TraceID)
+	assert.Equal(t, sc.SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spans[0].SpanID+"-01", s.SpanContext().TraceParent())
+}
@@ -0,0 +1,18 @@
+package model
+
+// Alert reports the business events that failed at the same action with the same error
+type Alert struct {
+	Action      string `json:"action"`
+	ActionIndex int    `json:"action_index"`
+	Error       string `json:"error"`
+	// Events are the business events that failed, each of them only once
+	Events []AlertedEvent `json:"events"`
+}
+
+// AlertedEvent identifies a business event that failed
+type AlertedEvent struct {
+	ID        string `json:"id"`
+	EventName string `json:"event_name,omitempty"`
+	// MessageID is the ID of the message that brought the business event in
+	MessageID string `json:"message_id,omitempty"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // Request is the generic API request
 type Request struct {
@@ -46,3 +49,14 @@ type Response struct {
 	Headers    map[string]string `json:"headers"`
 	Body       string            `json:"body"`
 }
+
+// GetTraceParent returns the trace context of the traceparent header (implements model.TraceCarrier)
+func (r Request) GetTraceParent() string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, TraceParentHeader) {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"go.uber.org/zap"
 
 	"github.com/zale144/ube/libs/validate"
 )
@@ -27,7 +26,13 @@ type BusinessEvent struct {
 	Error                 error         `json:"-"`
 	PreviousActionMandate ActionMandate `json:"-"`
 	PreviousAction        int           `json:"-"`
+	PreviousActionName    string        `json:"-"`
+	PreviousActionID      string        `json:"-"`
+	PendingActionIDs      []string      `json:"pending_action_ids,omitempty"`
 	RepublishAttempt      *int          `json:"is_republish,omitempty"`
+	NotBefore             *time.Time    `json:"not_before,omitempty"`
+	TraceParent           string        `json:"trace_parent,omitempty"`
+	GroupID               string        `json:"group_id,omitempty"`
 }
 
 var (
@@ -40,12 +45,68 @@ type ActionMandate int
 const (
 	StopFurtherProcessing ActionMandate = 1 << iota
 	ProcessOnlyCriticalActions
-	// StopAndPark
 	LogFailureAndContinue
 	StopAndRaiseError
 	StopAndRetry
+	StopAndPark
 )
 
+func (m ActionMandate) String() string {
+	switch m {
+	case StopFurtherProcessing:
+		return "StopFurtherProcessing"
+	case ProcessOnlyCriticalActions:
+		return "ProcessOnlyCriticalActions"
+	case LogFailureAndContinue:
+		return "LogFailureAndContinue"
+	case StopAndRaiseError:
+		return "StopAndRaiseError"
+	case StopAndRetry:
+		return "StopAndRetry"
+	case StopAndPark:
+		return "StopAndPark"
+	}
+
+	return ""
+}
+
+// ParseActionMandate returns the mandate with the provided name
+func ParseActionMandate(name string) (ActionMandate, error) {
+	for _, m := range []ActionMandate{
+		StopFurtherProcessing,
+		ProcessOnlyCriticalActions,
+		LogFailureAndContinue,
+		StopAndRaiseError,
+		StopAndRetry,
+		StopAndPark,
+	} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown action mandate '%s'", name)
+}
+
+// ErrDeadlineExceeded marks a business event that was not processed because the invocation was about to time out.
+// Such events are republished to resume at the same action, without it counting as a retry attempt
+var ErrDeadlineExceeded = errors.New("too close to the deadline to process the business event")
+
+// ErrActionTimeout marks a business event that was not processed because the action ran out of its time before it
+// got to its batch. Such events go through the failure mandate of the action
+var ErrActionTimeout = errors.New("the action ran out of time before it got to the business event")
+
+// ErrTooEarly marks a republished business event that arrived before its not-before time. Such events are
+// deferred again until that time, without it counting as a retry attempt
+var ErrTooEarly = errors.New("too early to process the business event")
+
+// ErrNotRepublished marks a business event that failed to go back to the queue. It's not republished again in the same
+// invocation, so its message is left to the queue to redeliver
+var ErrNotRepublished = errors.New("business event was not republished")
+
+// ErrUnknownAction marks a republished business event that should resume at an action the pipeline no longer has
+var ErrUnknownAction = errors.New("the action to resume at is not in the pipeline")
+
 // UnmarshalJSON overrides the default method
 func (be *BusinessEvent) UnmarshalJSON(data []byte) error {
 	if be.entity == nil {
@@ -92,8 +153,16 @@ func (be *BusinessEvent) UnmarshalJSON(data []byte) error {
 
 	*be = BusinessEvent(*aux)
 
-	if pa, ok := m["previous_action"]; ok {
-		be.PreviousAction = int(pa.(float64))
+	// the events republished before the actions had IDs carry just the index of the action
+	switch pa := m["previous_action"].(type) {
+	case float64:
+		be.PreviousAction = int(pa)
+	case string:
+		be.PreviousActionID = pa
+	}
+
+	if id, ok := m["previous_action_id"].(string); ok {
+		be.PreviousActionID = id
 	}
 
 	return nil
@@ -138,6 +207,15 @@ func InputsToBusinessEvents(ins []Input, entity Entity) ([]Medium, error) {
 			be.entity = entity
 		}
 
+		// the trace context the message was sent with is fresher than the one of the business event
+		if tc, ok := in.(TraceCarrier); ok && tc.GetTraceParent() != "" {
+			be.TraceParent = tc.GetTraceParent()
+		}
+
+		if gc, ok := in.(GroupCarrier); ok && gc.GetGroupID() != "" {
+			be.GroupID = gc.GetGroupID()
+		}
+
 		if len(be.Entities) == 0 {
 			be.Entities = append(be.Entities, entity)
 		}
@@ -164,7 +242,7 @@ func (be *BusinessEvent) InitEntity(entType reflect.Type) error {
 	be.entity = reflect.New(entType).Interface().(Entity)
 
 	if err := json.Unmarshal(be.Body, &be.entity); err != nil {
-		zap.L().Warn("input is not a single record, retrying to unmarshal as a list: %s", zap.Error(err))
+		L().Warn("input is not a single record, retrying to unmarshal as a list: %s", "error", err)
 
 		list := make([]interface{}, 0)
 		if err = json.Unmarshal(be.Body, &list); err != nil {
@@ -234,10 +312,19 @@ func (be *BusinessEvent) GetID() string {
 	return be.ID
 }
 
+// GetSensitiveDataFieldNames returns the names of the fields the event of the business event says are sensitive
+func (be *BusinessEvent) GetSensitiveDataFieldNames() []string {
+	if be == nil || be.Event == nil {
+		return nil
+	}
+
+	return be.Event.SensitiveDataFieldNames
+}
+
 // GetEvent gets the event if exists
 func (be *BusinessEvent) GetEvent() *Event {
 	if be.Event == nil {
-		zap.L().Error("no event")
+		L().Error("no event")
 		return &Event{}
 	}
 
@@ -298,6 +385,75 @@ func (be *BusinessEvent) SetPreviousAction(prevAct int) {
 	be.PreviousAction = prevAct
 }
 
+func (be *BusinessEvent) GetPreviousActionName() string {
+	return be.PreviousActionName
+}
+
+func (be *BusinessEvent) SetPreviousActionName(name string) {
+	be.PreviousActionName = name
+}
+
+func (be *BusinessEvent) GetPreviousActionID() string {
+	return be.PreviousActionID
+}
+
+func (be *BusinessEvent) SetPreviousActionID(id string) {
+	be.PreviousActionID = id
+}
+
+// GetPendingActionIDs returns the IDs of the other actions the business event failed at, alongside the previous one,
+// in a layer of actions that run at the same time. A republished business event resumes at all of them
+func (be *BusinessEvent) GetPendingActionIDs() []string {
+	return be.PendingActionIDs
+}
+
+func (be *BusinessEvent) SetPendingActionIDs(ids []string) {
+	be.PendingActionIDs = ids
+}
+
+// GetTraceParent returns the trace context of the last pipeline the business event went through
+func (be *BusinessEvent) GetTraceParent() string {
+	return be.TraceParent
+}
+
+func (be *BusinessEvent) SetTraceParent(traceParent string) {
+	be.TraceParent = traceParent
+}
+
+// GetSensitiveFieldsEncrypted tells whether the sensitive fields of the entities are encrypted
+func (be *BusinessEvent) GetSensitiveFieldsEncrypted() bool {
+	return be.Event != nil && be.Event.SensitiveFieldsEncrypted
+}
+
+// GetEncryptionKeyIDs returns the IDs of the keys that wrapped the data keys of the encrypted fields
+func (be *BusinessEvent) GetEncryptionKeyIDs() []string {
+	if be.Event == nil {
+		return nil
+	}
+
+	return be.Event.EncryptionKeyIDs
+}
+
+// SetSensitiveFieldsEncrypted records whether the sensitive fields of the entities are encrypted, and the IDs of the keys
+// that wrapped their data keys
+func (be *BusinessEvent) SetSensitiveFieldsEncrypted(encrypted bool, keyIDs []string) {
+	if be.Event == nil {
+		be.Event = &Event{}
+	}
+
+	be.Event.SensitiveFieldsEncrypted = encrypted
+	be.Event.EncryptionKeyIDs = keyIDs
+}
+
+// GetGroupID returns the group of the business events it's processed in order with, if it came with one
+func (be *BusinessEvent) GetGroupID() string {
+	return be.GroupID
+}
+
+func (be *BusinessEvent) SetGroupID(groupID string) {
+	be.GroupID = groupID
+}
+
 func (be *BusinessEvent) SetEventProcessedTime(t time.Time) {
 	if be.Event == nil {
 		return
@@ -324,6 +480,23 @@ func (be *BusinessEvent) IncrementRepublishAttempt() {
 	*be.RepublishAttempt++
 }
 
+// GetNotBefore returns the time before which the business event must not be processed, zero meaning any time
+func (be *BusinessEvent) GetNotBefore() time.Time {
+	if be.NotBefore == nil {
+		return time.Time{}
+	}
+	return *be.NotBefore
+}
+
+// SetNotBefore sets the time before which the business event must not be processed, zero clearing it
+func (be *BusinessEvent) SetNotBefore(t time.Time) {
+	if t.IsZero() {
+		be.NotBefore = nil
+		return
+	}
+	be.NotBefore = &t
+}
+
 func (be *BusinessEvent) SetPreviousActionMandate(mandate ActionMandate) {
 	be.PreviousActionMandate = mandate
 }
@@ -388,3 +561,34 @@ func (be *BusinessEvent) GetEventSource() string {
 	}
 	return be.Event.EventSource
 }
+
+// LogProcessing adds an entry to the processing journal of the business event, kept in the event ChangeLog,
+// so it's published and republished along with the event
+func (be *BusinessEvent) LogProcessing(entry *MessageProcessingLog) {
+	if be.Event == nil {
+		be.Event = &Event{}
+	}
+
+	be.Event.ChangeLog = append(be.Event.ChangeLog, &LogMessage{
+		ID:            UUIDStr(),
+		LogType:       ProcessingLogType,
+		Timestamp:     entry.EndTime,
+		ProcessingLog: entry,
+	})
+}
+
+// GetProcessingLog returns the processing journal of the business event, in the order the entries were added
+func (be *BusinessEvent) GetProcessingLog() []*MessageProcessingLog {
+	if be.Event == nil {
+		return nil
+	}
+
+	var journal []*MessageProcessingLog
+	for _, msg := range be.Event.ChangeLog {
+		if msg.LogType == ProcessingLogType && msg.ProcessingLog != nil {
+			journal = append(journal, msg.ProcessingLog)
+		}
+	}
+
+	return journal
+}
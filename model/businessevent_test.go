@@ -82,6 +82,45 @@ func TestBusinessEvent_UnmarshalJSON(t *testing.T) {
 				return err == nil
 			},
 		},
+		{
+			name: "success: with previous_action_id",
+			args: args{
+				data:   []byte(`{"event": {"event_category": "product"}, "product": [{}], "previous_action": 1, "previous_action_id": "Persister:products"}`),
+				entity: &Product{},
+			},
+			want: &BusinessEvent{
+				Event: &Event{
+					EventHeader: EventHeader{
+						EventCategory: "product",
+					},
+				},
+				Entities:         []Entity{&Product{}},
+				PreviousAction:   1,
+				PreviousActionID: "Persister:products",
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return err == nil
+			},
+		},
+		{
+			name: "success: with previous_action as an ID",
+			args: args{
+				data:   []byte(`{"event": {"event_category": "product"}, "product": [{}], "previous_action": "Persister"}`),
+				entity: &Product{},
+			},
+			want: &BusinessEvent{
+				Event: &Event{
+					EventHeader: EventHeader{
+						EventCategory: "product",
+					},
+				},
+				Entities:         []Entity{&Product{}},
+				PreviousActionID: "Persister",
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return err == nil
+			},
+		},
 		{
 			name: "failure: unmarshal into map",
 			args: args{
@@ -393,3 +432,19 @@ func (b PBaseKey) PK() string {
 func (p Product) GetKey() Key {
 	return p.PBaseKey
 }
+
+func TestBusinessEvent_LogProcessing(t *testing.T) {
+	be := &BusinessEvent{}
+	be.Event = &Event{ChangeLog: []*LogMessage{{LogType: "audit"}}}
+
+	first := &MessageProcessingLog{ProcessorID: "first", Status: ProcessingSucceeded, EndTime: "2021-11-12T00:00:00Z"}
+	second := &MessageProcessingLog{ProcessorID: "second", Status: ProcessingFailed, Mandate: StopAndRetry.String()}
+
+	be.LogProcessing(first)
+	be.LogProcessing(second)
+
+	assert.Len(t, be.Event.ChangeLog, 3)
+	assert.Equal(t, "2021-11-12T00:00:00Z", be.Event.ChangeLog[1].Timestamp)
+	assert.Equal(t, []*MessageProcessingLog{first, second}, be.GetProcessingLog())
+	assert.Equal(t, "StopAndRetry", second.Mandate)
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// DeadLetter is a business event that ran out of retry attempts, along with what made it fail
+type DeadLetter struct {
+	ID             string `json:"id"`
+	EventName      string `json:"event_name,omitempty"`
+	Action         string `json:"action"`
+	ActionIndex    int    `json:"action_index"`
+	ActionID       string `json:"action_id,omitempty"`
+	Attempts       int    `json:"attempts"`
+	Error          string `json:"error"`
+	DeadLetteredAt string `json:"dead_lettered_at"`
+	// RawData is the original data the business event was created from
+	RawData [][]byte `json:"raw_data,omitempty"`
+	// Event is the serialised business event
+	Event json.RawMessage `json:"event"`
+}
+
+// ErrDeadLettered marks a business event that ran out of retry attempts and was sent to the dead letter queue
+var ErrDeadLettered = errors.New("business event was dead-lettered")
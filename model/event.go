@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type EventHeader struct {
 	EventName     string `json:"event_name,omitempty"`
 	EventCategory string `json:"event_category,omitempty"`
@@ -23,6 +25,7 @@ type Event struct {
 	RecordUpdated            bool              `json:"record_updated,omitempty"`
 	RequiresRecordUpdate     bool              `json:"requires_record_update,omitempty"`
 	SensitiveFieldsEncrypted bool              `json:"sensitive_fields_encrypted,omitempty"`
+	EncryptionKeyIDs         []string          `json:"encryption_key_ids,omitempty"` // the keys that wrapped the data keys of the encrypted fields
 	ExternalEventID          string            `json:"external_event_id,omitempty"`
 	Data                     map[string]string `json:"data,omitempty"`
 	SensitiveDataFieldNames  []string          `json:"sensitive_data_field_names,omitempty"`
@@ -75,15 +78,33 @@ type SensitiveDataAccessLog struct {
 	RequestID      string   `json:"request_id,omitempty"`
 }
 
-// MessageProcessingLog -
+// MessageProcessingLog - an entry of the processing journal, describing one attempt of a pipeline action
 type MessageProcessingLog struct {
-	ProcessedEventID   string `json:"processed_event_id,omitempty"`
-	ProcessedEventName string `json:"processed_event_name,omitempty"`
-	ProcessorID        string `json:"processor_id,omitempty"`
-	Status             string `json:"status,omitempty"`
-	Error              string `json:"error,omitempty"`
+	ProcessedEventID   string        `json:"processed_event_id,omitempty"`
+	ProcessedEventName string        `json:"processed_event_name,omitempty"`
+	ProcessorID        string        `json:"processor_id,omitempty"` // the name of the pipeline action
+	ProcessorIndex     int           `json:"processor_index"`
+	Status             string        `json:"status,omitempty"`
+	Error              string        `json:"error,omitempty"`
+	StartTime          string        `json:"start_time,omitempty"`
+	EndTime            string        `json:"end_time,omitempty"`
+	Duration           time.Duration `json:"duration,omitempty"`
+	EntityCount        int           `json:"entity_count,omitempty"`
+	Mandate            string        `json:"mandate,omitempty"` // the failure mandate applied to the event
+	Attempt            int           `json:"attempt,omitempty"` // the republish attempt
+	Skipped            bool          `json:"skipped,omitempty"`
 }
 
+// Processing journal entry statuses
+const (
+	ProcessingSucceeded = "Succeeded"
+	ProcessingFailed    = "Failed"
+	ProcessingSkipped   = "Skipped"
+)
+
+// ProcessingLogType is the LogType of the processing journal entries in the ChangeLog
+const ProcessingLogType = "processing"
+
 type DerivedEventDetails struct {
 	ID                string `json:"id,omitempty"`
 	EventName         string `json:"event_name,omitempty"`
@@ -0,0 +1,22 @@
+package model
+
+import "errors"
+
+// IdempotencyState is the state of a message in the idempotency store
+type IdempotencyState string
+
+const (
+	// IdempotencyNew is the state of a message that was not seen before, or whose record expired
+	IdempotencyNew IdempotencyState = ""
+	// IdempotencyInProgress is the state of a message that is being processed
+	IdempotencyInProgress IdempotencyState = "in_progress"
+	// IdempotencyCompleted is the state of a message that was processed successfully
+	IdempotencyCompleted IdempotencyState = "completed"
+)
+
+var (
+	// ErrDuplicate marks a message that was processed already, so it skipped the pipeline
+	ErrDuplicate = errors.New("message was processed already")
+	// ErrInFlight marks a message that another invocation is processing, so it skipped the pipeline
+	ErrInFlight = errors.New("message is being processed by another invocation")
+)
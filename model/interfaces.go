@@ -32,11 +32,17 @@ type PipelineMedium interface {
 	Medium
 	GetPreviousAction() int
 	SetPreviousAction(int)
+	GetPreviousActionName() string
+	SetPreviousActionName(string)
+	GetPreviousActionID() string
+	SetPreviousActionID(string)
 	SetEventProcessedTime(time.Time)
 	GetPreviousActionMandate() ActionMandate
 	GetRepublishAttempt() *int
 	SetRepublishAttempt(*int)
 	IncrementRepublishAttempt()
+	GetNotBefore() time.Time
+	SetNotBefore(time.Time)
 	SetPreviousActionMandate(ActionMandate)
 	GetEventID() string
 	GetEventReference() string
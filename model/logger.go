@@ -0,0 +1,112 @@
+package model
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Logger is what UBE logs through, with the fields as alternating keys and values, the way logr and slog have them.
+// Zap is the default, so everything keeps logging where it did, unless another logger is plugged in.
+// The loggers that come with UBE redact the sensitive data out of the values, see Redactor
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+	// With returns a logger that adds the keys and values to everything it logs
+	With(keysAndValues ...interface{}) Logger
+}
+
+type loggerHolder struct {
+	Logger
+}
+
+var defaultLogger atomic.Value
+
+func init() {
+	defaultLogger.Store(loggerHolder{zapGlobal{}})
+}
+
+// L returns the default logger, the one used when there's none in the context
+func L() Logger {
+	return defaultLogger.Load().(loggerHolder).Logger
+}
+
+// SetLogger replaces the default logger, returning a func that puts the previous one back
+func SetLogger(l Logger) func() {
+	prev := L()
+	defaultLogger.Store(loggerHolder{l})
+
+	return func() {
+		defaultLogger.Store(loggerHolder{prev})
+	}
+}
+
+type loggerKey struct{}
+
+// WithLogger returns the context that carries the logger, for everything down the line to log through it
+func WithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFrom returns the logger of the context, or the default one if there isn't any
+func LoggerFrom(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+			return l
+		}
+	}
+
+	return L()
+}
+
+// EventLogger returns the logger of the context, scoped to the business event
+func EventLogger(ctx context.Context, be Medium) Logger {
+	return LoggerFrom(ctx).With("event_id", be.GetID(), "event_name", be.GetEventName())
+}
+
+// NewZapLogger adapts the zap logger to a Logger
+func NewZapLogger(l *zap.Logger) Logger {
+	return zapLogger{s: l.WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+type zapLogger struct {
+	s *zap.SugaredLogger
+}
+
+func (l zapLogger) Debug(msg string, kv ...interface{}) { l.s.Debugw(msg, redactKV(kv)...) }
+func (l zapLogger) Info(msg string, kv ...interface{})  { l.s.Infow(msg, redactKV(kv)...) }
+func (l zapLogger) Warn(msg string, kv ...interface{})  { l.s.Warnw(msg, redactKV(kv)...) }
+func (l zapLogger) Error(msg string, kv ...interface{}) { l.s.Errorw(msg, redactKV(kv)...) }
+
+func (l zapLogger) With(kv ...interface{}) Logger {
+	return zapLogger{s: l.s.With(redactKV(kv)...)}
+}
+
+// zapGlobal logs through the global zap logger of the moment, so that replacing it still works the way it used to
+type zapGlobal struct{}
+
+func (zapGlobal) sugar() *zap.SugaredLogger {
+	return zap.L().WithOptions(zap.AddCallerSkip(1)).Sugar()
+}
+
+func (l zapGlobal) Debug(msg string, kv ...interface{}) { l.sugar().Debugw(msg, redactKV(kv)...) }
+func (l zapGlobal) Info(msg string, kv ...interface{})  { l.sugar().Infow(msg, redactKV(kv)...) }
+func (l zapGlobal) Warn(msg string, kv ...interface{})  { l.sugar().Warnw(msg, redactKV(kv)...) }
+func (l zapGlobal) Error(msg string, kv ...interface{}) { l.sugar().Errorw(msg, redactKV(kv)...) }
+
+func (zapGlobal) With(kv ...interface{}) Logger {
+	return zapLogger{s: zap.L().Sugar().With(redactKV(kv)...)}
+}
+
+// NopLogger is a logger that logs nothing
+type NopLogger struct{}
+
+func (NopLogger) Debug(string, ...interface{}) {}
+func (NopLogger) Info(string, ...interface{})  {}
+func (NopLogger) Warn(string, ...interface{})  {}
+func (NopLogger) Error(string, ...interface{}) {}
+
+func (l NopLogger) With(...interface{}) Logger { return l }
@@ -0,0 +1,23 @@
+//go:build go1.21
+
+package model
+
+import "log/slog"
+
+// NewSlogLogger adapts the slog logger to a Logger
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (l slogLogger) Debug(msg string, kv ...interface{}) { l.l.Debug(msg, redactKV(kv)...) }
+func (l slogLogger) Info(msg string, kv ...interface{})  { l.l.Info(msg, redactKV(kv)...) }
+func (l slogLogger) Warn(msg string, kv ...interface{})  { l.l.Warn(msg, redactKV(kv)...) }
+func (l slogLogger) Error(msg string, kv ...interface{}) { l.l.Error(msg, redactKV(kv)...) }
+
+func (l slogLogger) With(kv ...interface{}) Logger {
+	return slogLogger{l: l.l.With(redactKV(kv)...)}
+}
@@ -0,0 +1,26 @@
+//go:build go1.21
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := NewSlogLogger(slog.New(slog.NewJSONHandler(b, nil))).With("pipeline", "products")
+
+	l.Info("hello", "size", 2)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &line))
+	assert.Equal(t, "hello", line["msg"])
+	assert.Equal(t, "products", line["pipeline"])
+	assert.Equal(t, float64(2), line["size"])
+}
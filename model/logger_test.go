@@ -0,0 +1,54 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := NewZapLogger(zap.New(core)).With("pipeline", "products")
+
+	l.Info("hello", "size", 2)
+	l.Error("oops", "error", errors.New("boom"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"pipeline": "products", "size": int64(2)}, entries[0].ContextMap())
+	assert.Equal(t, "boom", entries[1].ContextMap()["error"])
+}
+
+func TestLoggerFrom(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	undo := zap.ReplaceGlobals(zap.New(core))
+	defer undo()
+
+	// the default logger logs through the global zap logger of the moment
+	LoggerFrom(context.Background()).Info("default")
+	require.Equal(t, 1, logs.Len())
+
+	ctxCore, ctxLogs := observer.New(zap.DebugLevel)
+	ctx := WithLogger(context.Background(), NewZapLogger(zap.New(ctxCore)))
+
+	be := &BusinessEvent{ID: "1", Event: &Event{EventHeader: EventHeader{EventName: "created"}}}
+	EventLogger(ctx, be).Warn("scoped")
+
+	require.Equal(t, 1, ctxLogs.Len())
+	assert.Equal(t, map[string]interface{}{"event_id": "1", "event_name": "created"}, ctxLogs.All()[0].ContextMap())
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestSetLogger(t *testing.T) {
+	undo := SetLogger(NopLogger{})
+	assert.Equal(t, NopLogger{}, L())
+	assert.Equal(t, NopLogger{}, LoggerFrom(nil))
+
+	undo()
+	assert.Equal(t, zapGlobal{}, L())
+}
@@ -10,20 +10,28 @@ type Message struct {
 	Reference string          `json:"reference"`
 	SourceURI string          `json:"source_uri"`
 	Body      json.RawMessage `json:"body"`
+	// TraceParent is the trace context the message is sent or received with, e.g. as a message attribute
+	TraceParent string `json:"trace_parent,omitempty"`
+	// GroupID is the group of the messages that are processed in the order they were sent, e.g. the SQS FIFO message group ID
+	GroupID string `json:"group_id,omitempty"`
 }
 
 func (m *Message) MarshalJSON() ([]byte, error) {
 	type tempMsg struct {
-		ID        string          `json:"id,omitempty"`
-		Reference string          `json:"reference,omitempty"`
-		Body      json.RawMessage `json:"body,omitempty"`
-		SourceURI string          `json:"source_uri,omitempty"`
+		ID          string          `json:"id,omitempty"`
+		Reference   string          `json:"reference,omitempty"`
+		Body        json.RawMessage `json:"body,omitempty"`
+		SourceURI   string          `json:"source_uri,omitempty"`
+		TraceParent string          `json:"trace_parent,omitempty"`
+		GroupID     string          `json:"group_id,omitempty"`
 	}
 	tm := &tempMsg{
-		ID:        m.ID,
-		Reference: m.Reference,
-		Body:      []byte(m.Body),
-		SourceURI: m.SourceURI,
+		ID:          m.ID,
+		Reference:   m.Reference,
+		Body:        []byte(m.Body),
+		SourceURI:   m.SourceURI,
+		TraceParent: m.TraceParent,
+		GroupID:     m.GroupID,
 	}
 	return json.MarshalIndent(tm, "", "	")
 }
@@ -47,3 +55,13 @@ func (m Message) GetSourceURI() string {
 func (m Message) GetBody() string {
 	return string(m.Body)
 }
+
+// GetTraceParent returns the trace context the message came with (implements model.TraceCarrier)
+func (m Message) GetTraceParent() string {
+	return m.TraceParent
+}
+
+// GetGroupID returns the group the message was sent to (implements model.GroupCarrier)
+func (m Message) GetGroupID() string {
+	return m.GroupID
+}
@@ -0,0 +1,90 @@
+package model
+
+import "context"
+
+// the metrics the pipeline, its actions and the handlers report
+const (
+	// MetricEventsIn counts the messages that came into the pipeline
+	MetricEventsIn = "ube_events_in_total"
+	// MetricEventsOut counts the business events that went out of the pipeline, by status
+	MetricEventsOut = "ube_events_out_total"
+	// MetricActionDuration is the histogram of how long the action batches took, in seconds
+	MetricActionDuration = "ube_action_duration_seconds"
+	// MetricActionBatchSize is the histogram of how many business events the action batches had
+	MetricActionBatchSize = "ube_action_batch_size"
+	// MetricActionErrors counts the business events that failed at an action, by failure mandate
+	MetricActionErrors = "ube_action_errors_total"
+	// MetricActionRetries counts the business events an action retried within the invocation
+	MetricActionRetries = "ube_action_retries_total"
+	// MetricRepublished counts the business events the Republisher sent back to the queue, or to the dead letter one
+	MetricRepublished = "ube_republished_total"
+	// MetricAcks counts the messages acknowledged by the handler, by status
+	MetricAcks = "ube_acks_total"
+)
+
+// Metrics is where the pipeline statistics are reported to
+type Metrics interface {
+	// Count adds the value to the counter with the given tags
+	Count(name string, value float64, tags map[string]string)
+	// Observe records the value in the histogram with the given tags
+	Observe(name string, value float64, tags map[string]string)
+}
+
+type metricsKey struct{}
+
+// WithMetrics returns the context that the metrics are reported through
+func WithMetrics(ctx context.Context, m Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// MetricsFrom returns the metrics of the context, or ones that go nowhere if there aren't any
+func MetricsFrom(ctx context.Context) Metrics {
+	if ctx != nil {
+		if m, ok := ctx.Value(metricsKey{}).(Metrics); ok {
+			return m
+		}
+	}
+
+	return noMetrics{}
+}
+
+// WithMetricTags returns the context whose metrics have the tags added to everything reported through it
+func WithMetricTags(ctx context.Context, tags map[string]string) context.Context {
+	m := MetricsFrom(ctx)
+	if _, ok := m.(noMetrics); ok {
+		return ctx
+	}
+
+	return WithMetrics(ctx, taggedMetrics{metrics: m, tags: tags})
+}
+
+type taggedMetrics struct {
+	metrics Metrics
+	tags    map[string]string
+}
+
+func (t taggedMetrics) Count(name string, value float64, tags map[string]string) {
+	t.metrics.Count(name, value, t.with(tags))
+}
+
+func (t taggedMetrics) Observe(name string, value float64, tags map[string]string) {
+	t.metrics.Observe(name, value, t.with(tags))
+}
+
+// with merges the tags with the ones of the context, the given ones taking precedence
+func (t taggedMetrics) with(tags map[string]string) map[string]string {
+	merged := make(map[string]string, len(t.tags)+len(tags))
+	for k, v := range t.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+type noMetrics struct{}
+
+func (noMetrics) Count(string, float64, map[string]string)   {}
+func (noMetrics) Observe(string, float64, map[string]string) {}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// MessageGroupIDAttribute is the SQS attribute with the message group ID of the messages from FIFO queues
+const MessageGroupIDAttribute = "MessageGroupId"
+
+// ErrHeldBack fails the business events of an ordered action whose key an earlier business event failed with,
+// for them not to overtake it
+var ErrHeldBack = errors.New("an earlier business event with the same key failed")
+
+type (
+	// GroupCarrier is an input or a business event that belongs to a group whose members must be processed
+	// in the order they came in, e.g. a message from an SQS FIFO queue
+	GroupCarrier interface {
+		GetGroupID() string
+	}
+	// OrderKeyFn returns the key of the business event, the business events with the same key being processed
+	// in the order they came in
+	OrderKeyFn func(be Medium) string
+)
+
+// EntitiesKey returns the keys of the entities of the business event, or its ID if it has no entities
+func EntitiesKey(be Medium) string {
+	ents := be.GetEntities()
+	if len(ents) == 0 {
+		return be.GetID()
+	}
+
+	keys := make([]string, 0, len(ents))
+	for _, ent := range ents {
+		if ent == nil || ent.GetKey() == nil {
+			continue
+		}
+		keys = append(keys, StringifyKey(ent.GetKey()))
+	}
+
+	return strings.Join(keys, ",")
+}
+
+// GroupKey returns the group ID of the business event, if it came with one, or else its EntitiesKey
+func GroupKey(be Medium) string {
+	if gc, ok := be.(GroupCarrier); ok && gc.GetGroupID() != "" {
+		return gc.GetGroupID()
+	}
+
+	return EntitiesKey(be)
+}
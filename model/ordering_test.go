@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupKey(t *testing.T) {
+	grouped := &BusinessEvent{ID: "1", GroupID: "group-1", Entities: []Entity{&Product{PBaseKey: PBaseKey{ProductID: 7}}}}
+	assert.Equal(t, "group-1", GroupKey(grouped))
+
+	keyed := &BusinessEvent{ID: "2", Entities: []Entity{
+		&Product{PBaseKey: PBaseKey{ProductID: 7}},
+		&Product{PBaseKey: PBaseKey{ProductID: 8}},
+	}}
+	assert.Equal(t, "7,8", GroupKey(keyed))
+
+	assert.Equal(t, "3", GroupKey(&BusinessEvent{ID: "3"}))
+}
+
+func TestInputsToBusinessEvents_GroupID(t *testing.T) {
+	bes, err := InputsToBusinessEvents([]Input{&Message{ID: "1", Body: []byte(`{}`), GroupID: "group-1"}}, &Product{})
+	assert.NoError(t, err)
+	assert.Equal(t, "group-1", bes[0].(*BusinessEvent).GetGroupID())
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ParkedEvent is a business event that failed with the StopAndPark mandate, and waits for someone to fix it
+type ParkedEvent struct {
+	ID               string `json:"id"`
+	EventName        string `json:"event_name,omitempty"`
+	PreviousAction   int    `json:"previous_action"`
+	PreviousActionID string `json:"previous_action_id,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ParkedAt         string `json:"parked_at,omitempty"`
+	// Event is the serialised business event, ready to resume at the action that failed
+	Event json.RawMessage `json:"event"`
+}
+
+// ErrNotParked is returned for a business event that can't be found among the parked ones
+var ErrNotParked = errors.New("business event is not parked")
+
+// SetParked marks the business event as one that should be parked, and whether it was
+func (be *BusinessEvent) SetParked(parked bool) {
+	if be.Metadata == nil {
+		be.Metadata = &Metadata{}
+	}
+
+	be.Metadata.ShouldBeParked = NewStringBool(true)
+	be.Metadata.IsParked = NewStringBool(parked)
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/zale144/ube/libs/redact"
+)
+
+type redactorHolder struct {
+	*redact.Redactor
+}
+
+var defaultRedactor atomic.Value
+
+func init() {
+	defaultRedactor.Store(redactorHolder{redact.Default()})
+}
+
+// Redactor returns the redactor the loggers redact the values with, nil meaning they log them as they are
+func Redactor() *redact.Redactor {
+	return defaultRedactor.Load().(redactorHolder).Redactor
+}
+
+// SetRedactor replaces the redactor the loggers redact the values with, returning a func that puts the previous one back
+func SetRedactor(r *redact.Redactor) func() {
+	prev := Redactor()
+	defaultRedactor.Store(redactorHolder{r})
+
+	return func() {
+		defaultRedactor.Store(redactorHolder{prev})
+	}
+}
+
+// Redact returns a copy of the value with its sensitive data redacted. The business events
+// have the fields their event says are sensitive masked as well
+func Redact(r *redact.Redactor, v interface{}) interface{} {
+	if r == nil || v == nil {
+		return v
+	}
+
+	switch tv := v.(type) {
+	case string:
+		return r.String(tv)
+	case error, time.Time, time.Duration:
+		// their text is what gets logged, and there's nothing to tag in them
+		return v
+	}
+
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return r.RedactFields(v, sensitiveFieldsOf(v)...)
+	}
+
+	return v
+}
+
+// sensitiveFieldsOf returns the names of the fields the business event says are sensitive
+func sensitiveFieldsOf(v interface{}) []string {
+	if s, ok := v.(interface{ GetSensitiveDataFieldNames() []string }); ok {
+		return s.GetSensitiveDataFieldNames()
+	}
+
+	return nil
+}
+
+// redactKV redacts the values of the keys and values to be logged
+func redactKV(kv []interface{}) []interface{} {
+	r := Redactor()
+	if r == nil || len(kv) == 0 {
+		return kv
+	}
+
+	redacted := make([]interface{}, len(kv))
+	copy(redacted, kv)
+
+	for i := 1; i < len(redacted); i += 2 {
+		redacted[i] = Redact(r, redacted[i])
+	}
+
+	return redacted
+}
+
+// Redacting returns a logger that redacts the values before they're logged with l. The loggers that come with UBE
+// do that already, so it's there for the other ones
+func Redacting(l Logger) Logger {
+	if _, ok := l.(redactingLogger); ok {
+		return l
+	}
+
+	return redactingLogger{l: l}
+}
+
+type redactingLogger struct {
+	l Logger
+}
+
+func (l redactingLogger) Debug(msg string, kv ...interface{}) { l.l.Debug(msg, redactKV(kv)...) }
+func (l redactingLogger) Info(msg string, kv ...interface{})  { l.l.Info(msg, redactKV(kv)...) }
+func (l redactingLogger) Warn(msg string, kv ...interface{})  { l.l.Warn(msg, redactKV(kv)...) }
+func (l redactingLogger) Error(msg string, kv ...interface{}) { l.l.Error(msg, redactKV(kv)...) }
+
+func (l redactingLogger) With(kv ...interface{}) Logger {
+	return redactingLogger{l: l.l.With(redactKV(kv)...)}
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/zale144/ube/libs/redact"
+)
+
+type customer struct {
+	Base
+	Name  string `json:"name" sensitive:"mask"`
+	Email string `json:"email"`
+	Tier  string `json:"tier"`
+}
+
+func TestRedact_BusinessEvent(t *testing.T) {
+	be := &BusinessEvent{
+		ID: "1",
+		Event: &Event{
+			EventHeader:             EventHeader{EventName: "created", EventCategory: "customer"},
+			SensitiveDataFieldNames: []string{"tier"},
+		},
+		Entities: []Entity{&customer{Name: "Jane Doe", Email: "jane@example.com", Tier: "gold"}},
+	}
+
+	red, ok := Redact(redact.Default(), be).(*BusinessEvent)
+	require.True(t, ok)
+
+	jsn, err := json.Marshal(red)
+	require.NoError(t, err)
+	assert.Contains(t, string(jsn), `"customer":[{"name":"****","email":"****","tier":"****"}]`)
+
+	// the business event itself is left as it is
+	assert.Equal(t, "Jane Doe", be.Entities[0].(*customer).Name)
+}
+
+func TestLogger_Redacts(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := NewZapLogger(zap.New(core))
+
+	l.Info("saving", "entity", &customer{Name: "Jane Doe", Email: "jane@example.com"}, "note", "write to jane@example.com")
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "****", fields["entity"].(*customer).Name)
+	assert.Equal(t, "write to ****", fields["note"])
+
+	undo := SetRedactor(nil)
+	defer undo()
+
+	l.Info("saving", "note", "write to jane@example.com")
+	assert.Equal(t, "write to jane@example.com", logs.All()[1].ContextMap()["note"])
+}
+
+func TestRedacting(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+
+	// a logger of its own, which doesn't redact anything
+	l := Redacting(plainLogger{zap.New(core).Sugar()})
+	assert.Equal(t, l, Redacting(l))
+
+	l.With("email", "jane@example.com").Warn("hello")
+	assert.Equal(t, "****", logs.All()[0].ContextMap()["email"])
+}
+
+type plainLogger struct {
+	s *zap.SugaredLogger
+}
+
+func (l plainLogger) Debug(msg string, kv ...interface{}) { l.s.Debugw(msg, kv...) }
+func (l plainLogger) Info(msg string, kv ...interface{})  { l.s.Infow(msg, kv...) }
+func (l plainLogger) Warn(msg string, kv ...interface{})  { l.s.Warnw(msg, kv...) }
+func (l plainLogger) Error(msg string, kv ...interface{}) { l.s.Errorw(msg, kv...) }
+
+func (l plainLogger) With(kv ...interface{}) Logger {
+	return plainLogger{s: l.s.With(kv...)}
+}
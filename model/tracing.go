@@ -0,0 +1,142 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TraceParentHeader is the W3C trace context header, and the message attribute, that carries the trace parent
+const TraceParentHeader = "traceparent"
+
+// SpanContext identifies a span across process boundaries
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+var traceParentRe = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// ParseTraceParent parses the W3C trace parent, returning false if it's not a valid one
+func ParseTraceParent(traceParent string) (SpanContext, bool) {
+	m := traceParentRe.FindStringSubmatch(strings.TrimSpace(strings.ToLower(traceParent)))
+	if m == nil {
+		return SpanContext{}, false
+	}
+
+	sc := SpanContext{TraceID: m[1], SpanID: m[2], Sampled: m[3] == "01"}
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
+
+// IsValid tells whether the span context identifies a span
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.TraceID != strings.Repeat("0", 32) &&
+		sc.SpanID != "" && sc.SpanID != strings.Repeat("0", 16)
+}
+
+// TraceParent formats the span context as a W3C trace parent, or returns an empty string if it's not valid
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+type (
+	// Tracer starts the spans of the pipeline. The span is the child of the one in the context, or of the remote
+	// span context if there's no span in it
+	Tracer interface {
+		Start(ctx context.Context, name string) (context.Context, Span)
+	}
+	// Span is a single operation within a trace
+	Span interface {
+		SpanContext() SpanContext
+		SetAttribute(key string, value interface{})
+		// AddLink links the span to another one it's related to, but not the child of
+		AddLink(sc SpanContext)
+		// End ends the span, recording the error it failed with, if any
+		End(err error)
+	}
+	// TraceCarrier is an input or a business event that carries the trace context it came with
+	TraceCarrier interface {
+		GetTraceParent() string
+	}
+)
+
+type (
+	tracerKey        struct{}
+	spanKey          struct{}
+	remoteSpanCtxKey struct{}
+)
+
+// WithTracer returns the context that the spans are started through
+func WithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// StartSpan starts a span with the tracer of the context, or one that goes nowhere if there isn't any
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	if ctx != nil {
+		if t, ok := ctx.Value(tracerKey{}).(Tracer); ok {
+			return t.Start(ctx, name)
+		}
+	}
+
+	return ctx, noSpan{}
+}
+
+// WithSpan returns the context with the span, for the spans started through it to be its children
+func WithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFrom returns the span of the context, or one that goes nowhere if there isn't any
+func SpanFrom(ctx context.Context) Span {
+	if ctx != nil {
+		if s, ok := ctx.Value(spanKey{}).(Span); ok {
+			return s
+		}
+	}
+
+	return noSpan{}
+}
+
+// WithRemoteSpanContext returns the context with the span context that came from another process,
+// e.g. with the traceparent header of a request
+func WithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanCtxKey{}, sc)
+}
+
+// ParentSpanContext returns the span context the spans started through the context are the children of
+func ParentSpanContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+
+	if sc := SpanFrom(ctx).SpanContext(); sc.IsValid() {
+		return sc, true
+	}
+
+	sc, ok := ctx.Value(remoteSpanCtxKey{}).(SpanContext)
+
+	return sc, ok && sc.IsValid()
+}
+
+type noSpan struct{}
+
+func (noSpan) SpanContext() SpanContext         { return SpanContext{} }
+func (noSpan) SetAttribute(string, interface{}) {}
+func (noSpan) AddLink(SpanContext)              {}
+func (noSpan) End(error)                        {}
@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zale144/ube/model"
+)
+
+// ResumeFallback is what happens to a republished business event whose action is no longer in the pipeline
+type ResumeFallback int
+
+const (
+	// RestartPipeline runs the business event through the whole pipeline again
+	RestartPipeline ResumeFallback = iota
+	// ParkEvent fails the business event with the StopAndPark mandate, so the Parker keeps it until someone replays it
+	ParkEvent
+)
+
+// MissingActionFallback sets what happens to the republished business events whose action was removed or renamed
+// since they were republished. By default, they run through the whole pipeline again
+func MissingActionFallback(fallback ResumeFallback) Option {
+	return func(p *Pipeline) {
+		p.resumeFallback = fallback
+	}
+}
+
+// labeled is an action that is told apart from the ones with the same name by a label
+type labeled interface {
+	Label() string
+}
+
+// actionIDs maps the stable IDs of the actions to their indexes and back
+type actionIDs struct {
+	byID    map[string]int
+	byIndex map[int]string
+}
+
+// register assigns the action its ID, which is its name, followed by its label if there is one.
+// The actions with the same ID get a suffix, though it's stable only as long as their order is, and a warning to the logger
+func (a *actionIDs) register(act action, idx int, l model.Logger) {
+	if a.byID == nil {
+		a.byID, a.byIndex = make(map[string]int), make(map[int]string)
+	}
+
+	id := act.Name()
+	if l, ok := act.(labeled); ok && l.Label() != "" {
+		id += ":" + l.Label()
+	}
+
+	if _, ok := a.byID[id]; ok {
+		l.Warn("Two actions with the same ID walk into a pipeline. Give them a label to keep them apart.",
+			"id", id)
+
+		for n := 2; ; n++ {
+			if _, ok := a.byID[fmt.Sprintf("%s#%d", id, n)]; !ok {
+				id = fmt.Sprintf("%s#%d", id, n)
+				break
+			}
+		}
+	}
+
+	a.byID[id] = idx
+	a.byIndex[idx] = id
+}
+
+// resumable is a business event that keeps the other actions of a layer it failed at, alongside the previous one
+type resumable interface {
+	GetPendingActionIDs() []string
+	SetPendingActionIDs(ids []string)
+}
+
+// pendingAt tells whether the republished business event resumes at the action along with the one it failed at,
+// as they both failed while running at the same time
+func (p *Pipeline) pendingAt(beI model.Medium, idx int) bool {
+	be, ok := beI.(model.PipelineMedium)
+	if !ok || be.GetError() != nil || be.GetRepublishAttempt() == nil {
+		return false
+	}
+
+	r, ok := beI.(resumable)
+	if !ok {
+		return false
+	}
+
+	for _, id := range r.GetPendingActionIDs() {
+		if id == p.ids.byIndex[idx] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resume points the republished business events at the current index of the action they failed at.
+// The ones republished before the actions had IDs keep the index they came with
+func (p *Pipeline) resume(ctx context.Context, bes []model.Medium) {
+	for _, iBe := range bes {
+		be, ok := iBe.(model.PipelineMedium)
+		if !ok || be.GetError() != nil || be.GetRepublishAttempt() == nil || be.GetPreviousActionID() == "" {
+			continue
+		}
+
+		if idx, ok := p.ids.byID[be.GetPreviousActionID()]; ok {
+			be.SetPreviousAction(idx)
+			continue
+		}
+
+		model.EventLogger(ctx, be).Warn("The action this business event was waiting for is gone. Redeployed, were we?",
+			"action", be.GetPreviousActionID())
+
+		switch p.resumeFallback {
+		case ParkEvent:
+			be.SetError(fmt.Errorf("'%s': %w", be.GetPreviousActionID(), model.ErrUnknownAction))
+			be.SetPreviousActionMandate(model.StopAndPark)
+		default:
+			// starting over, so it's no longer a republished event
+			be.SetRepublishAttempt(nil)
+			be.SetPreviousActionID("")
+			if r, ok := be.(resumable); ok {
+				r.SetPendingActionIDs(nil)
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/memory"
+	"github.com/zale144/ube/model"
+)
+
+// republishedInput is a business event that was republished after failing at the given action
+func republishedInput(id, previousAction string) model.Input {
+	return &model.Message{
+		ID: "msg_" + id,
+		Body: []byte(`{"id":"` + id + `","event":{"event_name":"created","event_category":"product"},` +
+			`"product":[{"SomeField":"` + id + `"}],"is_republish":1,"previous_action":1` + previousAction + `}`),
+	}
+}
+
+func TestActionIDs(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	p := NewPipeline(&product{},
+		Action(newRecordingAction("Persister", actions.Label("products"))),
+		Action(newRecordingAction("Persister", actions.Label("stock"))),
+		Action(newRecordingAction("Publisher")),
+		Action(newRecordingAction("Publisher")),
+	)
+
+	assert.Equal(t, map[string]int{"Persister:products": 0, "Persister:stock": 1, "Publisher": 2, "Publisher#2": 3}, p.ids.byID)
+}
+
+func TestPipeline_resume(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	// the event failed at the Persister, which was the second action before the Enricher was added
+	enricher := newRecordingAction("Enricher")
+	transformer := newRecordingAction("InputTransformer")
+	persister := newRecordingAction("Persister")
+	publisher := newRecordingAction("Publisher")
+
+	p := NewPipeline(&product{}, Action(transformer), Action(enricher), Action(persister), Action(publisher))
+
+	result, err := p.InvokePipeline(ctx,
+		republishedInput("1", `,"previous_action_id":"Persister"`),
+		// republished before the actions had IDs
+		republishedInput("2", ""),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, result.Status)
+
+	assert.Empty(t, transformer.visited)
+	assert.Equal(t, []string{"2"}, enricher.visited)
+	assert.Equal(t, []string{"1", "2"}, persister.visited)
+	assert.Equal(t, []string{"1", "2"}, publisher.visited)
+}
+
+func TestPipeline_resume_missing_action(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	t.Run("restart", func(t *testing.T) {
+		first := newRecordingAction("first")
+		second := newRecordingAction("second")
+
+		p := NewPipeline(&product{}, Action(first), Action(second))
+
+		result, err := p.InvokePipeline(ctx, republishedInput("1", `,"previous_action_id":"removed"`))
+		require.NoError(t, err)
+		assert.Equal(t, StatusSucceeded, result.Status)
+
+		assert.Equal(t, []string{"1"}, first.visited)
+		assert.Equal(t, []string{"1"}, second.visited)
+	})
+
+	t.Run("park", func(t *testing.T) {
+		first := newRecordingAction("first")
+		second := newRecordingAction("second")
+		store := memory.NewParkStore()
+
+		p := NewPipeline(&product{}, Action(first), Action(second), Parker(store), MissingActionFallback(ParkEvent))
+
+		_, err := p.InvokePipeline(ctx, republishedInput("1", `,"previous_action_id":"removed"`))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, model.ErrUnknownAction)
+
+		assert.Empty(t, first.visited)
+		assert.Empty(t, second.visited)
+
+		parked, err := store.GetParked(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "removed", parked.PreviousActionID)
+	})
+}
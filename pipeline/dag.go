@@ -0,0 +1,452 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+// plan arranges the actions into layers, so that every action runs only after all the actions it depends on.
+// An action that declares no dependencies depends on the action preceding it, and dependencies can only
+// point back to actions declared earlier in the pipeline, which keeps the graph acyclic
+func plan(acts []action) ([][]int, error) {
+	levels := make([]int, len(acts))
+
+	var layers [][]int
+
+	for pos := range acts {
+		deps, err := dependencies(acts, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		level := 0
+		for _, dep := range deps {
+			if levels[dep]+1 > level {
+				level = levels[dep] + 1
+			}
+		}
+
+		levels[pos] = level
+
+		if level == len(layers) {
+			layers = append(layers, nil)
+		}
+
+		layers[level] = append(layers[level], pos)
+	}
+
+	for _, lyr := range layers {
+		if len(lyr) == 1 {
+			continue
+		}
+
+		for _, pos := range lyr {
+			if _, ok := acts[pos].(composite); ok {
+				return nil, fmt.Errorf("action '%s' runs nested actions and can't run at the same time as other actions", acts[pos].Name())
+			}
+		}
+	}
+
+	return layers, nil
+}
+
+// dependencies resolves the positions of the actions that the action at pos depends on.
+// Names are resolved to the closest preceding action with that name
+func dependencies(acts []action, pos int) ([]int, error) {
+	var names []string
+	if dep, ok := acts[pos].(dependent); ok {
+		names = dep.DependsOn()
+	}
+
+	if len(names) == 0 {
+		if pos == 0 {
+			return nil, nil
+		}
+		return []int{pos - 1}, nil
+	}
+
+	deps := make([]int, 0, len(names))
+
+	for _, name := range names {
+		found := -1
+		for i := pos - 1; i >= 0; i-- {
+			if acts[i].Name() == name {
+				found = i
+				break
+			}
+		}
+
+		if found < 0 {
+			return nil, fmt.Errorf("action '%s' depends on '%s', which is not declared before it", acts[pos].Name(), name)
+		}
+
+		deps = append(deps, found)
+	}
+
+	return deps, nil
+}
+
+// runConcurrently executes the actions of a layer at the same time against the same business events.
+// Each action works on its own view of the business events, so their errors don't overwrite each other,
+// and the outcomes are settled on the business events in the order the actions were declared
+func (p *Pipeline) runConcurrently(ctx context.Context, bes []model.Medium, lyr []int) []ActionOutcome {
+	views := make([][]*isolatedMedium, len(lyr))
+
+	// deciding which events are processable changes their state, so it can't be done concurrently
+	for k, pos := range lyr {
+		act, idx := p.actions[pos], p.offset+pos
+		for _, be := range bes {
+			if isEventProcessable(ctx, be, act, idx) || p.pendingAt(be, idx) {
+				views[k] = append(views[k], &isolatedMedium{PipelineMedium: be.(model.PipelineMedium), err: be.GetError()})
+			} else {
+				journalSkip(ctx, be, act, idx)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for k, pos := range lyr {
+		act, idx := p.actions[pos], p.offset+pos
+		if len(views[k]) == 0 {
+			model.LoggerFrom(ctx).Info("No business events to process.", "action", act.Name())
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(view []*isolatedMedium) {
+			defer wg.Done()
+
+			actx := p.withActionLogger(ctx, idx)
+
+			model.LoggerFrom(actx).Info("Starting pipeline action alongside its independent peers. Cake is twice as likely.",
+				"action", act.Name(), "action batchsize", act.BatchSize())
+
+			vbes := make([]model.Medium, len(view))
+			for i := range view {
+				vbes[i] = view[i]
+			}
+
+			actx, span := p.startAction(actx, act, idx)
+			actx = withActionTimeout(actx, act)
+
+			var outcome ActionOutcome
+			if act.IsAsync() {
+				outcome = processAsync(actx, vbes, act, idx, p.batch(invokeBatchAction), p.slots, p.orderKeyOf(act))
+			} else {
+				outcome = processSync(actx, vbes, act, idx, p.batch(invokeBatchAction))
+			}
+
+			endAction(span, []ActionOutcome{outcome})
+
+			model.LoggerFrom(actx).Info("Finished pipeline action for all business events", "action", act.Name())
+		}(views[k])
+	}
+
+	wg.Wait()
+
+	outcomes := make([]ActionOutcome, len(lyr))
+
+	// every action gets its own journal entry, even if another action of the layer decides what happens to the event
+	for k, pos := range lyr {
+		act, idx := p.actions[pos], p.offset+pos
+		outcomes[k] = newOutcome(act, idx)
+
+		for _, view := range views[k] {
+			outcomes[k].add(1, 0)
+
+			if view.err != nil {
+				outcomes[k].add(0, 1)
+			}
+
+			journal(ctx, view, settledBy(act, view.err), idx, view.started, view.ended)
+		}
+	}
+
+	// the IDs of the actions of the layer each business event failed at, in the order they were declared
+	failed := make(map[model.PipelineMedium][]string)
+
+	for k, pos := range lyr {
+		idx := p.offset + pos
+
+		for _, view := range views[k] {
+			be := view.PipelineMedium
+
+			// the first failing action of the layer decides what happens to the event,
+			// and the others are kept for a republished event to resume at them as well
+			if ids, ok := failed[be]; ok {
+				if view.err != nil {
+					failed[be] = append(ids, p.ids.byIndex[idx])
+				}
+				continue
+			}
+
+			if view.err != nil {
+				failed[be] = []string{p.ids.byIndex[idx]}
+				be.SetError(view.err)
+			}
+
+			handleActionError(ctx, be, settledBy(p.actions[pos], view.err), idx, p.ids.byIndex[idx])
+		}
+	}
+
+	for be, ids := range failed {
+		if r, ok := be.(resumable); ok {
+			r.SetPendingActionIDs(ids[1:])
+		}
+	}
+
+	return outcomes
+}
+
+// settledBy returns the action whose mandate applies to a business event failed with err
+func settledBy(act action, err error) action {
+	if errors.Is(err, model.ErrDeadlineExceeded) {
+		return postponed{act}
+	}
+
+	return act
+}
+
+// invokeBatchAction processes a batch of business events that were already checked for being processable
+func invokeBatchAction(ctx context.Context, bes []model.Medium, action action, _ int) (processed, failed int) {
+	started := time.Now()
+	invokeWithRetry(ctx, action, bes)
+	ended := time.Now()
+
+	for _, be := range bes {
+		if view, ok := be.(*isolatedMedium); ok {
+			view.started, view.ended = started, ended
+		}
+		if be.GetError() != nil {
+			failed++
+		}
+	}
+
+	return len(bes), failed
+}
+
+// isolatedMedium is the view of a business event given to an action running at the same time as other
+// actions. It keeps the error of the action to itself and delegates everything else to the business event
+type isolatedMedium struct {
+	model.PipelineMedium
+	err error
+	// started and ended are the times the action processed the batch of the view
+	started, ended time.Time
+}
+
+func (m *isolatedMedium) GetError() error {
+	return m.err
+}
+
+func (m *isolatedMedium) SetError(err error) {
+	m.err = err
+}
+
+// LogProcessing adds the entry to the journal of the business event, if it keeps one
+func (m *isolatedMedium) LogProcessing(entry *model.MessageProcessingLog) {
+	if j, ok := m.PipelineMedium.(journaled); ok {
+		j.LogProcessing(entry)
+	}
+}
+
+// encryptable is a business event that keeps track of whether its sensitive fields are encrypted
+type encryptable interface {
+	GetSensitiveFieldsEncrypted() bool
+	GetEncryptionKeyIDs() []string
+	SetSensitiveFieldsEncrypted(encrypted bool, keyIDs []string)
+}
+
+// GetSensitiveFieldsEncrypted tells whether the sensitive fields of the business event are encrypted, if it keeps track of that
+func (m *isolatedMedium) GetSensitiveFieldsEncrypted() bool {
+	if e, ok := m.PipelineMedium.(encryptable); ok {
+		return e.GetSensitiveFieldsEncrypted()
+	}
+
+	return false
+}
+
+// GetEncryptionKeyIDs returns the IDs of the keys the business event is encrypted with, if it keeps track of them
+func (m *isolatedMedium) GetEncryptionKeyIDs() []string {
+	if e, ok := m.PipelineMedium.(encryptable); ok {
+		return e.GetEncryptionKeyIDs()
+	}
+
+	return nil
+}
+
+// SetSensitiveFieldsEncrypted records whether the business event is encrypted, if it keeps track of that
+func (m *isolatedMedium) SetSensitiveFieldsEncrypted(encrypted bool, keyIDs []string) {
+	if e, ok := m.PipelineMedium.(encryptable); ok {
+		e.SetSensitiveFieldsEncrypted(encrypted, keyIDs)
+	}
+}
+
+// GetGroupID returns the group of the business event, if it came with one
+func (m *isolatedMedium) GetGroupID() string {
+	if gc, ok := m.PipelineMedium.(model.GroupCarrier); ok {
+		return gc.GetGroupID()
+	}
+
+	return ""
+}
+
+// GetSensitiveDataFieldNames returns the names of the fields the business event says are sensitive, for the
+// redacted copy of the view to have them masked as well
+func (m *isolatedMedium) GetSensitiveDataFieldNames() []string {
+	if s, ok := m.PipelineMedium.(interface{ GetSensitiveDataFieldNames() []string }); ok {
+		return s.GetSensitiveDataFieldNames()
+	}
+
+	return nil
+}
+
+// GetPendingActionIDs returns the other actions the business event resumes at, if it keeps track of them
+func (m *isolatedMedium) GetPendingActionIDs() []string {
+	if r, ok := m.PipelineMedium.(resumable); ok {
+		return r.GetPendingActionIDs()
+	}
+
+	return nil
+}
+
+// SetPendingActionIDs sets the other actions the business event resumes at, if it keeps track of them
+func (m *isolatedMedium) SetPendingActionIDs(ids []string) {
+	if r, ok := m.PipelineMedium.(resumable); ok {
+		r.SetPendingActionIDs(ids)
+	}
+}
+
+// GetTraceParent returns the trace context of the business event, if it carries one
+func (m *isolatedMedium) GetTraceParent() string {
+	if tc, ok := m.PipelineMedium.(model.TraceCarrier); ok {
+		return tc.GetTraceParent()
+	}
+
+	return ""
+}
+
+// SetTraceParent records the trace context on the business event, if it carries one
+func (m *isolatedMedium) SetTraceParent(traceParent string) {
+	if t, ok := m.PipelineMedium.(traced); ok {
+		t.SetTraceParent(traceParent)
+	}
+}
+
+// SetParked marks the business event as parked, if it keeps track of that
+func (m *isolatedMedium) SetParked(parked bool) {
+	if pk, ok := m.PipelineMedium.(interface{ SetParked(bool) }); ok {
+		pk.SetParked(parked)
+	}
+}
+
+// UpdateMetadata updates the metadata of the business event, if it has any
+func (m *isolatedMedium) UpdateMetadata(now func() time.Time) {
+	if md, ok := m.PipelineMedium.(interface{ UpdateMetadata(func() time.Time) }); ok {
+		md.UpdateMetadata(now)
+	}
+}
+
+// GetEventCategory returns the category of the business event, if it has one
+func (m *isolatedMedium) GetEventCategory() string {
+	if cat, ok := m.PipelineMedium.(interface{ GetEventCategory() string }); ok {
+		return cat.GetEventCategory()
+	}
+
+	return ""
+}
+
+// GetEventSource returns the source URI of the business event, if it has one
+func (m *isolatedMedium) GetEventSource() string {
+	if src, ok := m.PipelineMedium.(interface{ GetEventSource() string }); ok {
+		return src.GetEventSource()
+	}
+
+	return ""
+}
+
+// SetGroupID sets the group of the business event, if it keeps one
+func (m *isolatedMedium) SetGroupID(groupID string) {
+	if gc, ok := m.PipelineMedium.(interface{ SetGroupID(string) }); ok {
+		gc.SetGroupID(groupID)
+	}
+}
+
+// GetEvent returns the event of the business event, if it has one
+func (m *isolatedMedium) GetEvent() *model.Event {
+	if ev, ok := m.PipelineMedium.(interface{ GetEvent() *model.Event }); ok {
+		return ev.GetEvent()
+	}
+
+	return nil
+}
+
+// GetProcessingLog returns the journal of the business event, if it keeps one
+func (m *isolatedMedium) GetProcessingLog() []*model.MessageProcessingLog {
+	if j, ok := m.PipelineMedium.(interface {
+		GetProcessingLog() []*model.MessageProcessingLog
+	}); ok {
+		return j.GetProcessingLog()
+	}
+
+	return nil
+}
+
+// GetBody returns the body the business event came with, if it's an input one
+func (m *isolatedMedium) GetBody() []byte {
+	if in, ok := m.PipelineMedium.(model.InputActionMedium); ok {
+		return in.GetBody()
+	}
+
+	return nil
+}
+
+// SetBody sets the body of the business event, if it's an input one
+func (m *isolatedMedium) SetBody(body []byte) {
+	if in, ok := m.PipelineMedium.(model.InputActionMedium); ok {
+		in.SetBody(body)
+	}
+}
+
+// SetEventName sets the event name of the business event, if it's an input one
+func (m *isolatedMedium) SetEventName(name string) {
+	if in, ok := m.PipelineMedium.(model.InputActionMedium); ok {
+		in.SetEventName(name)
+	}
+}
+
+// SetEventCategory sets the event category of the business event, if it's an input one
+func (m *isolatedMedium) SetEventCategory(category string) {
+	if in, ok := m.PipelineMedium.(model.InputActionMedium); ok {
+		in.SetEventCategory(category)
+	}
+}
+
+// SetSource sets the source URI of the business event, if it's an input one
+func (m *isolatedMedium) SetSource(source string) {
+	if in, ok := m.PipelineMedium.(model.InputActionMedium); ok {
+		in.SetSource(source)
+	}
+}
+
+// InitEntity initialises the entity of the business event, if it's an input one
+func (m *isolatedMedium) InitEntity(entType reflect.Type) error {
+	if in, ok := m.PipelineMedium.(model.InputActionMedium); ok {
+		return in.InitEntity(entType)
+	}
+
+	return fmt.Errorf("expected InputActionMedium, got %T", m.PipelineMedium)
+}
+
+// MarshalJSON marshals the underlying business event, so publishing a view is the same as publishing the event
+func (m *isolatedMedium) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.PipelineMedium)
+}
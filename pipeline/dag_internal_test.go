@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/redact"
+	"github.com/zale144/ube/model"
+)
+
+func newRecordingAction(name string, options ...actions.BaseOption) *recordingAction {
+	a := &recordingAction{name: name}
+	for _, opt := range options {
+		opt(&a.Base)
+	}
+
+	return a
+}
+
+type clearingAction struct {
+	*recordingAction
+}
+
+// Process clears the errors of all the events, the way Persister and Publisher do on success
+func (a *clearingAction) Process(ctx context.Context, bes ...model.Medium) {
+	a.recordingAction.Process(ctx, bes...)
+	for _, be := range bes {
+		be.SetError(nil)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	acts := []action{
+		newRecordingAction("InputTransformer"),
+		newRecordingAction("Uploader", actions.DependsOn("InputTransformer")),
+		newRecordingAction("Enricher", actions.DependsOn("InputTransformer")),
+		newRecordingAction("Persister", actions.DependsOn("Enricher")),
+		newRecordingAction("Publisher"),
+	}
+
+	layers, err := plan(acts)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0}, {1, 2}, {3}, {4}}, layers)
+
+	layers, err = plan(acts[:1])
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0}}, layers)
+}
+
+func TestPlan_unknown_dependency(t *testing.T) {
+	acts := []action{
+		newRecordingAction("Uploader", actions.DependsOn("Persister")),
+		newRecordingAction("Persister"),
+	}
+
+	_, err := plan(acts)
+	assert.EqualError(t, err, "action 'Uploader' depends on 'Persister', which is not declared before it")
+}
+
+func TestPipeline_concurrent_actions(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := newRecordingAction("first")
+	uploader := newRecordingAction("uploader",
+		actions.DependsOn("first"), actions.FailureMandate(model.StopFurtherProcessing))
+	uploader.fail = map[string]error{"2": errors.New("upload failed")}
+	persister := &clearingAction{recordingAction: newRecordingAction("persister", actions.DependsOn("first"))}
+	last := newRecordingAction("last", actions.DependsOn("uploader", "persister"))
+
+	p := NewPipeline(&product{}, Action(first), Action(uploader), Action(persister), Action(last))
+
+	bes := []model.Medium{newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct")}
+
+	outcomes := p.run(context.Background(), bes)
+
+	assert.Equal(t, []string{"1", "2"}, uploader.visited)
+	assert.Equal(t, []string{"1", "2"}, persister.visited)
+	assert.Equal(t, []string{"1"}, last.visited)
+
+	assert.NoError(t, bes[0].GetError())
+	assert.EqualError(t, bes[1].GetError(), "upload failed")
+	assert.Equal(t, model.StopFurtherProcessing, bes[1].(model.PipelineMedium).GetPreviousActionMandate())
+	assert.Equal(t, 1, bes[1].(model.PipelineMedium).GetPreviousAction())
+
+	assert.Equal(t, []ActionOutcome{
+		{Action: "first", Index: 0, Processed: 2},
+		{Action: "uploader", Index: 1, Processed: 2, Failed: 1},
+		{Action: "persister", Index: 2, Processed: 2},
+		{Action: "last", Index: 3, Processed: 1},
+	}, outcomes)
+}
+
+func TestIsolatedMedium_delegates(t *testing.T) {
+	be, view := reflect.TypeOf(&model.BusinessEvent{}), reflect.TypeOf(&isolatedMedium{})
+
+	for i := 0; i < be.NumMethod(); i++ {
+		name := be.Method(i).Name
+		// the view is never unmarshalled into
+		if name == "UnmarshalJSON" {
+			continue
+		}
+		_, ok := view.MethodByName(name)
+		assert.True(t, ok, "isolatedMedium doesn't delegate %s", name)
+	}
+}
+
+func TestPipeline_concurrent_redacting_publisher(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var published []model.Input
+
+	pub := actions.NewMockIPublisher(ctrl)
+	pub.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msgs ...model.Input) error {
+			published = append(published, msgs...)
+			return nil
+		})
+
+	p := NewPipeline(&product{},
+		Action(newRecordingAction("Persister")),
+		// the Publisher runs alongside the Uploader, on its own view of the business events
+		Publisher(pub, actions.DependsOn("Persister"), actions.Redact(redact.New())),
+		Action(newRecordingAction("Uploader", actions.DependsOn("Persister"))),
+	)
+
+	_, err := p.InvokePipeline(context.Background(), &model.Message{
+		ID: "msg_1",
+		Body: []byte(`{"id":"1","event":{"event_name":"created","event_category":"product",` +
+			`"sensitive_data_field_names":["AnotherOne"]},"product":[{"SomeField":"1","AnotherOne":7}]}`),
+	})
+	require.NoError(t, err)
+
+	// there's no telling how to mask a number, so it's dropped
+	require.Len(t, published, 1)
+	assert.Contains(t, string(published[0].GetBody()), `"product":[{"SomeField":"1","AnotherOne":0}]`)
+}
+
+func TestPipeline_concurrent_actions_resume_at_all_failed(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := newRecordingAction("first")
+	uploader := newRecordingAction("Uploader", actions.DependsOn("first"), actions.FailureMandate(model.StopAndRetry))
+	uploader.fail = map[string]error{"1": errors.New("upload failed")}
+	persister := newRecordingAction("Persister", actions.DependsOn("first"), actions.FailureMandate(model.StopAndRetry))
+	persister.fail = map[string]error{"1": errors.New("persist failed")}
+	last := newRecordingAction("last", actions.DependsOn("Uploader", "Persister"))
+
+	p := NewPipeline(&product{}, Action(first), Action(uploader), Action(persister), Action(last))
+
+	be := newEvent("1", "CreateProduct")
+	p.run(context.Background(), []model.Medium{be})
+
+	// the Uploader decides what happens to the event, and the Persister is kept to resume at as well
+	assert.EqualError(t, be.GetError(), "upload failed")
+	assert.Equal(t, "Uploader", be.GetPreviousActionID())
+	assert.Equal(t, []string{"Persister"}, be.GetPendingActionIDs())
+
+	// the queue redelivers the republished event
+	attempt := 1
+	be.SetError(nil)
+	be.SetRepublishAttempt(&attempt)
+	uploader.fail, persister.fail = nil, nil
+
+	p.resume(context.Background(), []model.Medium{be})
+	p.run(context.Background(), []model.Medium{be})
+
+	assert.Equal(t, []string{"1"}, first.visited)
+	assert.Equal(t, []string{"1", "1"}, uploader.visited)
+	assert.Equal(t, []string{"1", "1"}, persister.visited)
+	assert.Equal(t, []string{"1"}, last.visited)
+
+	assert.NoError(t, be.GetError())
+	assert.Nil(t, be.GetRepublishAttempt())
+	assert.Empty(t, be.GetPendingActionIDs())
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+// timed is an action that limits how long it may take to process all of its batches
+type timed interface {
+	Timeout() time.Duration
+}
+
+// actionDeadlineKey keeps the time the action in progress has to be done by
+type actionDeadlineKey struct{}
+
+// withActionTimeout returns the context with the time the action has to process all of its batches by,
+// if it has a timeout. The time starts running with the first batch of the action
+func withActionTimeout(ctx context.Context, act action) context.Context {
+	if t, ok := act.(timed); ok && t.Timeout() > 0 {
+		return context.WithValue(ctx, actionDeadlineKey{}, time.Now().Add(t.Timeout()))
+	}
+
+	return ctx
+}
+
+// withTimeout runs fn with a context that expires once the action runs out of its time, if it has a timeout.
+// The batches that would start after that are not processed, and fail with model.ErrActionTimeout instead
+func withTimeout(fn batchFn) batchFn {
+	return func(ctx context.Context, bes []model.Medium, action action, actionIdx int) (int, int) {
+		deadline, ok := ctx.Value(actionDeadlineKey{}).(time.Time)
+		if !ok {
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		if !time.Now().Before(deadline) {
+			model.LoggerFrom(ctx).Warn("Time's up for this action, so the rest of its batches are sitting this one out.",
+				"action", action.Name(), "deadline", deadline)
+
+			return fn(ctx, bes, timedOut{action}, actionIdx)
+		}
+
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+
+		return fn(ctx, bes, action, actionIdx)
+	}
+}
+
+// timedOut stands in for an action that ran out of its time. It fails the business events it receives
+// with model.ErrActionTimeout, which are then handled by the failure mandate of the action
+type timedOut struct {
+	action
+}
+
+func (timedOut) Process(_ context.Context, bes ...model.Medium) {
+	for _, be := range bes {
+		be.SetError(model.ErrActionTimeout)
+	}
+}
+
+// deadlineAware stops fn from starting a new batch once the context deadline is closer than the margin.
+// The batch is then postponed: its business events are marked to be republished and resume at the same action
+func deadlineAware(margin time.Duration, fn batchFn) batchFn {
+	return func(ctx context.Context, bes []model.Medium, action action, actionIdx int) (int, int) {
+		if margin <= 0 {
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) >= margin {
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		model.LoggerFrom(ctx).Warn("The clock is ticking, so we're leaving the rest of the business events for the next invocation.",
+			"action", action.Name(), "deadline", deadline)
+
+		return fn(ctx, bes, postponed{action}, actionIdx)
+	}
+}
+
+// postponed stands in for an action that ran out of time. It fails the business events it receives
+// with model.ErrDeadlineExceeded, and asks for them to be retried
+type postponed struct {
+	action
+}
+
+func (postponed) Process(_ context.Context, bes ...model.Medium) {
+	for _, be := range bes {
+		be.SetError(model.ErrDeadlineExceeded)
+	}
+}
+
+func (postponed) FailureMandate() model.ActionMandate {
+	return model.StopAndRetry
+}
+
+// deferEarly fails the republished business events that came before their not-before time, asking for them
+// to be retried at the same action. A Republisher defers them again until they're due
+func deferEarly(ctx context.Context, bes []model.Medium) {
+	now := model.Now()
+
+	for _, beI := range bes {
+		be, ok := beI.(model.PipelineMedium)
+		if !ok || be.GetError() != nil {
+			continue
+		}
+
+		notBefore := be.GetNotBefore()
+		if !notBefore.After(now) {
+			continue
+		}
+
+		model.EventLogger(ctx, be).Info("Easy there! This business event is not due yet, so it goes back to wait for its turn.",
+			"not before", notBefore)
+
+		be.SetError(fmt.Errorf("%w: due at %s", model.ErrTooEarly, notBefore.UTC().Format(time.RFC3339)))
+		be.SetPreviousActionMandate(model.StopAndRetry)
+	}
+}
@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+type sleepingAction struct {
+	*recordingAction
+	sleep    time.Duration
+	deadline time.Time
+}
+
+func (a *sleepingAction) Process(ctx context.Context, bes ...model.Medium) {
+	a.deadline, _ = ctx.Deadline()
+	a.recordingAction.Process(ctx, bes...)
+	time.Sleep(a.sleep)
+}
+
+func TestPipeline_DeadlineMargin(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	slow := &sleepingAction{recordingAction: newRecordingAction("slow", actions.BatchSize(1)), sleep: 250 * time.Millisecond}
+	next := newRecordingAction("next")
+
+	p := NewPipeline(&product{}, DeadlineMargin(300*time.Millisecond), Action(slow), Action(next))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	bes := []model.Medium{newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct")}
+
+	outcomes := p.run(ctx, bes)
+
+	assert.Equal(t, []string{"1"}, slow.visited)
+	assert.Empty(t, next.visited)
+
+	// each event resumes at the action it didn't get to
+	for i, resumeAt := range []int{1, 0} {
+		postponed := bes[i].(model.PipelineMedium)
+		assert.ErrorIs(t, postponed.GetError(), model.ErrDeadlineExceeded)
+		assert.Equal(t, model.StopAndRetry, postponed.GetPreviousActionMandate())
+		assert.Equal(t, resumeAt, postponed.GetPreviousAction())
+		require.NotNil(t, postponed.GetRepublishAttempt())
+		assert.Equal(t, 0, *postponed.GetRepublishAttempt())
+	}
+
+	assert.Equal(t, []ActionOutcome{
+		{Action: "slow", Index: 0, Processed: 2, Failed: 1},
+		{Action: "next", Index: 1, Processed: 1, Failed: 1},
+	}, outcomes)
+}
+
+func TestPipeline_action_Timeout(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	limited := &sleepingAction{recordingAction: newRecordingAction("limited", actions.Timeout(time.Minute))}
+	unlimited := &sleepingAction{recordingAction: newRecordingAction("unlimited")}
+
+	p := NewPipeline(&product{}, Action(limited), Action(unlimited))
+	p.run(context.Background(), []model.Medium{newEvent("1", "CreateProduct")})
+
+	assert.WithinDuration(t, time.Now().Add(time.Minute), limited.deadline, time.Second)
+	assert.True(t, unlimited.deadline.IsZero())
+}
+
+func TestPipeline_action_Timeout_spans_batches(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	slow := &sleepingAction{
+		recordingAction: newRecordingAction("slow",
+			actions.BatchSize(1), actions.Timeout(300*time.Millisecond), actions.FailureMandate(model.StopAndRetry)),
+		sleep: 200 * time.Millisecond,
+	}
+
+	p := NewPipeline(&product{}, Action(slow))
+
+	bes := []model.Medium{newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct"), newEvent("3", "CreateProduct")}
+
+	started := time.Now()
+	outcomes := p.run(context.Background(), bes)
+
+	// the second batch gets what's left of the time of the action, and the third one is out of it
+	assert.Equal(t, []string{"1", "2"}, slow.visited)
+	assert.WithinDuration(t, started.Add(300*time.Millisecond), slow.deadline, 50*time.Millisecond)
+
+	assert.NoError(t, bes[0].GetError())
+	assert.NoError(t, bes[1].GetError())
+	assert.ErrorIs(t, bes[2].GetError(), model.ErrActionTimeout)
+	assert.Equal(t, model.StopAndRetry, bes[2].(model.PipelineMedium).GetPreviousActionMandate())
+
+	assert.Equal(t, []ActionOutcome{{Action: "slow", Index: 0, Processed: 3, Failed: 1}}, outcomes)
+}
+
+func TestDeferEarly(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := newRecordingAction("first")
+	second := newRecordingAction("second")
+
+	p := NewPipeline(&product{}, Action(first), Action(second))
+
+	attempt := 2
+	early, due := newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct")
+	for _, be := range []*model.BusinessEvent{early, due} {
+		be.RepublishAttempt = &attempt
+		be.PreviousAction = 1
+	}
+	early.SetNotBefore(model.Now().Add(time.Minute))
+	due.SetNotBefore(model.Now().Add(-time.Minute))
+
+	bes := []model.Medium{early, due}
+
+	deferEarly(context.Background(), bes)
+	p.run(context.Background(), bes)
+
+	assert.Equal(t, []string{"2"}, second.visited)
+	assert.Empty(t, first.visited)
+
+	// the early one waits for its turn, without using up an attempt
+	assert.ErrorIs(t, early.GetError(), model.ErrTooEarly)
+	assert.Equal(t, model.StopAndRetry, early.GetPreviousActionMandate())
+	assert.Equal(t, 2, *early.RepublishAttempt)
+
+	assert.NoError(t, due.GetError())
+	assert.Nil(t, due.RepublishAttempt)
+	assert.Nil(t, due.NotBefore)
+}
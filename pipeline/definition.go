@@ -0,0 +1,347 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-yaml/yaml"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+type (
+	// Definition is a declarative pipeline, usually loaded from a YAML or JSON document
+	Definition struct {
+		Actions        []ActionDefinition     `yaml:"actions" json:"actions"`
+		Concurrency    int                    `yaml:"concurrency" json:"concurrency"`
+		Ordered        bool                   `yaml:"ordered" json:"ordered"` // orders the async actions by model.GroupKey
+		DeadlineMargin string                 `yaml:"deadline_margin" json:"deadline_margin"`
+		Journal        bool                   `yaml:"journal" json:"journal"`
+		Idempotency    *IdempotencyDefinition `yaml:"idempotency" json:"idempotency"`
+		MissingAction  string                 `yaml:"missing_action" json:"missing_action"` // 'restart', the default, or 'park'
+		Metrics        *MetricsDefinition     `yaml:"metrics" json:"metrics"`
+		Tracer         string                 `yaml:"tracer" json:"tracer"` // the name the tracer was registered with
+	}
+	// MetricsDefinition declares the metrics the pipeline reports to, by the name they were registered with,
+	// and the name of the pipeline they're tagged with
+	MetricsDefinition struct {
+		Metrics string `yaml:"metrics" json:"metrics"`
+		Name    string `yaml:"name" json:"name"`
+	}
+	// IdempotencyDefinition declares the store of the messages processed already, by the name it was registered with.
+	// The Key is either 'id', the default, or 'content_hash'
+	IdempotencyDefinition struct {
+		Store         string `yaml:"store" json:"store"`
+		Key           string `yaml:"key" json:"key"`
+		InProgressTTL string `yaml:"in_progress_ttl" json:"in_progress_ttl"`
+		CompletedTTL  string `yaml:"completed_ttl" json:"completed_ttl"`
+	}
+	// ActionDefinition declares a pipeline action by the name it was registered with
+	ActionDefinition struct {
+		Action         string                 `yaml:"action" json:"action"`
+		Params         map[string]interface{} `yaml:"params" json:"params"`
+		Deps           map[string]string      `yaml:"deps" json:"deps"`
+		Options        []OptionDefinition     `yaml:"options" json:"options"`
+		BatchSize      int                    `yaml:"batch_size" json:"batch_size"`
+		FailureMandate string                 `yaml:"failure_mandate" json:"failure_mandate"`
+		Skip           []string               `yaml:"skip" json:"skip"`
+		Async          bool                   `yaml:"async" json:"async"`
+		Critical       bool                   `yaml:"critical" json:"critical"`
+		DependsOn      []string               `yaml:"depends_on" json:"depends_on"`
+		Timeout        string                 `yaml:"timeout" json:"timeout"`
+		Concurrency    int                    `yaml:"concurrency" json:"concurrency"`
+		Ordered        bool                   `yaml:"ordered" json:"ordered"`
+		Retry          *RetryDefinition       `yaml:"retry" json:"retry"`
+		Label          string                 `yaml:"label" json:"label"`
+		Redact         bool                   `yaml:"redact" json:"redact"` // redacts the payloads with the default redactor
+	}
+	// RetryDefinition declares how the failed business events are retried at the action within the invocation
+	RetryDefinition struct {
+		Attempts          int     `yaml:"attempts" json:"attempts"`
+		BackoffBase       string  `yaml:"backoff_base" json:"backoff_base"`
+		BackoffMax        string  `yaml:"backoff_max" json:"backoff_max"`
+		BackoffMultiplier float64 `yaml:"backoff_multiplier" json:"backoff_multiplier"`
+		BackoffJitter     float64 `yaml:"backoff_jitter" json:"backoff_jitter"`
+	}
+	// OptionDefinition declares a transform or an enrich option of an action by the name it was registered with.
+	// The Event of an enrich option is the name of the event it enriches, empty meaning all of them
+	OptionDefinition struct {
+		Name   string                 `yaml:"name" json:"name"`
+		Event  string                 `yaml:"event" json:"event"`
+		Params map[string]interface{} `yaml:"params" json:"params"`
+		Deps   map[string]string      `yaml:"deps" json:"deps"`
+	}
+)
+
+// Load builds a new pipeline from a YAML or JSON document, with the actions, options and dependencies of the registry
+func (r *Registry) Load(entity model.Entity, doc []byte) (*Pipeline, error) {
+	var def Definition
+	// JSON is valid YAML, so the same parser takes care of both
+	if err := yaml.Unmarshal(doc, &def); err != nil {
+		return nil, fmt.Errorf("unmarshal pipeline definition fail: %w", err)
+	}
+
+	return r.Build(entity, def)
+}
+
+// Build builds a new pipeline from the definition, with the actions, options and dependencies of the registry
+func (r *Registry) Build(entity model.Entity, def Definition) (*Pipeline, error) {
+	if len(def.Actions) == 0 {
+		return nil, fmt.Errorf("no actions were defined for the pipeline")
+	}
+
+	var options []Option
+
+	if def.Concurrency > 0 {
+		options = append(options, Concurrency(def.Concurrency))
+	}
+
+	if def.Ordered {
+		options = append(options, Ordered(model.GroupKey))
+	}
+
+	if def.DeadlineMargin != "" {
+		margin, err := time.ParseDuration(def.DeadlineMargin)
+		if err != nil {
+			return nil, fmt.Errorf("parse deadline margin fail: %w", err)
+		}
+		options = append(options, DeadlineMargin(margin))
+	}
+
+	if def.Journal {
+		options = append(options, Journal())
+	}
+
+	switch def.MissingAction {
+	case "", "restart":
+	case "park":
+		options = append(options, MissingActionFallback(ParkEvent))
+	default:
+		return nil, fmt.Errorf("unknown missing action fallback '%s'", def.MissingAction)
+	}
+
+	if def.Metrics != nil {
+		dep, ok := r.deps[def.Metrics.Metrics]
+		if !ok {
+			return nil, fmt.Errorf("dependency '%s' for 'metrics' is not provided", def.Metrics.Metrics)
+		}
+		m, ok := dep.(model.Metrics)
+		if !ok {
+			return nil, fmt.Errorf("dependency '%s' is of type %T, not metrics", def.Metrics.Metrics, dep)
+		}
+		options = append(options, Metrics(m, def.Metrics.Name))
+	}
+
+	if def.Tracer != "" {
+		dep, ok := r.deps[def.Tracer]
+		if !ok {
+			return nil, fmt.Errorf("dependency '%s' for 'tracer' is not provided", def.Tracer)
+		}
+		t, ok := dep.(model.Tracer)
+		if !ok {
+			return nil, fmt.Errorf("dependency '%s' is of type %T, not a tracer", def.Tracer, dep)
+		}
+		options = append(options, Tracing(t))
+	}
+
+	if def.Idempotency != nil {
+		opt, err := r.idempotency(*def.Idempotency)
+		if err != nil {
+			return nil, fmt.Errorf("build idempotency fail: %w", err)
+		}
+		options = append(options, opt)
+	}
+
+	for i, actDef := range def.Actions {
+		opt, err := r.action(actDef)
+		if err != nil {
+			return nil, fmt.Errorf("build action #%d '%s' fail: %w", i, actDef.Action, err)
+		}
+		options = append(options, opt)
+	}
+
+	p, err := newPipeline(entity, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *Registry) idempotency(def IdempotencyDefinition) (Option, error) {
+	dep, ok := r.deps[def.Store]
+	if !ok {
+		return nil, fmt.Errorf("dependency '%s' for 'store' is not provided", def.Store)
+	}
+
+	store, ok := dep.(actions.IIdempotencyStore)
+	if !ok {
+		return nil, fmt.Errorf("dependency '%s' is of type %T, not an idempotency store", def.Store, dep)
+	}
+
+	var options []IdempotencyOption
+
+	switch def.Key {
+	case "", "id":
+	case "content_hash":
+		options = append(options, ContentHash())
+	default:
+		return nil, fmt.Errorf("unknown idempotency key '%s'", def.Key)
+	}
+
+	inProgress, completed := defaultInProgressTTL, defaultCompletedTTL
+
+	if def.InProgressTTL != "" {
+		d, err := time.ParseDuration(def.InProgressTTL)
+		if err != nil {
+			return nil, fmt.Errorf("parse in progress ttl fail: %w", err)
+		}
+		inProgress = d
+	}
+
+	if def.CompletedTTL != "" {
+		d, err := time.ParseDuration(def.CompletedTTL)
+		if err != nil {
+			return nil, fmt.Errorf("parse completed ttl fail: %w", err)
+		}
+		completed = d
+	}
+
+	options = append(options, IdempotencyTTL(inProgress, completed))
+
+	return Idempotency(store, options...), nil
+}
+
+func (r *Registry) action(def ActionDefinition) (Option, error) {
+	factory, ok := r.actions[def.Action]
+	if !ok {
+		return nil, fmt.Errorf("action '%s' is not registered", def.Action)
+	}
+
+	base, err := baseOptions(def)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := r.resolve(def.Deps)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory(Args{
+		Params:   def.Params,
+		Base:     base,
+		deps:     deps,
+		options:  def.Options,
+		registry: r,
+	})
+}
+
+// baseOptions turns the common settings of the action definition into BaseOptions
+func baseOptions(def ActionDefinition) ([]actions.BaseOption, error) {
+	var base []actions.BaseOption
+
+	if def.BatchSize > 0 {
+		base = append(base, actions.BatchSize(def.BatchSize))
+	}
+
+	if def.FailureMandate != "" {
+		mandate, err := model.ParseActionMandate(def.FailureMandate)
+		if err != nil {
+			return nil, err
+		}
+		base = append(base, actions.FailureMandate(mandate))
+	}
+
+	if len(def.Skip) > 0 {
+		base = append(base, actions.Skip(def.Skip...))
+	}
+
+	if def.Async {
+		base = append(base, actions.Async())
+	}
+
+	if def.Critical {
+		base = append(base, actions.Critical())
+	}
+
+	if len(def.DependsOn) > 0 {
+		base = append(base, actions.DependsOn(def.DependsOn...))
+	}
+
+	if def.Timeout != "" {
+		timeout, err := time.ParseDuration(def.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("parse timeout fail: %w", err)
+		}
+		base = append(base, actions.Timeout(timeout))
+	}
+
+	if def.Concurrency > 0 {
+		base = append(base, actions.Concurrency(def.Concurrency))
+	}
+
+	if def.Ordered {
+		base = append(base, actions.Ordered())
+	}
+
+	if def.Label != "" {
+		base = append(base, actions.Label(def.Label))
+	}
+
+	if def.Redact {
+		base = append(base, actions.Redact(model.Redactor()))
+	}
+
+	if def.Retry != nil {
+		policy, err := retryPolicy(*def.Retry)
+		if err != nil {
+			return nil, err
+		}
+		base = append(base, actions.Retry(policy))
+	}
+
+	return base, nil
+}
+
+func retryPolicy(def RetryDefinition) (actions.RetryPolicy, error) {
+	policy := actions.RetryPolicy{
+		Attempts: def.Attempts,
+		Backoff: actions.Backoff{
+			Multiplier: def.BackoffMultiplier,
+			Jitter:     def.BackoffJitter,
+		},
+	}
+
+	var err error
+
+	if def.BackoffBase != "" {
+		if policy.Backoff.Base, err = time.ParseDuration(def.BackoffBase); err != nil {
+			return policy, fmt.Errorf("parse retry backoff base fail: %w", err)
+		}
+	}
+
+	if def.BackoffMax != "" {
+		if policy.Backoff.Max, err = time.ParseDuration(def.BackoffMax); err != nil {
+			return policy, fmt.Errorf("parse retry backoff max fail: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// resolve maps the parameter names of the definition to the provided dependencies
+func (r *Registry) resolve(names map[string]string) (map[string]interface{}, error) {
+	deps := make(map[string]interface{}, len(names))
+
+	for param, name := range names {
+		dep, ok := r.deps[name]
+		if !ok {
+			return nil, fmt.Errorf("dependency '%s' for '%s' is not provided", name, param)
+		}
+		deps[param] = dep
+	}
+
+	return deps, nil
+}
@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+const productDefinition = `
+journal: true
+deadline_margin: 2s
+actions:
+  - action: InputTransformer
+    options:
+      - name: RecordsFromFilePointer
+        params: {file_key: filePointer, category: product, source: Zale144, event_name: CreateProduct}
+        deps: {downloader: downloader}
+      - name: RecordsFromKey
+        params: {category: product, source: Zale144}
+  - action: Uploader
+    deps: {uploader: uploader}
+    skip: [UpdateProduct]
+  - action: Enricher
+    options:
+      - name: WithDedupe
+        event: CreateProduct
+        deps: {repository: products}
+      - name: WithSubEntity
+        event: CreateProduct
+        params: {sub_entity: store}
+        deps: {repository: stores}
+      - name: WithPatchOriginal
+        event: UpdateProduct
+        deps: {repository: products}
+  - action: Persister
+    deps: {repository: products}
+    batch_size: 40
+    failure_mandate: StopAndRetry
+    retry: {attempts: 3, backoff_base: 100ms}
+  - action: Publisher
+    deps: {publisher: publisher}
+    batch_size: 10
+    async: true
+    concurrency: 2
+    timeout: 5s
+    failure_mandate: StopAndRetry
+  - action: Republisher
+    deps: {republisher: queue}
+    params: {max_attempts: 5, backoff_base: 1s, backoff_max: 1m, backoff_jitter: 0.2}
+`
+
+func newTestRegistry(ctrl *gomock.Controller) *Registry {
+	return NewRegistry().
+		Provide("downloader", actions.NewMockIDownloader(ctrl)).
+		Provide("uploader", actions.NewMockIUploader(ctrl)).
+		Provide("products", actions.NewMockIRepository(ctrl)).
+		Provide("stores", actions.NewMockIRepository(ctrl)).
+		Provide("publisher", actions.NewMockIPublisher(ctrl)).
+		Provide("queue", actions.NewMockIRepublisher(ctrl))
+}
+
+func TestRegistry_Load(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p, err := newTestRegistry(ctrl).Load(&product{}, []byte(productDefinition))
+	require.NoError(t, err)
+
+	var names []string
+	for _, act := range p.actions {
+		names = append(names, act.Name())
+	}
+
+	assert.Equal(t, []string{"InputTransformer", "Uploader", "Enricher", "Persister", "Publisher"}, names)
+	assert.True(t, p.journal)
+	assert.Equal(t, 2*time.Second, p.deadlineMargin)
+
+	persister := p.actions[3]
+	assert.Equal(t, 40, persister.BatchSize())
+	assert.Equal(t, model.StopAndRetry, persister.FailureMandate())
+
+	publisher := p.actions[4].(*actions.Publish)
+	assert.Equal(t, 10, publisher.BatchSize())
+	assert.True(t, publisher.IsAsync())
+	assert.Equal(t, 2, publisher.Concurrency())
+	assert.Equal(t, 5*time.Second, publisher.Timeout())
+
+	retry := persister.(*actions.Persist).RetryPolicy()
+	require.NotNil(t, retry)
+	assert.Equal(t, 3, retry.Attempts)
+	assert.Equal(t, 100*time.Millisecond, retry.Backoff.Base)
+
+	require.Len(t, p.afterEach, 1)
+	assert.Equal(t, "Republisher", p.afterEach[0].Name())
+
+	it := p.actions[0].(*actions.InputTransform)
+	assert.Len(t, it.Transforms, 2)
+	assert.Equal(t, []string{"DownloadFileFromBucket"}, it.DepCallNames())
+}
+
+func TestRegistry_Load_json(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	doc := `{"actions": [
+		{"action": "InputTransformer", "options": [
+			{"name": "EventFromQueueSource", "params": {"category": "product", "source": "Zale144", "event_names": {"createQueue": "CreateProduct"}}}
+		]},
+		{"action": "Persister", "deps": {"repository": "products"}, "failure_mandate": "LogFailureAndContinue", "critical": true}
+	]}`
+
+	p, err := newTestRegistry(ctrl).Load(&product{}, []byte(doc))
+	require.NoError(t, err)
+	require.Len(t, p.actions, 2)
+
+	assert.True(t, p.actions[1].IsCritical())
+	assert.Equal(t, model.LogFailureAndContinue, p.actions[1].FailureMandate())
+}
+
+func TestRegistry_Load_fail(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{
+			name:    "no actions",
+			doc:     `journal: true`,
+			wantErr: "no actions were defined for the pipeline",
+		},
+		{
+			name:    "unknown action",
+			doc:     `actions: [{action: Teleporter}]`,
+			wantErr: "build action #0 'Teleporter' fail: action 'Teleporter' is not registered",
+		},
+		{
+			name:    "unknown dependency",
+			doc:     `actions: [{action: Persister, deps: {repository: cars}}]`,
+			wantErr: "build action #0 'Persister' fail: dependency 'cars' for 'repository' is not provided",
+		},
+		{
+			name:    "wrong dependency",
+			doc:     `actions: [{action: Persister, deps: {repository: publisher}}]`,
+			wantErr: "build action #0 'Persister' fail: dependency 'repository' is of type *actions.MockIPublisher, not actions.IRepository",
+		},
+		{
+			name:    "unknown mandate",
+			doc:     `actions: [{action: Persister, deps: {repository: products}, failure_mandate: Panic}]`,
+			wantErr: "build action #0 'Persister' fail: unknown action mandate 'Panic'",
+		},
+		{
+			name:    "unknown transform",
+			doc:     `actions: [{action: InputTransformer, options: [{name: Magic}]}]`,
+			wantErr: "build action #0 'InputTransformer' fail: transform 'Magic' is not registered",
+		},
+		{
+			name:    "missing param",
+			doc:     `actions: [{action: InputTransformer, options: [{name: RecordsFromKey, params: {category: product}}]}]`,
+			wantErr: "build action #0 'InputTransformer' fail: build transform 'RecordsFromKey' fail: string parameter 'source' is missing",
+		},
+		{
+			name:    "unknown depends_on",
+			doc:     `actions: [{action: Persister, deps: {repository: products}, depends_on: [Teleporter]}]`,
+			wantErr: "build pipeline fail: invalid pipeline action dependencies: action 'Persister' depends on 'Teleporter', which is not declared before it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestRegistry(ctrl).Load(&product{}, []byte(tt.doc))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type (
+	// Description describes the actions of a pipeline, as they were configured
+	Description struct {
+		Actions []ActionDescription `json:"actions"`
+		// Layers are the indexes of the actions that run at the same time, in the order they run
+		Layers [][]int `json:"layers"`
+		// AfterEach are the actions that run after each layer, their index being the position in the list
+		AfterEach []ActionDescription `json:"after_each,omitempty"`
+	}
+	// ActionDescription describes a pipeline action
+	ActionDescription struct {
+		Index          int                `json:"index"`
+		Name           string             `json:"name"`
+		ID             string             `json:"id,omitempty"` // what the republished business events resume at
+		BatchSize      int                `json:"batch_size,omitempty"`
+		Async          bool               `json:"async,omitempty"`
+		Critical       bool               `json:"critical,omitempty"`
+		FailureMandate string             `json:"failure_mandate,omitempty"`
+		Skips          []string           `json:"skips,omitempty"`
+		DepCallNames   []string           `json:"dep_call_names,omitempty"`
+		Timeout        time.Duration      `json:"timeout,omitempty"`
+		Concurrency    int                `json:"concurrency,omitempty"`
+		Attempts       int                `json:"attempts,omitempty"` // how many times a failed event is processed within the invocation
+		After          []int              `json:"after,omitempty"`    // the indexes of the actions it runs after
+		Routes         []RouteDescription `json:"routes,omitempty"`
+	}
+	// RouteDescription describes a route of a Router
+	RouteDescription struct {
+		Name     string      `json:"name"`
+		Pipeline Description `json:"pipeline"`
+	}
+)
+
+// Describe returns the description of the pipeline actions, including the ones of the routes
+func (p *Pipeline) Describe() Description {
+	var d Description
+
+	for pos, act := range p.actions {
+		ad := describe(act, p.offset+pos)
+		ad.ID = p.ids.byIndex[p.offset+pos]
+
+		// the dependencies were already resolved when the pipeline was constructed
+		deps, _ := dependencies(p.actions, pos)
+		for _, dep := range deps {
+			ad.After = append(ad.After, p.offset+dep)
+		}
+
+		if r, ok := act.(*Router); ok {
+			for _, rt := range r.routes {
+				ad.Routes = append(ad.Routes, RouteDescription{Name: rt.name, Pipeline: rt.pipeline.Describe()})
+			}
+		}
+
+		d.Actions = append(d.Actions, ad)
+	}
+
+	for _, lyr := range p.layers {
+		idxs := make([]int, len(lyr))
+		for i, pos := range lyr {
+			idxs[i] = p.offset + pos
+		}
+		d.Layers = append(d.Layers, idxs)
+	}
+
+	for i, act := range p.afterEach {
+		d.AfterEach = append(d.AfterEach, describe(act, i))
+	}
+
+	return d
+}
+
+func describe(act action, idx int) ActionDescription {
+	ad := ActionDescription{
+		Index:        idx,
+		Name:         act.Name(),
+		BatchSize:    act.BatchSize(),
+		Async:        act.IsAsync(),
+		Critical:     act.IsCritical(),
+		DepCallNames: act.DepCallNames(),
+	}
+
+	if act.FailureMandate() != 0 {
+		ad.FailureMandate = act.FailureMandate().String()
+	}
+
+	if s, ok := act.(interface{ Skips() []string }); ok && len(s.Skips()) > 0 {
+		ad.Skips = s.Skips()
+	}
+
+	if t, ok := act.(timed); ok {
+		ad.Timeout = t.Timeout()
+	}
+
+	if c, ok := act.(concurrent); ok {
+		ad.Concurrency = c.Concurrency()
+	}
+
+	if r, ok := act.(retrying); ok && r.RetryPolicy() != nil {
+		ad.Attempts = r.RetryPolicy().Attempts
+	}
+
+	return ad
+}
+
+// details lists the configuration of the action worth showing on a diagram
+func (a ActionDescription) details() []string {
+	var details []string
+
+	if a.ID != "" && a.ID != a.Name {
+		details = append(details, "id: "+a.ID)
+	}
+	if a.BatchSize > 0 {
+		details = append(details, fmt.Sprintf("batch size: %d", a.BatchSize))
+	}
+	if a.FailureMandate != "" {
+		details = append(details, "on failure: "+a.FailureMandate)
+	}
+	if a.Async {
+		details = append(details, "async")
+	}
+	if a.Critical {
+		details = append(details, "critical")
+	}
+	if a.Concurrency > 0 {
+		details = append(details, fmt.Sprintf("concurrency: %d", a.Concurrency))
+	}
+	if a.Timeout > 0 {
+		details = append(details, "timeout: "+a.Timeout.String())
+	}
+	if a.Attempts > 1 {
+		details = append(details, fmt.Sprintf("attempts: %d", a.Attempts))
+	}
+	if len(a.Skips) > 0 {
+		details = append(details, "skips: "+strings.Join(a.Skips, ", "))
+	}
+	if len(a.DepCallNames) > 0 && len(a.Routes) == 0 {
+		details = append(details, "calls: "+strings.Join(a.DepCallNames, ", "))
+	}
+
+	return details
+}
+
+func (d Description) afterEachNames() []string {
+	names := make([]string, len(d.AfterEach))
+	for i, a := range d.AfterEach {
+		names[i] = a.Name
+	}
+
+	return names
+}
+
+// DOT renders the pipeline as a Graphviz digraph
+func (d Description) DOT() string {
+	b := &strings.Builder{}
+
+	b.WriteString("digraph pipeline {\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+	d.writeDOT(b, "\t", "")
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+func (d Description) writeDOT(b *strings.Builder, indent, prefix string) {
+	dotLabel := func(lines []string) string {
+		return strings.ReplaceAll(strings.Join(lines, `\n`), `"`, `\"`)
+	}
+
+	for _, a := range d.Actions {
+		fmt.Fprintf(b, "%sa%d [label=\"%s\"];\n", indent, a.Index, dotLabel(append([]string{a.Name}, a.details()...)))
+
+		for _, dep := range a.After {
+			fmt.Fprintf(b, "%sa%d -> a%d;\n", indent, dep, a.Index)
+		}
+
+		for i, rt := range a.Routes {
+			id := fmt.Sprintf("%sa%d_%d", prefix, a.Index, i)
+
+			fmt.Fprintf(b, "%ssubgraph cluster_%s {\n", indent, id)
+			fmt.Fprintf(b, "%s\tlabel=\"%s\";\n", indent, dotLabel([]string{rt.Name}))
+			rt.Pipeline.writeDOT(b, indent+"\t", id+"_")
+			fmt.Fprintf(b, "%s}\n", indent)
+
+			for _, entry := range rt.Pipeline.entries() {
+				fmt.Fprintf(b, "%sa%d -> a%d [label=\"%s\"];\n", indent, a.Index, entry, dotLabel([]string{rt.Name}))
+			}
+		}
+	}
+
+	if len(d.AfterEach) > 0 {
+		lines := append([]string{"after each action:"}, d.afterEachNames()...)
+		fmt.Fprintf(b, "%s%safter_each [shape=note, label=\"%s\"];\n", indent, prefix, dotLabel(lines))
+	}
+}
+
+// Mermaid renders the pipeline as a Mermaid flowchart
+func (d Description) Mermaid() string {
+	b := &strings.Builder{}
+
+	b.WriteString("flowchart TD\n")
+	d.writeMermaid(b, "\t", "")
+
+	return b.String()
+}
+
+func (d Description) writeMermaid(b *strings.Builder, indent, prefix string) {
+	mermaidLabel := func(lines []string) string {
+		return strings.ReplaceAll(strings.Join(lines, "<br/>"), `"`, "#quot;")
+	}
+
+	for _, a := range d.Actions {
+		fmt.Fprintf(b, "%sa%d[\"%s\"]\n", indent, a.Index, mermaidLabel(append([]string{"<b>" + a.Name + "</b>"}, a.details()...)))
+
+		for _, dep := range a.After {
+			fmt.Fprintf(b, "%sa%d --> a%d\n", indent, dep, a.Index)
+		}
+
+		for i, rt := range a.Routes {
+			id := fmt.Sprintf("%sa%d_%d", prefix, a.Index, i)
+
+			fmt.Fprintf(b, "%ssubgraph %s [\"%s\"]\n", indent, id, mermaidLabel([]string{rt.Name}))
+			rt.Pipeline.writeMermaid(b, indent+"\t", id+"_")
+			fmt.Fprintf(b, "%send\n", indent)
+
+			for _, entry := range rt.Pipeline.entries() {
+				fmt.Fprintf(b, "%sa%d -->|%s| a%d\n", indent, a.Index, mermaidLabel([]string{rt.Name}), entry)
+			}
+		}
+	}
+
+	if len(d.AfterEach) > 0 {
+		lines := append([]string{"after each action:"}, d.afterEachNames()...)
+		fmt.Fprintf(b, "%s%safter_each>\"%s\"]\n", indent, prefix, mermaidLabel(lines))
+	}
+}
+
+// PlantUML renders the pipeline as a PlantUML activity diagram
+func (d Description) PlantUML() string {
+	b := &strings.Builder{}
+
+	b.WriteString("@startuml\n")
+	b.WriteString("title Pipeline Activity Diagram\n\n")
+	b.WriteString("start\n")
+	d.writePlantUML(b, "")
+	b.WriteString("stop\n")
+	b.WriteString("@enduml\n")
+
+	return b.String()
+}
+
+func (d Description) writePlantUML(b *strings.Builder, indent string) {
+	byIndex := make(map[int]ActionDescription, len(d.Actions))
+	for _, a := range d.Actions {
+		byIndex[a.Index] = a
+	}
+
+	for _, lyr := range d.Layers {
+		if len(lyr) == 1 {
+			byIndex[lyr[0]].writePlantUML(b, indent)
+			continue
+		}
+
+		for i, idx := range lyr {
+			if i == 0 {
+				fmt.Fprintf(b, "%sfork\n", indent)
+			} else {
+				fmt.Fprintf(b, "%sfork again\n", indent)
+			}
+			byIndex[idx].writePlantUML(b, indent+"\t")
+		}
+		fmt.Fprintf(b, "%send fork\n", indent)
+	}
+
+	if len(d.AfterEach) > 0 {
+		fmt.Fprintf(b, "%sfloating note right\n", indent)
+		fmt.Fprintf(b, "%s\tafter each action:\n", indent)
+		for _, name := range d.afterEachNames() {
+			fmt.Fprintf(b, "%s\t* %s\n", indent, name)
+		}
+		fmt.Fprintf(b, "%send note\n", indent)
+	}
+}
+
+func (a ActionDescription) writePlantUML(b *strings.Builder, indent string) {
+	if len(a.Routes) > 0 {
+		fmt.Fprintf(b, "%sswitch (**%s**)\n", indent, a.Name)
+		for _, rt := range a.Routes {
+			fmt.Fprintf(b, "%scase (%s)\n", indent, rt.Name)
+			rt.Pipeline.writePlantUML(b, indent+"\t")
+		}
+		fmt.Fprintf(b, "%sendswitch\n", indent)
+
+		return
+	}
+
+	fmt.Fprintf(b, "%s:**%s**;\n", indent, a.Name)
+
+	details := a.details()
+	if len(details) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "%snote right\n", indent)
+	for _, detail := range details {
+		fmt.Fprintf(b, "%s\t* %s\n", indent, detail)
+	}
+	fmt.Fprintf(b, "%send note\n", indent)
+}
+
+// entries returns the indexes of the actions that run first
+func (d Description) entries() []int {
+	if len(d.Layers) == 0 {
+		return nil
+	}
+
+	return d.Layers[0]
+}
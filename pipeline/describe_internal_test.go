@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+func describedPipeline() *Pipeline {
+	return NewPipeline(&product{},
+		Action(newRecordingAction("source", actions.BatchSize(10), actions.Critical())),
+		Action(newRecordingAction("left", actions.DependsOn("source"), actions.Skip("DeleteProduct"))),
+		Action(newRecordingAction("right", actions.DependsOn("source"), actions.Async(),
+			actions.Concurrency(2), actions.Timeout(time.Second), actions.FailureMandate(model.StopAndRetry))),
+		Route(
+			Branch("create", OnEventName("CreateProduct"), Action(newRecordingAction("create"))),
+		),
+		AfterEach(newRecordingAction("after")),
+	)
+}
+
+func TestPipeline_Describe(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	want := Description{
+		Actions: []ActionDescription{
+			{Index: 0, Name: "source", ID: "source", BatchSize: 10, Critical: true, DepCallNames: []string{"source"}},
+			{Index: 1, Name: "left", ID: "left", Skips: []string{"DeleteProduct"}, DepCallNames: []string{"left"}, After: []int{0}},
+			{
+				Index: 2, Name: "right", ID: "right", Async: true, FailureMandate: "StopAndRetry", DepCallNames: []string{"right"},
+				Timeout: time.Second, Concurrency: 2, After: []int{0},
+			},
+			{
+				Index: 3, Name: "Router", ID: "Router", DepCallNames: []string{"create"}, After: []int{2},
+				Routes: []RouteDescription{{
+					Name: "create",
+					Pipeline: Description{
+						Actions: []ActionDescription{{Index: 4, Name: "create", ID: "create", DepCallNames: []string{"create"}}},
+						Layers:  [][]int{{4}},
+					},
+				}},
+			},
+		},
+		Layers:    [][]int{{0}, {1, 2}, {3}},
+		AfterEach: []ActionDescription{{Index: 0, Name: "after", DepCallNames: []string{"after"}}},
+	}
+
+	assert.Equal(t, want, describedPipeline().Describe())
+}
+
+func TestDescription_render(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	d := describedPipeline().Describe()
+
+	assert.Equal(t, `digraph pipeline {
+	node [shape=box, style=rounded];
+	a0 [label="source\nbatch size: 10\ncritical\ncalls: source"];
+	a1 [label="left\nskips: DeleteProduct\ncalls: left"];
+	a0 -> a1;
+	a2 [label="right\non failure: StopAndRetry\nasync\nconcurrency: 2\ntimeout: 1s\ncalls: right"];
+	a0 -> a2;
+	a3 [label="Router"];
+	a2 -> a3;
+	subgraph cluster_a3_0 {
+		label="create";
+		a4 [label="create\ncalls: create"];
+	}
+	a3 -> a4 [label="create"];
+	after_each [shape=note, label="after each action:\nafter"];
+}
+`, d.DOT())
+
+	assert.Equal(t, `flowchart TD
+	a0["<b>source</b><br/>batch size: 10<br/>critical<br/>calls: source"]
+	a1["<b>left</b><br/>skips: DeleteProduct<br/>calls: left"]
+	a0 --> a1
+	a2["<b>right</b><br/>on failure: StopAndRetry<br/>async<br/>concurrency: 2<br/>timeout: 1s<br/>calls: right"]
+	a0 --> a2
+	a3["<b>Router</b>"]
+	a2 --> a3
+	subgraph a3_0 ["create"]
+		a4["<b>create</b><br/>calls: create"]
+	end
+	a3 -->|create| a4
+	after_each>"after each action:<br/>after"]
+`, d.Mermaid())
+
+	assert.Equal(t, `@startuml
+title Pipeline Activity Diagram
+
+start
+:**source**;
+note right
+	* batch size: 10
+	* critical
+	* calls: source
+end note
+fork
+	:**left**;
+	note right
+		* skips: DeleteProduct
+		* calls: left
+	end note
+fork again
+	:**right**;
+	note right
+		* on failure: StopAndRetry
+		* async
+		* concurrency: 2
+		* timeout: 1s
+		* calls: right
+	end note
+end fork
+switch (**Router**)
+case (create)
+	:**create**;
+	note right
+		* calls: create
+	end note
+endswitch
+floating note right
+	after each action:
+	* after
+end note
+stop
+@enduml
+`, d.PlantUML())
+}
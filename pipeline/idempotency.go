@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+const (
+	defaultInProgressTTL = 15 * time.Minute
+	defaultCompletedTTL  = 24 * time.Hour
+)
+
+type (
+	// IdempotencyOption is a func type abstraction of the idempotency configuration
+	IdempotencyOption func(*idempotency)
+
+	idempotency struct {
+		store         actions.IIdempotencyStore
+		key           func(in model.Input) string
+		inProgressTTL time.Duration
+		completedTTL  time.Duration
+	}
+)
+
+// Idempotency makes the pipeline skip the messages it already processed successfully, e.g. the ones redelivered
+// by the queue. The messages are claimed in the store before they're processed, and marked as completed once their
+// business event makes it through the pipeline. The ones that fail are released, so that a retry is let through
+func Idempotency(store actions.IIdempotencyStore, options ...IdempotencyOption) Option {
+	return func(p *Pipeline) {
+		idem := &idempotency{
+			store:         store,
+			key:           func(in model.Input) string { return in.GetID() },
+			inProgressTTL: defaultInProgressTTL,
+			completedTTL:  defaultCompletedTTL,
+		}
+
+		for _, opt := range options {
+			opt(idem)
+		}
+
+		p.idempotency = idem
+	}
+}
+
+// IdempotencyKey sets how the idempotency key of a message is made. By default, it's the message ID
+func IdempotencyKey(key func(in model.Input) string) IdempotencyOption {
+	return func(i *idempotency) {
+		i.key = key
+	}
+}
+
+// ContentHash makes the hash of the message body its idempotency key, so the same content is processed only once
+// even when it comes in different messages
+func ContentHash() IdempotencyOption {
+	return IdempotencyKey(func(in model.Input) string {
+		sum := sha256.Sum256([]byte(in.GetBody()))
+		return hex.EncodeToString(sum[:])
+	})
+}
+
+// IdempotencyTTL sets how long a message stays in progress, in case its invocation never finishes,
+// and how long it's remembered as completed
+func IdempotencyTTL(inProgress, completed time.Duration) IdempotencyOption {
+	return func(i *idempotency) {
+		i.inProgressTTL = inProgress
+		i.completedTTL = completed
+	}
+}
+
+// claim claims the inputs, returning the ones to process along with their keys. The IDs of the inputs that were
+// processed already, and the ones that are being processed by another invocation, are added to the result
+func (i *idempotency) claim(ctx context.Context, inputs []model.Input, result *EventProcessingResult) ([]model.Input, []string) {
+	var (
+		claimed []model.Input
+		keys    []string
+	)
+
+	for _, in := range inputs {
+		key := i.key(in)
+
+		state, err := i.store.Claim(ctx, key, i.inProgressTTL)
+		if err != nil {
+			// better processing a message twice than not at all
+			model.LoggerFrom(ctx).Warn("Couldn't check if the message was processed already, so it goes through anyway.",
+				"id", in.GetID(), "error", err)
+			state = model.IdempotencyNew
+		}
+
+		switch state {
+		case model.IdempotencyCompleted:
+			result.Duplicates = append(result.Duplicates, in.GetID())
+		case model.IdempotencyInProgress:
+			result.InFlight = append(result.InFlight, in.GetID())
+		default:
+			claimed = append(claimed, in)
+			keys = append(keys, key)
+		}
+	}
+
+	if skipped := len(inputs) - len(claimed); skipped > 0 {
+		model.LoggerFrom(ctx).Info("Déjà vu! Some of the messages were seen before, so they're skipping the pipeline.",
+			"duplicates", result.Duplicates, "in flight", result.InFlight)
+	}
+
+	return claimed, keys
+}
+
+// settle marks the keys of the business events that made it through the pipeline as completed, and releases the rest
+func (i *idempotency) settle(ctx context.Context, keys []string, bes []model.Medium) {
+	for n, be := range bes {
+		var err error
+		if be.GetError() == nil {
+			err = i.store.Complete(ctx, keys[n], i.completedTTL)
+		} else {
+			err = i.store.Release(ctx, keys[n])
+		}
+
+		if err != nil {
+			model.LoggerFrom(ctx).Warn("Couldn't record how the message went, so it may be processed again.",
+				"key", keys[n], "error", err)
+		}
+	}
+}
+
+// release releases the keys, so that the messages are processed again when they're redelivered
+func (i *idempotency) release(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := i.store.Release(ctx, key); err != nil {
+			model.LoggerFrom(ctx).Warn("Couldn't release the message, so it'll have to wait for its claim to expire.",
+				"key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/memory"
+	"github.com/zale144/ube/model"
+)
+
+func TestIdempotency(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	act := newRecordingAction("act")
+	act.fail = map[string]error{"2": errors.New("boom")}
+
+	store := memory.NewIdempotencyStore()
+
+	p := NewPipeline(&product{}, Action(act), Idempotency(store))
+
+	_, err := p.InvokePipeline(ctx, parkInput("1"), parkInput("2"))
+	require.Error(t, err)
+
+	// the queue redelivers both of them, though only the failed one is processed again
+	act.fail = nil
+
+	result, err := p.InvokePipeline(ctx, parkInput("1"), parkInput("2"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, result.Status)
+	assert.Equal(t, []string{"msg_1"}, result.Duplicates)
+	assert.Equal(t, []string{"1", "2", "2"}, act.visited)
+
+	// nothing left to process
+	result, err = p.InvokePipeline(ctx, parkInput("1"), parkInput("2"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, result.Status)
+	assert.Equal(t, []string{"msg_1", "msg_2"}, result.Duplicates)
+	assert.Empty(t, result.BusinessEvents)
+	assert.Equal(t, []string{"1", "2", "2"}, act.visited)
+}
+
+func TestIdempotency_in_flight(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	act := newRecordingAction("act")
+	store := memory.NewIdempotencyStore()
+
+	// another invocation is processing it
+	_, err := store.Claim(ctx, "msg_1", time.Minute)
+	require.NoError(t, err)
+
+	p := NewPipeline(&product{}, Action(act), Idempotency(store))
+
+	result, err := p.InvokePipeline(ctx, parkInput("1"), parkInput("2"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"msg_1"}, result.InFlight)
+	assert.Equal(t, []string{"2"}, act.visited)
+}
+
+func TestIdempotency_content_hash(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	act := newRecordingAction("act")
+
+	p := NewPipeline(&product{}, Action(act), Idempotency(memory.NewIdempotencyStore(), ContentHash()))
+
+	same := parkInput("1").(*model.Message)
+	same.ID = "another_msg"
+
+	_, err := p.InvokePipeline(ctx, parkInput("1"))
+	require.NoError(t, err)
+
+	result, err := p.InvokePipeline(ctx, same)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"another_msg"}, result.Duplicates)
+	assert.Equal(t, []string{"1"}, act.visited)
+}
+
+func TestIdempotency_store_fail(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	act := newRecordingAction("act")
+
+	store := actions.NewMockIIdempotencyStore(ctrl)
+	store.EXPECT().Claim(gomock.Any(), "msg_1", defaultInProgressTTL).Return(model.IdempotencyNew, errors.New("throttled"))
+	store.EXPECT().Complete(gomock.Any(), "msg_1", time.Hour).Return(nil)
+
+	p := NewPipeline(&product{}, Action(act), Idempotency(store, IdempotencyTTL(defaultInProgressTTL, time.Hour)))
+
+	// better twice than never
+	result, err := p.InvokePipeline(ctx, parkInput("1"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, result.Status)
+	assert.Equal(t, []string{"1"}, act.visited)
+}
+
+func TestIdempotency_stream(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	act := newRecordingAction("act")
+	store := memory.NewIdempotencyStore()
+
+	p := NewPipeline(&product{}, Action(act), Idempotency(store))
+
+	_, err := p.InvokePipeline(ctx, parkInput("1"))
+	require.NoError(t, err)
+
+	inputs := make(chan model.Input, 2)
+	inputs <- parkInput("1")
+	inputs <- parkInput("2")
+	close(inputs)
+
+	var results []EventResult
+	for res := range p.InvokeStream(ctx, inputs, Window(2)) {
+		results = append(results, res)
+	}
+
+	// the stream skips the messages processed already, like the batches do
+	require.Len(t, results, 2)
+	assert.Equal(t, "msg_1", results[0].ID)
+	assert.ErrorIs(t, results[0].Err, model.ErrDuplicate)
+	assert.Nil(t, results[0].BusinessEvent)
+	assert.Equal(t, "msg_2", results[1].ID)
+	assert.NoError(t, results[1].Err)
+	require.NotNil(t, results[1].BusinessEvent)
+	assert.Equal(t, "2", results[1].BusinessEvent.GetID())
+	assert.Equal(t, []string{"1", "2"}, act.visited)
+
+	// and settles the ones it processed
+	state, err := store.Claim(ctx, "msg_2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, model.IdempotencyCompleted, state)
+}
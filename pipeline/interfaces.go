@@ -24,3 +24,21 @@ type action interface {
 	BatchSize() int
 	IsAsync() bool
 }
+
+// composite is an action that runs its own nested actions, which handle
+// the failures of each business event on their own
+type composite interface {
+	action
+	// process runs the business events through the nested actions
+	process(ctx context.Context, bes []model.Medium) []ActionOutcome
+	// link shares the pipeline settings with the nested actions, assigns their indexes
+	// starting at offset, and returns the next free action index
+	link(s *settings, offset int, l model.Logger) int
+	// check returns the error that makes the nested actions unfit to run, if any
+	check() error
+}
+
+// dependent is an action that declares the actions it depends on
+type dependent interface {
+	DependsOn() []string
+}
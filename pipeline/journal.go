@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+// Journal makes the pipeline keep a journal on each business event, with an entry for every action it went through.
+// The journal is kept in the event ChangeLog, so it's published, and republished, along with the event
+func Journal() Option {
+	return func(p *Pipeline) {
+		p.journal = true
+	}
+}
+
+type journalKey struct{}
+
+// withJournal marks the context of a pipeline that keeps a journal
+func withJournal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, journalKey{}, true)
+}
+
+func journaling(ctx context.Context) bool {
+	on, _ := ctx.Value(journalKey{}).(bool)
+	return on
+}
+
+// journaled is a business event that keeps a journal of the pipeline actions it went through
+type journaled interface {
+	LogProcessing(entry *model.MessageProcessingLog)
+}
+
+// journal adds an entry for the attempt of the action to the journal of the business event, if the pipeline keeps one
+func journal(ctx context.Context, be model.Medium, action action, actionIdx int, started, ended time.Time) {
+	j, ok := be.(journaled)
+	if !ok || !journaling(ctx) {
+		return
+	}
+
+	entry := &model.MessageProcessingLog{
+		ProcessedEventName: be.GetEventName(),
+		ProcessorID:        action.Name(),
+		ProcessorIndex:     actionIdx,
+		Status:             model.ProcessingSucceeded,
+		StartTime:          started.UTC().Format(time.RFC3339Nano),
+		EndTime:            ended.UTC().Format(time.RFC3339Nano),
+		Duration:           ended.Sub(started),
+		EntityCount:        len(be.GetEntities()),
+	}
+
+	if pbe, ok := be.(model.PipelineMedium); ok {
+		entry.ProcessedEventID = pbe.GetEventID()
+		if attempt := pbe.GetRepublishAttempt(); attempt != nil {
+			entry.Attempt = *attempt
+		}
+	}
+
+	if err := be.GetError(); err != nil {
+		entry.Status = model.ProcessingFailed
+		entry.Error = err.Error()
+		entry.Mandate = action.FailureMandate().String()
+	}
+
+	j.LogProcessing(entry)
+}
+
+// journalSkip adds an entry for the action that the business event was not processable by
+func journalSkip(ctx context.Context, be model.Medium, action action, actionIdx int) {
+	j, ok := be.(journaled)
+	if !ok || !journaling(ctx) {
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	entry := &model.MessageProcessingLog{
+		ProcessedEventName: be.GetEventName(),
+		ProcessorID:        action.Name(),
+		ProcessorIndex:     actionIdx,
+		Status:             model.ProcessingSkipped,
+		StartTime:          now,
+		EndTime:            now,
+		EntityCount:        len(be.GetEntities()),
+		Skipped:            true,
+	}
+
+	if pbe, ok := be.(model.PipelineMedium); ok {
+		entry.ProcessedEventID = pbe.GetEventID()
+	}
+
+	j.LogProcessing(entry)
+}
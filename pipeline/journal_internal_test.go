@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+type journalEntry struct {
+	action  string
+	index   int
+	status  string
+	mandate string
+	err     string
+	skipped bool
+}
+
+func entries(be *model.BusinessEvent) []journalEntry {
+	var got []journalEntry
+	for _, entry := range be.GetProcessingLog() {
+		got = append(got, journalEntry{
+			action:  entry.ProcessorID,
+			index:   entry.ProcessorIndex,
+			status:  entry.Status,
+			mandate: entry.Mandate,
+			err:     entry.Error,
+			skipped: entry.Skipped,
+		})
+	}
+
+	return got
+}
+
+func TestPipeline_journal(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := newRecordingAction("first", actions.FailureMandate(model.StopFurtherProcessing))
+	first.fail = map[string]error{"2": errors.New("boom")}
+	second := newRecordingAction("second")
+
+	p := NewPipeline(&product{}, Journal(), Action(first), Action(second))
+
+	ok, ko := newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct")
+	p.run(context.Background(), []model.Medium{ok, ko})
+
+	assert.Equal(t, []journalEntry{
+		{action: "first", index: 0, status: model.ProcessingSucceeded},
+		{action: "second", index: 1, status: model.ProcessingSucceeded},
+	}, entries(ok))
+	assert.Equal(t, []journalEntry{
+		{action: "first", index: 0, status: model.ProcessingFailed, mandate: "StopFurtherProcessing", err: "boom"},
+		{action: "second", index: 1, status: model.ProcessingSkipped, skipped: true},
+	}, entries(ko))
+
+	// the journal travels with the event
+	raw, err := json.Marshal(ko.Event)
+	require.NoError(t, err)
+
+	republished := &model.BusinessEvent{Event: &model.Event{}}
+	require.NoError(t, json.Unmarshal(raw, republished.Event))
+	assert.Equal(t, ko.GetProcessingLog(), republished.GetProcessingLog())
+}
+
+func TestPipeline_no_journal(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	be := newEvent("1", "CreateProduct")
+	NewPipeline(&product{}, Action(newRecordingAction("first"))).run(context.Background(), []model.Medium{be})
+
+	assert.Empty(t, be.Event.ChangeLog)
+}
+
+func TestPipeline_journal_concurrent(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	source := newRecordingAction("source")
+	left := newRecordingAction("left", actions.DependsOn("source"), actions.FailureMandate(model.LogFailureAndContinue))
+	left.fail = map[string]error{"1": errors.New("left")}
+	right := newRecordingAction("right", actions.DependsOn("source"), actions.FailureMandate(model.StopAndRaiseError))
+	right.fail = map[string]error{"1": errors.New("right")}
+
+	p := NewPipeline(&product{}, Journal(), Action(source), Action(left), Action(right))
+
+	be := newEvent("1", "CreateProduct")
+	p.run(context.Background(), []model.Medium{be})
+
+	assert.Equal(t, []journalEntry{
+		{action: "source", index: 0, status: model.ProcessingSucceeded},
+		{action: "left", index: 1, status: model.ProcessingFailed, mandate: "LogFailureAndContinue", err: "left"},
+		{action: "right", index: 2, status: model.ProcessingFailed, mandate: "StopAndRaiseError", err: "right"},
+	}, entries(be))
+	assert.EqualError(t, be.GetError(), "left")
+}
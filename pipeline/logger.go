@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/zale144/ube/model"
+)
+
+// Logger makes the pipeline, and the actions, log through the logger, rather than the one of the context
+// or the default one. Everything logged for an action is scoped to it with its ID
+func Logger(l model.Logger) Option {
+	return func(p *Pipeline) {
+		p.logger = l
+	}
+}
+
+// invocationLoggerKey keeps the logger of the invocation, for the actions of the routes not to nest their scopes
+type invocationLoggerKey struct{}
+
+// withLogger returns the context with the logger of the pipeline, if it has one, or the one of the context
+func (p *Pipeline) withLogger(ctx context.Context) context.Context {
+	l := p.logger
+	if l == nil {
+		l = model.LoggerFrom(ctx)
+	}
+
+	return context.WithValue(model.WithLogger(ctx, l), invocationLoggerKey{}, l)
+}
+
+// withActionLogger returns the context with the logger of the invocation scoped to the action
+func (p *Pipeline) withActionLogger(ctx context.Context, idx int) context.Context {
+	l, ok := ctx.Value(invocationLoggerKey{}).(model.Logger)
+	if !ok {
+		l = model.LoggerFrom(ctx)
+	}
+
+	return model.WithLogger(ctx, l.With("action_id", p.ids.byIndex[idx]))
+}
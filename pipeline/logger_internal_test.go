@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	globalCore, globalLogs := observer.New(zap.DebugLevel)
+	undo := zap.ReplaceGlobals(zap.New(globalCore))
+	defer undo()
+
+	persister := newRecordingAction("Persister", actions.Label("stock"), actions.BatchSize(10))
+	persister.fail = map[string]error{"2": errors.New("boom")}
+
+	p := NewPipeline(&product{}, Action(persister), Logger(model.NewZapLogger(zap.New(core))))
+
+	_, err := p.InvokePipeline(context.Background(), parkInput("1"), parkInput("2"))
+	require.Error(t, err)
+
+	// the pipeline logs through its own logger only
+	assert.Zero(t, globalLogs.Len())
+
+	started := logs.FilterMessageSnippet("Starting pipeline action").All()
+	require.Len(t, started, 1)
+	assert.Equal(t, "Persister:stock", started[0].ContextMap()["action_id"])
+
+	failed := logs.FilterField(zap.String("event_id", "2")).All()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "Persister:stock", failed[0].ContextMap()["action_id"])
+	assert.Equal(t, "created", failed[0].ContextMap()["event_name"])
+}
+
+func TestLogger_duplicate_action_IDs(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	globalCore, globalLogs := observer.New(zap.DebugLevel)
+	undo := zap.ReplaceGlobals(zap.New(globalCore))
+	defer undo()
+
+	NewPipeline(&product{},
+		Action(newRecordingAction("Publisher")),
+		Action(newRecordingAction("Publisher")),
+		Logger(model.NewZapLogger(zap.New(core))),
+	)
+
+	// the pipeline warns about the actions it can't tell apart through its own logger
+	assert.Zero(t, globalLogs.Len())
+	assert.Equal(t, 1, logs.FilterField(zap.String("id", "Publisher")).Len())
+}
+
+func TestLogger_Context(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := model.WithLogger(context.Background(), model.NewZapLogger(zap.New(core)))
+
+	p := NewPipeline(&product{}, Action(newRecordingAction("Persister")))
+
+	_, err := p.InvokePipeline(ctx, parkInput("1"))
+	require.NoError(t, err)
+
+	// without a logger of its own, the pipeline logs through the one of the context
+	assert.NotZero(t, logs.FilterField(zap.String("action_id", "Persister")).Len())
+}
@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+// Metrics makes the pipeline, its actions included, report its statistics: the business events in and out,
+// and the duration, batch size, errors and retries of every action. The name tags everything the pipeline reports,
+// so that the pipelines sharing the same metrics can be told apart
+func Metrics(metrics model.Metrics, name string) Option {
+	return func(p *Pipeline) {
+		p.metrics = metrics
+		p.metricTags = map[string]string{"pipeline": name}
+	}
+}
+
+// withMetrics returns the context that the pipeline reports its metrics through
+func (p *Pipeline) withMetrics(ctx context.Context) context.Context {
+	if p.metrics == nil {
+		return ctx
+	}
+
+	return model.WithMetricTags(model.WithMetrics(ctx, p.metrics), p.metricTags)
+}
+
+// measure reports the duration, size and errors of the action batches processed by fn.
+// Everything reported while the batch is processed is tagged with the ID of the action
+func (p *Pipeline) measure(fn batchFn) batchFn {
+	return func(ctx context.Context, bes []model.Medium, action action, actionIdx int) (int, int) {
+		if p.metrics == nil {
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		ctx = model.WithMetricTags(ctx, map[string]string{"action": p.ids.byIndex[actionIdx]})
+		m := model.MetricsFrom(ctx)
+
+		started := time.Now()
+		processed, failed := fn(ctx, bes, action, actionIdx)
+
+		if processed == 0 {
+			return processed, failed
+		}
+
+		m.Observe(model.MetricActionDuration, time.Since(started).Seconds(), nil)
+		m.Observe(model.MetricActionBatchSize, float64(processed), nil)
+
+		if failed > 0 {
+			m.Count(model.MetricActionErrors, float64(failed), map[string]string{"mandate": action.FailureMandate().String()})
+		}
+
+		return processed, failed
+	}
+}
+
+// countEvents reports the messages that came into the pipeline, and how their business events went out of it
+func countEvents(ctx context.Context, in int, result EventProcessingResult) {
+	m := model.MetricsFrom(ctx)
+
+	m.Count(model.MetricEventsIn, float64(in), nil)
+
+	var failed int
+	for _, be := range result.BusinessEvents {
+		if be.GetError() != nil {
+			failed++
+		}
+	}
+
+	out := []struct {
+		status string
+		count  int
+	}{
+		{"succeeded", len(result.BusinessEvents) - failed},
+		// the dead-lettered events failed too, though they're not coming back
+		{"failed", failed - len(result.DeadLettered)},
+		{"dead_lettered", len(result.DeadLettered)},
+		{"duplicate", len(result.Duplicates)},
+		{"in_flight", len(result.InFlight)},
+	}
+
+	for _, o := range out {
+		if o.count > 0 {
+			m.Count(model.MetricEventsOut, float64(o.count), map[string]string{"status": o.status})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/metrics"
+	"github.com/zale144/ube/model"
+)
+
+func TestMetrics(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	errFlaky := errors.New("flaky")
+
+	persister := newRecordingAction("Persister", actions.Label("stock"), actions.BatchSize(10), actions.FailureMandate(model.StopAndRetry),
+		actions.Retry(actions.RetryPolicy{Attempts: 2, Retryable: actions.RetryOn(errFlaky)}))
+	persister.fail = map[string]error{"2": errFlaky}
+	publisher := newRecordingAction("Publisher", actions.BatchSize(1))
+
+	reg := metrics.NewRegistry()
+
+	p := NewPipeline(&product{}, Action(persister), Action(publisher), Metrics(reg, "products"))
+
+	_, err := p.InvokePipeline(context.Background(), parkInput("1"), parkInput("2"), parkInput("3"))
+	require.Error(t, err)
+
+	pipelineTags := map[string]string{"pipeline": "products"}
+	persisterTags := map[string]string{"pipeline": "products", "action": "Persister:stock"}
+	publisherTags := map[string]string{"pipeline": "products", "action": "Publisher"}
+
+	assert.Equal(t, float64(3), reg.Value(model.MetricEventsIn, pipelineTags))
+	assert.Equal(t, float64(2), reg.Value(model.MetricEventsOut, map[string]string{"pipeline": "products", "status": "succeeded"}))
+	assert.Equal(t, float64(1), reg.Value(model.MetricEventsOut, map[string]string{"pipeline": "products", "status": "failed"}))
+
+	// a batch for the Persister, and one for each of the events that made it to the Publisher
+	assert.Equal(t, float64(1), reg.Value(model.MetricActionDuration, persisterTags))
+	assert.Equal(t, float64(2), reg.Value(model.MetricActionBatchSize, publisherTags))
+
+	assert.Equal(t, float64(1), reg.Value(model.MetricActionRetries, persisterTags))
+	assert.Equal(t, float64(1), reg.Value(model.MetricActionErrors,
+		map[string]string{"pipeline": "products", "action": "Persister:stock", "mandate": model.StopAndRetry.String()}))
+}
+
+func TestMetrics_concurrent_actions(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := newRecordingAction("first")
+	uploader := newRecordingAction("Uploader", actions.DependsOn("first"), actions.FailureMandate(model.StopAndRetry))
+	uploader.fail = map[string]error{"2": errors.New("upload failed")}
+	persister := newRecordingAction("Persister", actions.DependsOn("first"))
+
+	reg := metrics.NewRegistry()
+
+	p := NewPipeline(&product{}, Action(first), Action(uploader), Action(persister), Metrics(reg, "products"))
+
+	_, err := p.InvokePipeline(context.Background(), parkInput("1"), parkInput("2"), parkInput("3"))
+	require.Error(t, err)
+
+	// the failures of the actions running at the same time are counted the same as the others
+	assert.Equal(t, float64(1), reg.Value(model.MetricActionErrors,
+		map[string]string{"pipeline": "products", "action": "Uploader", "mandate": model.StopAndRetry.String()}))
+	assert.Zero(t, reg.Value(model.MetricActionErrors,
+		map[string]string{"pipeline": "products", "action": "Persister", "mandate": model.StopAndRaiseError.String()}))
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/zale144/ube/model"
+)
+
+type (
+	// ActionCall describes a single call of a pipeline action with a batch of business events
+	ActionCall struct {
+		// Action is the name of the called action
+		Action string
+		// Index is the index of the action in the pipeline
+		Index int
+		// Batch is the number of the batch, in the order the business events were split into batches
+		Batch int
+		// Started is the time the batch was handed to the middleware chain
+		Started time.Time
+	}
+	// ActionFunc processes a batch of business events on behalf of a pipeline action
+	ActionFunc func(ctx context.Context, call ActionCall, bes ...model.Medium)
+	// Middleware wraps the processing of every action batch. It can act before and after calling
+	// next, or not call it at all
+	Middleware func(next ActionFunc) ActionFunc
+)
+
+// Use adds middleware wrapping the Process of every pipeline action, including the ones of the routes.
+// The first middleware is the outermost one
+func Use(middleware ...Middleware) Option {
+	return func(p *Pipeline) {
+		p.middleware = append(p.middleware, middleware...)
+	}
+}
+
+// intercept hands the action to fn wrapped in the middleware chain of the pipeline
+func intercept(middleware []Middleware, fn batchFn) batchFn {
+	return func(ctx context.Context, bes []model.Medium, action action, actionIdx int) (int, int) {
+		switch action.(type) {
+		case postponed, timedOut, abandoned:
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		if len(middleware) == 0 {
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		return fn(ctx, bes, intercepted{action: action, index: actionIdx, middleware: middleware}, actionIdx)
+	}
+}
+
+// intercepted runs the Process of the action through the middleware chain
+type intercepted struct {
+	action
+	index      int
+	middleware []Middleware
+}
+
+func (a intercepted) unwrap() action {
+	return a.action
+}
+
+func (a intercepted) Process(ctx context.Context, bes ...model.Medium) {
+	next := func(ctx context.Context, _ ActionCall, bes ...model.Medium) {
+		a.action.Process(ctx, bes...)
+	}
+
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		next = a.middleware[i](next)
+	}
+
+	next(ctx, ActionCall{Action: a.Name(), Index: a.index, Batch: batchOf(ctx), Started: time.Now()}, bes...)
+}
+
+// Logging is a middleware that logs how each action batch went
+func Logging() Middleware {
+	return func(next ActionFunc) ActionFunc {
+		return func(ctx context.Context, call ActionCall, bes ...model.Medium) {
+			next(ctx, call, bes...)
+
+			var failed int
+			for _, be := range bes {
+				if be.GetError() != nil {
+					failed++
+				}
+			}
+
+			model.LoggerFrom(ctx).Info("pipeline action batch processed",
+				"action", call.Action,
+				"index", call.Index,
+				"batch", call.Batch,
+				"size", len(bes),
+				"failed", failed,
+				"duration", time.Since(call.Started))
+		}
+	}
+}
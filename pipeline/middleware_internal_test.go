@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+func TestPipeline_Use(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	var (
+		mu    sync.Mutex
+		trace []string
+		calls []ActionCall
+	)
+
+	record := func(name string) Middleware {
+		return func(next ActionFunc) ActionFunc {
+			return func(ctx context.Context, call ActionCall, bes ...model.Medium) {
+				mu.Lock()
+				trace = append(trace, name+" before "+call.Action)
+				mu.Unlock()
+
+				next(ctx, call, bes...)
+
+				mu.Lock()
+				trace = append(trace, name+" after "+call.Action)
+				calls = append(calls, call)
+				mu.Unlock()
+			}
+		}
+	}
+
+	deny := func(next ActionFunc) ActionFunc {
+		return func(ctx context.Context, call ActionCall, bes ...model.Medium) {
+			if call.Action == "guarded" {
+				for _, be := range bes {
+					be.SetError(errors.New("not allowed"))
+				}
+				return
+			}
+			next(ctx, call, bes...)
+		}
+	}
+
+	first := newRecordingAction("first", actions.BatchSize(2))
+	guarded := newRecordingAction("guarded", actions.BatchSize(2))
+
+	p := NewPipeline(&product{},
+		Use(record("outer"), record("inner"), deny, Logging()),
+		Action(first),
+		Action(guarded),
+	)
+
+	bes := []model.Medium{newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct")}
+	p.run(context.Background(), bes)
+
+	assert.Equal(t, []string{
+		"outer before first", "inner before first", "inner after first", "outer after first",
+		"outer before guarded", "inner before guarded", "inner after guarded", "outer after guarded",
+	}, trace)
+
+	assert.Equal(t, []string{"1", "2"}, first.visited)
+	assert.Empty(t, guarded.visited)
+	assert.EqualError(t, bes[0].GetError(), "not allowed")
+
+	for i, call := range calls {
+		assert.Equal(t, i/2, call.Index)
+		assert.WithinDuration(t, time.Now(), call.Started, time.Second)
+	}
+}
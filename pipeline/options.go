@@ -1,7 +1,10 @@
 package pipeline
 
 import (
+	"time"
+
 	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/encryption"
 )
 
 func Action(act action) Option {
@@ -17,6 +20,15 @@ func AfterEach(action ...action) Option {
 	}
 }
 
+// DeadlineMargin makes the pipeline stop starting new batches once the context deadline, e.g. the one of
+// the Lambda invocation, is closer than the margin. The business events that were left out are marked for
+// a retry at the action they didn't get to, so a Republisher can send them back to the queue
+func DeadlineMargin(margin time.Duration) Option {
+	return func(p *Pipeline) {
+		p.deadlineMargin = margin
+	}
+}
+
 // Enricher constructs a new action with the Enrich action
 func Enricher(enrichers ...actions.EnricherOption) Option {
 	return Action(actions.Enricher(enrichers...))
@@ -42,6 +54,16 @@ func Persister(repo actions.IRepository, options ...actions.BaseOption) Option {
 	return Action(actions.Persister(repo, options...))
 }
 
+// Encrypter constructs a new action with the Encrypt action
+func Encrypter(keys encryption.KeyProvider, options ...actions.BaseOption) Option {
+	return Action(actions.Encrypter(keys, options...))
+}
+
+// Decrypter constructs a new action with the Decrypt action
+func Decrypter(keys encryption.KeyProvider, options ...actions.BaseOption) Option {
+	return Action(actions.Decrypter(keys, options...))
+}
+
 // Publisher constructs a new action with the Publisher action
 func Publisher(publisher actions.IPublisher, options ...actions.BaseOption) Option {
 	return Action(actions.Publisher(publisher, options...))
@@ -51,3 +73,13 @@ func Publisher(publisher actions.IPublisher, options ...actions.BaseOption) Opti
 func Republisher(republisher actions.IRepublisher, maxAttempts int, options ...actions.BaseOption) Option {
 	return AfterEach(actions.Republisher(republisher, maxAttempts, options...))
 }
+
+// Parker constructs a new action with the Parker action
+func Parker(store actions.IParkStore, options ...actions.BaseOption) Option {
+	return AfterEach(actions.Parker(store, options...))
+}
+
+// Alerter constructs a new action with the Alerter action
+func Alerter(alerter actions.IAlerter, options ...actions.BaseOption) Option {
+	return AfterEach(actions.Alerter(alerter, options...))
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/zale144/ube/model"
+)
+
+// PanicError is set on the business events of a batch during which the action panicked
+type PanicError struct {
+	Action string
+	Value  interface{}
+	Stack  []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("action '%s' panicked: %v", e.Action, e.Value)
+}
+
+// invoke lets the action process the business events. A panic is recovered and turned into an error
+// on each of the business events, so that only the batch fails and not the whole invocation
+func invoke(ctx context.Context, action action, bes []model.Medium) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		err := &PanicError{Action: action.Name(), Value: r, Stack: debug.Stack()}
+
+		model.LoggerFrom(ctx).Error("Well, that escalated quickly. The pipeline action panicked, but we caught it before it took everyone down with it.",
+			"action", action.Name(), "error", err, "stack", string(err.Stack))
+
+		for _, be := range bes {
+			be.SetError(err)
+		}
+	}()
+
+	action.Process(ctx, bes...)
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+type panickingAction struct {
+	*recordingAction
+	panicOn string
+}
+
+func (a *panickingAction) Process(ctx context.Context, bes ...model.Medium) {
+	a.recordingAction.Process(ctx, bes...)
+	for _, be := range bes {
+		if be.GetID() == a.panicOn {
+			var ent *product
+			_ = ent.AnotherOne // nil pointer dereference
+		}
+	}
+}
+
+func TestPipeline_panic_fails_only_its_batch(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	for _, async := range []bool{false, true} {
+		options := []actions.BaseOption{actions.BatchSize(2), actions.FailureMandate(model.StopFurtherProcessing)}
+		if async {
+			options = append(options, actions.Async())
+		}
+
+		faulty := &panickingAction{recordingAction: newRecordingAction("faulty", options...), panicOn: "3"}
+		next := newRecordingAction("next")
+
+		p := NewPipeline(&product{}, Action(faulty), Action(next))
+
+		bes := []model.Medium{
+			newEvent("1", "CreateProduct"),
+			newEvent("2", "CreateProduct"),
+			newEvent("3", "CreateProduct"),
+			newEvent("4", "CreateProduct"),
+		}
+
+		outcomes := p.run(context.Background(), bes)
+
+		assert.NoError(t, bes[0].GetError())
+		assert.NoError(t, bes[1].GetError())
+
+		for _, be := range bes[2:] {
+			var panicErr *PanicError
+			require.True(t, errors.As(be.GetError(), &panicErr))
+			assert.Equal(t, "faulty", panicErr.Action)
+			assert.Contains(t, panicErr.Error(), "action 'faulty' panicked: runtime error: invalid memory address or nil pointer dereference")
+			assert.NotEmpty(t, panicErr.Stack)
+			assert.Equal(t, model.StopFurtherProcessing, be.(model.PipelineMedium).GetPreviousActionMandate())
+		}
+
+		assert.ElementsMatch(t, []string{"1", "2"}, next.visited)
+		assert.Equal(t, ActionOutcome{Action: "faulty", Index: 0, Processed: 4, Failed: 2}, outcomes[0])
+	}
+}
@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+// ParkingLot lets someone list, inspect, fix and replay the business events that were parked by the pipeline
+type ParkingLot struct {
+	pipeline *Pipeline
+	store    actions.IParkStore
+}
+
+// NewParkingLot constructs a new ParkingLot, replaying the parked business events through the pipeline
+func NewParkingLot(p *Pipeline, store actions.IParkStore) *ParkingLot {
+	return &ParkingLot{pipeline: p, store: store}
+}
+
+// List returns all the parked business events
+func (l *ParkingLot) List(ctx context.Context) ([]*model.ParkedEvent, error) {
+	parked, err := l.store.Parked(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list parked business events fail: %w", err)
+	}
+
+	return parked, nil
+}
+
+// Inspect returns the parked business event
+func (l *ParkingLot) Inspect(ctx context.Context, id string) (*model.ParkedEvent, error) {
+	parked, err := l.store.GetParked(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get parked business event '%s' fail: %w", id, err)
+	}
+
+	return parked, nil
+}
+
+// Edit replaces the serialised business event of the parked one, e.g. to fix the data that made it fail
+func (l *ParkingLot) Edit(ctx context.Context, id string, event json.RawMessage) error {
+	if !json.Valid(event) {
+		return errors.New("the fixed business event is not even valid JSON")
+	}
+
+	parked, err := l.Inspect(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	parked.Event = event
+
+	if err = l.store.Park(ctx, parked); err != nil {
+		return fmt.Errorf("park edited business event '%s' fail: %w", id, err)
+	}
+
+	return nil
+}
+
+// Replay runs the parked business events through the pipeline again, starting with the action they failed at.
+// The ones that make it through are unparked, the rest stay parked, or get parked again by the pipeline
+func (l *ParkingLot) Replay(ctx context.Context, ids ...string) (EventProcessingResult, error) {
+	if len(ids) == 0 {
+		return EventProcessingResult{}, errors.New("nice try replaying nothing")
+	}
+
+	inputs := make([]model.Input, len(ids))
+
+	for i, id := range ids {
+		parked, err := l.Inspect(ctx, id)
+		if err != nil {
+			return EventProcessingResult{}, err
+		}
+
+		inputs[i] = &model.Message{ID: parked.ID, Body: parked.Event}
+	}
+
+	model.LoggerFrom(ctx).Info("Back on the road! Replaying the parked business events.", "size", len(ids))
+
+	result, resultErr := l.pipeline.InvokePipeline(ctx, inputs...)
+
+	var replayed []string
+	for _, be := range result.BusinessEvents {
+		if be.GetError() == nil {
+			replayed = append(replayed, be.GetID())
+		}
+	}
+
+	if len(replayed) > 0 {
+		if err := l.store.Unpark(ctx, replayed...); err != nil {
+			return result, fmt.Errorf("unpark replayed business events fail: %w", err)
+		}
+	}
+
+	return result, resultErr
+}
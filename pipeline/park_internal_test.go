@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/memory"
+	"github.com/zale144/ube/model"
+)
+
+func parkInput(id string) model.Input {
+	return &model.Message{
+		ID:   "msg_" + id,
+		Body: []byte(`{"id":"` + id + `","event":{"event_name":"created","event_category":"product"},"product":[{"SomeField":"` + id + `"}]}`),
+	}
+}
+
+func TestParkingLot_Replay(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	first := newRecordingAction("first")
+	second := newRecordingAction("second", actions.FailureMandate(model.StopAndPark))
+	second.fail = map[string]error{"1": errors.New("bad data")}
+	third := newRecordingAction("third")
+
+	store := memory.NewParkStore()
+
+	p := NewPipeline(&product{},
+		Action(first),
+		Action(second),
+		Action(third),
+		Parker(store),
+	)
+
+	_, err := p.InvokePipeline(ctx, parkInput("1"), parkInput("2"))
+	require.Error(t, err)
+
+	assert.Equal(t, []string{"2"}, third.visited)
+
+	lot := NewParkingLot(p, store)
+
+	parked, err := lot.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, parked, 1)
+	assert.Equal(t, "1", parked[0].ID)
+	assert.Equal(t, 1, parked[0].PreviousAction)
+	assert.Equal(t, "bad data", parked[0].Error)
+
+	_, err = lot.Inspect(ctx, "2")
+	assert.ErrorIs(t, err, model.ErrNotParked)
+
+	assert.Error(t, lot.Edit(ctx, "1", json.RawMessage(`{"id":`)))
+
+	// someone fixed the data
+	second.fail = nil
+
+	result, err := lot.Replay(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, result.Status)
+
+	// the replay resumes at the action that failed
+	assert.Equal(t, []string{"1", "2"}, first.visited)
+	assert.Equal(t, []string{"1", "2", "1"}, second.visited)
+	assert.Equal(t, []string{"2", "1"}, third.visited)
+
+	parked, err = lot.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, parked)
+}
+
+func TestParkingLot_Replay_parks_again(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx := context.Background()
+
+	failing := newRecordingAction("failing", actions.FailureMandate(model.StopAndPark))
+	failing.fail = map[string]error{"1": errors.New("bad data")}
+
+	store := memory.NewParkStore()
+
+	p := NewPipeline(&product{}, Action(failing), Parker(store))
+
+	_, err := p.InvokePipeline(ctx, parkInput("1"))
+	require.Error(t, err)
+
+	lot := NewParkingLot(p, store)
+
+	_, err = lot.Replay(ctx, "1")
+	require.Error(t, err)
+
+	parked, err := lot.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, parked, 1)
+}
@@ -4,10 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
-	"go.uber.org/zap"
 
 	"github.com/zale144/ube/model"
 )
@@ -17,15 +16,71 @@ type Pipeline struct {
 	entity    model.Entity
 	actions   []action
 	afterEach []action
+	// layers holds the positions of the actions that can run at the same time,
+	// in the order the layers must run
+	layers [][]int
+	// offset is the index of the first action, so that the actions of routed
+	// sub-pipelines get indexes that don't clash with the parent's ones
+	offset int
+	// settings are shared by the pipeline and its routed sub-pipelines
+	*settings
+}
+
+// settings holds the pipeline wide configuration
+type settings struct {
+	// deadlineMargin is how much time before the context deadline the pipeline stops starting new batches
+	deadlineMargin time.Duration
+	// middleware wraps the Process of every action
+	middleware []Middleware
+	// journal makes the pipeline keep a journal on each business event
+	journal bool
+	// slots limits how many async batches the pipeline processes at the same time, nil meaning no limit
+	slots chan struct{}
+	// orderKey makes the async actions process the business events with the same key in order, nil meaning they don't
+	orderKey model.OrderKeyFn
+	// ids are the stable IDs of the actions, routes included, that the republished business events resume at
+	ids actionIDs
+	// resumeFallback is what happens to the republished business events whose action is no longer in the pipeline
+	resumeFallback ResumeFallback
+	// metrics is where the pipeline reports its statistics to, tagged with the metricTags, nil meaning nowhere
+	metrics    model.Metrics
+	metricTags map[string]string
+	// logger is what the pipeline logs through, nil meaning the logger of the context
+	logger model.Logger
+	// tracer traces the invocations, the actions and their batches, nil meaning no tracing
+	tracer model.Tracer
+	// idempotency makes the pipeline skip the messages it processed already, nil meaning no such check
+	idempotency *idempotency
 }
 
 // EventProcessingResult - comment placeholder
 type EventProcessingResult struct {
 	Status         string                 `json:"status"`
 	Errors         []string               `json:"errors,omitempty"`
+	DeadLettered   []string               `json:"dead_lettered,omitempty"` // the IDs of the business events out of retry attempts
+	Duplicates     []string               `json:"duplicates,omitempty"`    // the IDs of the messages processed already
+	InFlight       []string               `json:"in_flight,omitempty"`     // the IDs of the messages another invocation is processing
+	Actions        []ActionOutcome        `json:"actions,omitempty"`
 	BusinessEvents []model.PipelineMedium `json:"-"`
 }
 
+// ActionOutcome holds how a single pipeline action fared with the business events it processed
+type ActionOutcome struct {
+	Action    string `json:"action"`
+	Index     int    `json:"index"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+}
+
+func newOutcome(action action, actionIdx int) ActionOutcome {
+	return ActionOutcome{Action: action.Name(), Index: actionIdx}
+}
+
+func (o *ActionOutcome) add(processed, failed int) {
+	o.Processed += processed
+	o.Failed += failed
+}
+
 const (
 	StatusSucceeded       = "Succeeded"
 	StatusFailed          = "Failed"
@@ -35,26 +90,95 @@ const (
 // Option is a func type abstraction of a pipeline action
 type Option func(*Pipeline)
 
-// NewPipeline uses functional options to construct a Pipeline: https://dave.cheney.net/2014/10/17/functional-options-for-friendly-apis
+// NewPipeline uses functional options to construct a Pipeline: https://dave.cheney.net/2014/10/17/functional-options-for-friendly-apis.
+// It panics if the pipeline can't be built, such as with no entity, no actions or unresolvable action dependencies
 func NewPipeline(entity model.Entity, options ...Option) *Pipeline {
+	p, err := newPipeline(entity, options...)
+	if err != nil {
+		l := model.L()
+		if p != nil && p.logger != nil {
+			l = p.logger
+		}
+		l.Error("This pipeline won't hold water.", "error", err)
+		panic(err)
+	}
+
+	return p
+}
+
+// newPipeline constructs a Pipeline, returning the error that makes it unfit to run, along with
+// whatever was built of it, for its logger
+func newPipeline(entity model.Entity, options ...Option) (*Pipeline, error) {
 	if entity == nil {
-		zap.L().Fatal("entity is not provided")
+		return nil, errors.New("entity is not provided")
 	}
 
 	p := &Pipeline{
-		entity:  entity,
-		actions: []action{},
+		entity:   entity,
+		actions:  []action{},
+		settings: &settings{},
 	}
 
 	for _, option := range options {
 		option(p)
 	}
 
+	if err := p.init(); err != nil {
+		return p, fmt.Errorf("build pipeline fail: %w", err)
+	}
+
+	l := p.logger
+	if l == nil {
+		l = model.L()
+	}
+
+	p.link(p.settings, 0, l)
+
+	return p, nil
+}
+
+// init checks and prepares a pipeline, or a routed sub-pipeline, for execution
+func (p *Pipeline) init() error {
 	if len(p.actions) == 0 {
-		zap.L().Fatal("no actions were provided for the pipeline")
+		return errors.New("no actions were provided for the pipeline")
 	}
 
-	return p
+	for _, act := range p.actions {
+		if c, ok := act.(composite); ok {
+			if err := c.check(); err != nil {
+				return err
+			}
+		}
+	}
+
+	layers, err := plan(p.actions)
+	if err != nil {
+		return fmt.Errorf("invalid pipeline action dependencies: %w", err)
+	}
+
+	p.layers = layers
+
+	return nil
+}
+
+// link shares the settings with the routed sub-pipelines and assigns the action index offsets,
+// returning the next free action index. What's wrong with the actions is logged through the logger
+func (p *Pipeline) link(s *settings, offset int, l model.Logger) int {
+	p.settings = s
+	p.offset = offset
+	next := offset + len(p.actions)
+
+	for pos, act := range p.actions {
+		s.ids.register(act, offset+pos, l)
+	}
+
+	for _, act := range p.actions {
+		if c, ok := act.(composite); ok {
+			next = c.link(s, next, l)
+		}
+	}
+
+	return next
 }
 
 // each action batch?
@@ -67,40 +191,132 @@ func (p *Pipeline) InvokePipeline(ctx context.Context, inputs ...model.Input) (E
 		return EventProcessingResult{}, errors.New("nice try passing empty inputs")
 	}
 
-	bes, err := model.InputsToBusinessEvents(inputs, p.entity)
-	if err != nil {
-		return EventProcessingResult{}, fmt.Errorf("perhaps you want to take another look at your inputs: %w", err)
+	if ctx == nil {
+		// the batches keep their metadata in the context, so there has to be one
+		ctx = context.Background()
 	}
 
+	ctx = p.withMetrics(ctx)
+	ctx = p.withLogger(ctx)
+
+	return p.invoke(ctx, "InvokePipeline", inputs)
+}
+
+// invoke runs the inputs through the pipeline with everything that comes with an invocation: the inputs processed
+// already skip the pipeline, the business events that came too early are deferred, the republished ones resume
+// where they left off, and the invocation is traced and counted. Both the batches and the stream windows go through it
+func (p *Pipeline) invoke(ctx context.Context, name string, inputs []model.Input) (EventProcessingResult, error) {
 	var (
-		result     EventProcessingResult
-		finalError error
+		skipped EventProcessingResult
+		keys    []string
 	)
 
-	// execute the pipeline actions sequentially
-	for idx, act := range p.actions {
-		zap.L().Info("Starting pipeline action for all business events. If everything goes well, there will be cake.",
-			zap.String("action", act.Name()), zap.Int("action batchsize", act.BatchSize()))
+	if p.idempotency != nil {
+		if inputs, keys = p.idempotency.claim(ctx, inputs, &skipped); len(inputs) == 0 {
+			skipped.Status = StatusSucceeded
+			countEvents(ctx, len(skipped.Duplicates)+len(skipped.InFlight), skipped)
+			return skipped, nil
+		}
+	}
 
-		if act.IsAsync() {
-			processAsync(ctx, bes, act, idx)
-		} else {
-			processSync(ctx, bes, act, idx)
+	bes, err := model.InputsToBusinessEvents(inputs, p.entity)
+	if err != nil {
+		if p.idempotency != nil {
+			p.idempotency.release(ctx, keys)
 		}
+		countEvents(ctx, len(inputs)+len(skipped.Duplicates)+len(skipped.InFlight), skipped)
+		return skipped, fmt.Errorf("perhaps you want to take another look at your inputs: %w", err)
+	}
 
-		zap.L().Info("Finished pipeline action for all business events", zap.String("action", act.Name()))
+	deferEarly(ctx, bes)
+	p.resume(ctx, bes)
+
+	ctx, span := p.startInvocation(ctx, name, bes)
+
+	outcomes := p.run(ctx, bes)
+
+	endInvocation(span, bes)
+
+	model.LoggerFrom(ctx).Info("At last! Finished processing all business events. Let's see how many of them made it to the end.")
+
+	if p.idempotency != nil {
+		p.idempotency.settle(ctx, keys, bes)
+	}
+
+	result, err := newResult(ctx, bes)
+	result.Actions = outcomes
+	result.Duplicates = skipped.Duplicates
+	result.InFlight = skipped.InFlight
+
+	countEvents(ctx, len(bes)+len(skipped.Duplicates)+len(skipped.InFlight), result)
+
+	return result, err
+}
+
+// run executes the pipeline actions layer by layer against the provided business events.
+// When no dependencies are declared, each layer holds a single action, so the actions run sequentially
+func (p *Pipeline) run(ctx context.Context, bes []model.Medium) []ActionOutcome {
+	var outcomes []ActionOutcome
+
+	if p.journal {
+		ctx = withJournal(ctx)
+	}
+
+	for _, lyr := range p.layers {
+		if len(lyr) > 1 {
+			outcomes = append(outcomes, p.runConcurrently(ctx, bes, lyr)...)
+		} else {
+			outcomes = append(outcomes, p.runAction(ctx, bes, lyr[0])...)
+		}
 
 		for _, postAct := range p.afterEach {
-			postAct.Process(ctx, bes...)
+			invoke(ctx, postAct, bes)
 		}
 	}
 
-	zap.L().Info("At last! Finished processing all business events. Let's see how many of them made it to the end.")
+	return outcomes
+}
+
+// runAction executes a single pipeline action against the provided business events
+func (p *Pipeline) runAction(ctx context.Context, bes []model.Medium, pos int) []ActionOutcome {
+	act, idx := p.actions[pos], p.offset+pos
+	ctx = p.withActionLogger(ctx, idx)
+
+	model.LoggerFrom(ctx).Info("Starting pipeline action for all business events. If everything goes well, there will be cake.",
+		"action", act.Name(), "action batchsize", act.BatchSize())
+
+	ctx, span := p.startAction(ctx, act, idx)
+	ctx = withActionTimeout(ctx, act)
+
+	var outcomes []ActionOutcome
+
+	if c, ok := act.(composite); ok {
+		// nested actions take care of the failure handling on their own
+		outcomes = c.process(ctx, bes)
+	} else if act.IsAsync() {
+		outcomes = []ActionOutcome{processAsync(ctx, bes, act, idx, p.batch(p.processBatchAction), p.slots, p.orderKeyOf(act))}
+	} else {
+		outcomes = []ActionOutcome{processSync(ctx, bes, act, idx, p.batch(p.processBatchAction))}
+	}
+
+	endAction(span, outcomes)
+
+	model.LoggerFrom(ctx).Info("Finished pipeline action for all business events", "action", act.Name())
+
+	return outcomes
+}
+
+// newResult summarises the state of the processed business events
+func newResult(ctx context.Context, bes []model.Medium) (EventProcessingResult, error) {
+	var (
+		result     EventProcessingResult
+		finalError error
+	)
 
 	for _, beI := range bes {
 		be, ok := beI.(model.PipelineMedium)
 		if !ok {
-			zap.L().Error("business event is not of type model.PipelineMedium")
+			model.LoggerFrom(ctx).Error("business event is not of type model.PipelineMedium")
 			continue
 		}
 		result.BusinessEvents = append(result.BusinessEvents, be)
@@ -108,6 +324,9 @@ func (p *Pipeline) InvokePipeline(ctx context.Context, inputs ...model.Input) (E
 
 	for _, be := range bes {
 		if be.GetError() != nil {
+			if errors.Is(be.GetError(), model.ErrDeadLettered) {
+				result.DeadLettered = append(result.DeadLettered, be.GetID())
+			}
 			result.Errors = append(result.Errors, be.GetError().Error())
 			finalError = multierror.Append(finalError, fmt.Errorf("another one bites the dust. ID: '%s'; err: %w", be.GetID(), be.GetError()))
 		}
@@ -115,104 +334,95 @@ func (p *Pipeline) InvokePipeline(ctx context.Context, inputs ...model.Input) (E
 
 	errs, events := len(result.Errors), len(bes)
 	if errs == 0 {
-		zap.L().Info("Congratulations, you made it! All events have been processed flawlessly. Here's your cake: 🍰")
+		model.LoggerFrom(ctx).Info("Congratulations, you made it! All events have been processed flawlessly. Here's your cake: 🍰")
 		result.Status = StatusSucceeded
 		return result, finalError
 	}
 
 	if errs > 0 && errs < events {
-		zap.L().Info("Not great, not terrible. Have fun debugging.", zap.Int("events", events), zap.Int("errors", errs))
+		model.LoggerFrom(ctx).Info("Not great, not terrible. Have fun debugging.", "events", events, "errors", errs)
 		result.Status = StatusPartiallyFailed // 1 or more pipeline actions failed
 	}
 
 	if errs == events {
-		zap.L().Info("We really admire your client's patience. This time everything failed.")
+		model.LoggerFrom(ctx).Info("We really admire your client's patience. This time everything failed.")
 		result.Status = StatusFailed // all pipeline actions failed
 	}
 
 	return result, finalError
 }
 
-func processSync(ctx context.Context, bes []model.Medium, action action, actionIdx int) {
-	lb := len(bes)
+// batchFn processes a single batch of business events with the action
+type batchFn func(ctx context.Context, bes []model.Medium, action action, actionIdx int) (processed, failed int)
 
-	batchSize := action.BatchSize()
-	if batchSize == 0 {
-		batchSize = 1
-	}
-
-	for i := 0; i < lb; i += batchSize {
-		j := i + batchSize
-		if j > lb || lb == 1 {
-			j = lb
-		}
-
-		processBatchAction(ctx, bes[i:j], action, actionIdx)
-	}
+// batch decorates fn with the per batch behaviour configured for the pipeline and the action.
+// The deadline is checked against the invocation context, before the action timeout narrows it down
+func (p *Pipeline) batch(fn batchFn) batchFn {
+	return p.measure(p.trace(deadlineAware(p.deadlineMargin, withTimeout(intercept(p.middleware, fn)))))
 }
 
-func processAsync(ctx context.Context, bes []model.Medium, action action, actionIdx int) {
-	wg := sync.WaitGroup{}
-	lb := len(bes)
+func processSync(ctx context.Context, bes []model.Medium, action action, actionIdx int, fn batchFn) ActionOutcome {
+	outcome := newOutcome(action, actionIdx)
 
-	batchSize := action.BatchSize()
-	if batchSize == 0 {
-		batchSize = 1
+	for n, beb := range split(bes, action.BatchSize()) {
+		outcome.add(fn(withBatch(ctx, n), beb, action, actionIdx))
 	}
 
-	for i := 0; i < lb; i += batchSize {
-		i := i
-		wg.Add(1)
-
-		j := i + batchSize
-		if j > lb || lb == 1 {
-			j = lb
-		}
-
-		go func(beb []model.Medium, idx int) {
-			defer wg.Done()
-			processBatchAction(ctx, beb, action, idx)
-		}(bes[i:j], actionIdx)
-	}
-
-	wg.Wait()
+	return outcome
 }
 
-func processBatchAction(ctx context.Context, bes []model.Medium, action action, actionIdx int) {
+func (p *Pipeline) processBatchAction(ctx context.Context, bes []model.Medium, action action, actionIdx int) (processed, failed int) {
 	if action == nil {
-		zap.L().Error("action is nil")
-		return
+		model.LoggerFrom(ctx).Error("action is nil")
+		return 0, 0
 	}
 
 	var toProcess []model.Medium
 	// add processable events
 	for _, be := range bes { // some might be retries
-		if isEventProcessable(be, action, actionIdx) {
+		if isEventProcessable(ctx, be, action, actionIdx) {
 			toProcess = append(toProcess, be)
+		} else {
+			journalSkip(ctx, be, action, actionIdx)
 		}
 	}
 	// if nothing to process - return
 	if len(toProcess) == 0 {
-		zap.L().Info("No business events to process in current batch.", zap.String("action", action.Name()))
-		return
+		model.LoggerFrom(ctx).Info("No business events to process in current batch.", "action", action.Name())
+		return 0, 0
 	}
 	// process events
-	action.Process(ctx, toProcess...)
+	started := time.Now()
+	invokeWithRetry(ctx, action, toProcess)
+	ended := time.Now()
 	// handle errors
 	for _, be := range toProcess {
-		handleActionError(be.(model.PipelineMedium), action, actionIdx)
+		if be.GetError() != nil {
+			failed++
+		}
+		journal(ctx, be, action, actionIdx, started, ended)
+		handleActionError(ctx, be.(model.PipelineMedium), action, actionIdx, p.ids.byIndex[actionIdx])
 	}
+
+	return len(toProcess), failed
 }
 
-func handleActionError(be model.PipelineMedium, action action, actionIdx int) {
+func handleActionError(ctx context.Context, be model.PipelineMedium, action action, actionIdx int, actionID string) {
 	be.SetPreviousActionMandate(action.FailureMandate())
-	be.SetPreviousAction(actionIdx) // what if redeploying ?
+	be.SetPreviousAction(actionIdx)
+	be.SetPreviousActionName(action.Name())
+	// unlike the index, the ID survives the changes to the pipeline between redeployments
+	be.SetPreviousActionID(actionID)
 
 	if be.GetError() == nil {
 		be.SetEventProcessedTime(model.Now())
 		// clean up from previous re-publish
 		if be.GetRepublishAttempt() != nil {
 			be.SetRepublishAttempt(nil)
+			be.SetNotBefore(time.Time{})
+		}
+		if r, ok := be.(resumable); ok {
+			r.SetPendingActionIDs(nil)
 		}
 		return
 	}
@@ -230,16 +440,21 @@ func handleActionError(be model.PipelineMedium, action action, actionIdx int) {
 	case model.StopAndRetry:
 		if be.GetRepublishAttempt() == nil {
 			attempt := 1
+			if errors.Is(be.GetError(), model.ErrDeadlineExceeded) {
+				attempt = 0 // running out of time is not a failed attempt
+			}
 			be.SetRepublishAttempt(&attempt)
 		}
 
 		errText = "Tell you what. We will give you another shot at this."
+	case model.StopAndPark:
+		errText = "This one needs a human touch. We'll park it right here until someone fixes it up."
 	}
 
-	zap.L().Error(errText, zap.String("action", action.Name()), zap.Error(be.GetError()))
+	model.EventLogger(ctx, be).Error(errText, "action", action.Name(), "error", be.GetError())
 }
 
-func isEventProcessable(beI model.Medium, action action, actionIdx int) bool {
+func isEventProcessable(ctx context.Context, beI model.Medium, action action, actionIdx int) bool {
 	be := beI.(model.PipelineMedium)
 	if be.GetError() == nil {
 		// if is re-publish - check if we are at the previously failed action
@@ -252,13 +467,10 @@ func isEventProcessable(beI model.Medium, action action, actionIdx int) bool {
 	}
 
 	if be.GetPreviousActionMandate() == model.LogFailureAndContinue {
-		zap.L().Error("failed to execute action", zap.Error(be.GetError()))
+		model.EventLogger(ctx, be).Error("failed to execute action", "error", be.GetError())
 		// start clean slate for next action
 		be.SetError(nil)
 		return true
-	} else if be.GetPreviousActionMandate() == model.StopAndRaiseError && action.Name() == "EventAlerter" {
-		// TODO: alert!
-		zap.L().Error("ALERT: failed to execute action", zap.Error(be.GetError()))
 	} else if be.GetPreviousActionMandate() == model.ProcessOnlyCriticalActions && action.IsCritical() {
 		return true
 	}
@@ -2,6 +2,8 @@ package pipeline
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,7 +50,7 @@ func TestPipeline_FailedTransformer(t *testing.T) {
 
 	bes = append(bes, be)
 
-	processBatchAction(ctx, bes, actions.InputTransformer(), 0)
+	(&Pipeline{settings: &settings{}}).processBatchAction(ctx, bes, actions.InputTransformer(), 0)
 	require.Error(t, be.Error)
 	assert.Equal(t, `failed to transform business events: unmarshal input body fail: unexpected end of JSON input`, be.Error.Error())
 }
@@ -64,7 +66,61 @@ func TestPipeline_nil_action(t *testing.T) {
 	be := &model.BusinessEvent{}
 	bes = append(bes, be)
 
-	processBatchAction(ctx, bes, nil, 0)
+	(&Pipeline{settings: &settings{}}).processBatchAction(ctx, bes, nil, 0)
 	assert.NoError(t, be.Error)
 	println()
 }
+
+// collectingAlerter keeps the alerts it was asked to raise
+type collectingAlerter struct {
+	alerts []*model.Alert
+}
+
+func (a *collectingAlerter) Alert(_ context.Context, alerts ...*model.Alert) error {
+	a.alerts = append(a.alerts, alerts...)
+	return nil
+}
+
+func TestPipeline_Alerter(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := newRecordingAction("first", actions.FailureMandate(model.StopAndRaiseError))
+	first.fail = map[string]error{"1": errors.New("boom"), "2": errors.New("boom")}
+	second := newRecordingAction("second")
+	alerter := &collectingAlerter{}
+
+	p := NewPipeline(&product{}, Action(first), Action(second), Alerter(alerter))
+
+	_, err := p.InvokePipeline(context.Background(), parkInput("1"), parkInput("2"), parkInput("3"))
+	require.Error(t, err)
+
+	// alerted about once, even though the Alerter runs after each action
+	require.Len(t, alerter.alerts, 1)
+	assert.Equal(t, "first", alerter.alerts[0].Action)
+	assert.Equal(t, "boom", alerter.alerts[0].Error)
+	assert.Len(t, alerter.alerts[0].Events, 2)
+
+	assert.Equal(t, []string{"3"}, second.visited)
+}
+
+func TestNewResult_DeadLettered(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	dead := newEvent("1", "created")
+	dead.Error = fmt.Errorf("%w: %w", model.ErrDeadLettered, errors.New("throttled"))
+	failed := newEvent("2", "created")
+	failed.Error = errors.New("throttled")
+
+	result, err := newResult(context.Background(), []model.Medium{dead, failed, newEvent("3", "created")})
+	require.Error(t, err)
+
+	assert.Equal(t, StatusPartiallyFailed, result.Status)
+	assert.Equal(t, []string{"1"}, result.DeadLettered)
+	assert.Len(t, result.Errors, 2)
+}
@@ -0,0 +1,301 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zale144/ube/model"
+)
+
+// concurrent is an action that limits how many of its batches may be processed at the same time
+type concurrent interface {
+	Concurrency() int
+}
+
+// Concurrency limits how many batches of async actions the pipeline, including its routes, processes
+// at the same time. It's also the number of workers of the async actions that don't set their own limit
+func Concurrency(limit int) Option {
+	return func(p *Pipeline) {
+		if limit > 0 {
+			p.slots = make(chan struct{}, limit)
+		}
+	}
+}
+
+// ordered is an action that processes the business events with the same key in the order they came in
+type ordered interface {
+	OrderKey() model.OrderKeyFn
+}
+
+// Ordered makes the async actions of the pipeline, including its routes, process the business events with the same key
+// one after another, in the order they came in, e.g. by model.GroupKey. The actions can have keys of their own, see actions.Ordered
+func Ordered(orderKey model.OrderKeyFn) Option {
+	return func(p *Pipeline) {
+		p.orderKey = orderKey
+	}
+}
+
+// orderKeyOf returns the order key of the action, or else the one of the pipeline, nil meaning the action isn't ordered
+func (s *settings) orderKeyOf(act action) model.OrderKeyFn {
+	if o, ok := act.(ordered); ok && o.OrderKey() != nil {
+		return o.OrderKey()
+	}
+
+	return s.orderKey
+}
+
+type batchKey struct{}
+
+// withBatch stores the number of the batch, in the order the batches were split, in the context
+func withBatch(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, batchKey{}, n)
+}
+
+// batchOf returns the number of the batch being processed
+func batchOf(ctx context.Context) int {
+	n, _ := ctx.Value(batchKey{}).(int)
+	return n
+}
+
+// split cuts the business events into batches of the given size, a size of zero meaning one event per batch
+func split(bes []model.Medium, batchSize int) [][]model.Medium {
+	lb := len(bes)
+
+	if batchSize == 0 {
+		batchSize = 1
+	}
+
+	var batches [][]model.Medium
+
+	for i := 0; i < lb; i += batchSize {
+		j := i + batchSize
+		if j > lb || lb == 1 {
+			j = lb
+		}
+
+		batches = append(batches, bes[i:j])
+	}
+
+	return batches
+}
+
+// processAsync processes the batches with a pool of workers. The batches are picked up in the order they were
+// split in, and the ones that weren't picked up before the context is done are failed with the context error.
+// The workers are limited by the action concurrency, or else by the pipeline one, and all the workers of the
+// pipeline share the slots, if any. Given the order key, the business events with the same key are processed
+// by the same worker, in the order they came in, see lanes
+func processAsync(ctx context.Context, bes []model.Medium, action action, actionIdx int, fn batchFn, slots chan struct{},
+	orderKey model.OrderKeyFn) ActionOutcome {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		next    int
+		outcome = newOutcome(action, actionIdx)
+	)
+
+	workers := len(split(bes, action.BatchSize()))
+	if c, ok := action.(concurrent); ok && c.Concurrency() > 0 && c.Concurrency() < workers {
+		workers = c.Concurrency()
+	} else if slots != nil && cap(slots) < workers {
+		workers = cap(slots)
+	}
+
+	var lns []lane
+	if orderKey != nil {
+		lns = lanes(bes, orderKey, workers, action.BatchSize())
+	} else {
+		// every batch is a lane of its own, so the batches are picked up one by one
+		for _, b := range split(bes, action.BatchSize()) {
+			lns = append(lns, lane{batches: [][]model.Medium{b}})
+		}
+	}
+
+	if workers > len(lns) {
+		workers = len(lns)
+	}
+
+	// number the batches across the lanes, in the order they're in
+	first := make([]int, len(lns))
+	for i := 1; i < len(lns); i++ {
+		first[i] = first[i-1] + len(lns[i-1].batches)
+	}
+
+	// take hands out the next lane to a worker, false meaning there are none left
+	take := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if next == len(lns) {
+			return 0, false
+		}
+		next++
+
+		return next - 1, true
+	}
+
+	work := func() {
+		defer wg.Done()
+
+		for {
+			l, ok := take()
+			if !ok {
+				return
+			}
+
+			for n, b := range lns[l].batches {
+				processed, failed := lns[l].process(withBatch(ctx, first[l]+n), b, action, actionIdx, fn, slots)
+
+				mu.Lock()
+				outcome.add(processed, failed)
+				mu.Unlock()
+			}
+		}
+	}
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go work()
+	}
+
+	wg.Wait()
+
+	return outcome
+}
+
+// lane is a sequence of batches that one worker processes one after another. The lanes of an ordered action
+// keep the keys of the business events in them, so that a business event whose key failed before it is held back
+type lane struct {
+	batches [][]model.Medium
+	// keys are the order keys of the business events, nil if the lane isn't ordered
+	keys map[model.Medium]string
+	// failed are the keys that failed in the lane so far
+	failed map[string]struct{}
+}
+
+// lanes deals the business events into as many lanes as there are workers, at most, each key going to a single lane,
+// in the order the keys first came in. The business events keep their order within the lanes,
+// which are split into batches on their own
+func lanes(bes []model.Medium, orderKey model.OrderKeyFn, workers, batchSize int) []lane {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		lns    []lane
+		byKey  = make(map[string]int)
+		events = make([][]model.Medium, 0, workers)
+	)
+
+	keys := make(map[model.Medium]string, len(bes))
+
+	for _, be := range bes {
+		key := orderKey(be)
+		keys[be] = key
+
+		l, ok := byKey[key]
+		if !ok {
+			l = len(byKey) % workers
+			byKey[key] = l
+		}
+
+		if l == len(events) {
+			events = append(events, nil)
+		}
+
+		events[l] = append(events[l], be)
+	}
+
+	for _, evs := range events {
+		lns = append(lns, lane{batches: split(evs, batchSize), keys: keys, failed: make(map[string]struct{})})
+	}
+
+	return lns
+}
+
+// process processes the batch of the lane, holding back the business events whose key failed earlier in the lane,
+// unless the action carries on regardless of its failures
+func (l lane) process(ctx context.Context, bes []model.Medium, action action, actionIdx int, fn batchFn, slots chan struct{}) (int, int) {
+	if l.keys == nil {
+		return processPooled(ctx, bes, action, actionIdx, fn, slots)
+	}
+
+	var ready, held []model.Medium
+
+	for _, be := range bes {
+		if _, ok := l.failed[l.keys[be]]; ok {
+			held = append(held, be)
+		} else {
+			ready = append(ready, be)
+		}
+	}
+
+	var processed, failed int
+
+	if len(held) > 0 {
+		model.LoggerFrom(ctx).Warn("Some business events will have to wait for their elders, who didn't make it.",
+			"action", action.Name(), "batch", batchOf(ctx), "held back", len(held))
+
+		processed, failed = fn(ctx, held, abandoned{action: action, err: model.ErrHeldBack}, actionIdx)
+	}
+
+	if len(ready) == 0 {
+		return processed, failed
+	}
+
+	// only the business events that fail in this action count, not the ones that came with an error
+	clean := make([]bool, len(ready))
+	for i, be := range ready {
+		clean[i] = be.GetError() == nil
+	}
+
+	p, f := processPooled(ctx, ready, action, actionIdx, fn, slots)
+
+	if action.FailureMandate() != model.LogFailureAndContinue {
+		for i, be := range ready {
+			if clean[i] && be.GetError() != nil {
+				l.failed[l.keys[be]] = struct{}{}
+			}
+		}
+	}
+
+	return processed + p, failed + f
+}
+
+// processPooled processes a batch once there's a free slot, or abandons it when the context is done first
+func processPooled(ctx context.Context, bes []model.Medium, action action, actionIdx int, fn batchFn, slots chan struct{}) (int, int) {
+	if ctx.Err() != nil {
+		return abandon(ctx, bes, action, actionIdx, fn)
+	}
+
+	if slots == nil {
+		return fn(ctx, bes, action, actionIdx)
+	}
+
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+		return fn(ctx, bes, action, actionIdx)
+	case <-ctx.Done():
+		return abandon(ctx, bes, action, actionIdx, fn)
+	}
+}
+
+func abandon(ctx context.Context, bes []model.Medium, action action, actionIdx int, fn batchFn) (int, int) {
+	model.LoggerFrom(ctx).Warn("Nobody is waiting for this batch anymore, so we're not even going to start it.",
+		"action", action.Name(), "batch", batchOf(ctx), "error", ctx.Err())
+
+	return fn(ctx, bes, abandoned{action: action, err: ctx.Err()}, actionIdx)
+}
+
+// abandoned stands in for an action whose batch was given up on because the context was done.
+// It fails the business events it receives with the context error, following the mandate of the action
+type abandoned struct {
+	action
+	err error
+}
+
+func (a abandoned) Process(_ context.Context, bes ...model.Medium) {
+	for _, be := range bes {
+		be.SetError(a.err)
+	}
+}
@@ -0,0 +1,223 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+// crowdedAction keeps track of how many of its batches are processed at the same time
+type crowdedAction struct {
+	*recordingAction
+	inFlight, peak int32
+}
+
+func (a *crowdedAction) Process(ctx context.Context, bes ...model.Medium) {
+	n := atomic.AddInt32(&a.inFlight, 1)
+	defer atomic.AddInt32(&a.inFlight, -1)
+
+	for {
+		peak := atomic.LoadInt32(&a.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&a.peak, peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	a.recordingAction.Process(ctx, bes...)
+}
+
+func events(n int) []model.Medium {
+	bes := make([]model.Medium, n)
+	for i := range bes {
+		bes[i] = newEvent(string(rune('a'+i)), "CreateProduct")
+	}
+
+	return bes
+}
+
+func TestPipeline_action_Concurrency(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	act := &crowdedAction{recordingAction: newRecordingAction("publish",
+		actions.Async(), actions.BatchSize(1), actions.Concurrency(2))}
+
+	p := NewPipeline(&product{}, Action(act))
+	outcomes := p.run(context.Background(), events(10))
+
+	assert.Equal(t, int32(2), act.peak)
+	assert.Len(t, act.visited, 10)
+	assert.Equal(t, []ActionOutcome{{Action: "publish", Index: 0, Processed: 10}}, outcomes)
+}
+
+func TestPipeline_Concurrency(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := &crowdedAction{recordingAction: newRecordingAction("first", actions.Async(), actions.BatchSize(1))}
+	second := &crowdedAction{recordingAction: newRecordingAction("second",
+		actions.Async(), actions.BatchSize(1), actions.Concurrency(5))}
+
+	var (
+		mu      sync.Mutex
+		batches []int
+	)
+
+	record := func(next ActionFunc) ActionFunc {
+		return func(ctx context.Context, call ActionCall, bes ...model.Medium) {
+			if call.Action == "first" {
+				mu.Lock()
+				batches = append(batches, call.Batch)
+				mu.Unlock()
+			}
+			next(ctx, call, bes...)
+		}
+	}
+
+	p := NewPipeline(&product{}, Concurrency(3), Use(record), Action(first), Action(second))
+	p.run(context.Background(), events(8))
+
+	// the pipeline limit applies even to the actions with a higher limit of their own
+	assert.LessOrEqual(t, first.peak, int32(3))
+	assert.LessOrEqual(t, second.peak, int32(3))
+	assert.Len(t, first.visited, 8)
+	assert.Len(t, second.visited, 8)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, batches)
+}
+
+func TestPipeline_async_cancelled(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	act := newRecordingAction("publish", actions.Async(), actions.BatchSize(2), actions.Concurrency(1),
+		actions.FailureMandate(model.StopFurtherProcessing))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bes := events(4)
+	outcomes := NewPipeline(&product{}, Action(act)).run(ctx, bes)
+
+	assert.Empty(t, act.visited)
+	for _, be := range bes {
+		assert.ErrorIs(t, be.GetError(), context.Canceled)
+	}
+	assert.Equal(t, []ActionOutcome{{Action: "publish", Index: 0, Processed: 4, Failed: 4}}, outcomes)
+}
+
+// sluggishAction takes longer on the events that come first, for the later ones to overtake them if they can
+type sluggishAction struct {
+	*crowdedAction
+}
+
+func (a *sluggishAction) Process(ctx context.Context, bes ...model.Medium) {
+	time.Sleep(time.Duration('z'-bes[0].GetID()[0]) * time.Millisecond)
+	a.crowdedAction.Process(ctx, bes...)
+}
+
+func groupedEvents(groups ...string) []model.Medium {
+	bes := events(len(groups))
+	for i, g := range groups {
+		bes[i].(*model.BusinessEvent).GroupID = g
+	}
+
+	return bes
+}
+
+// visitedBy returns the IDs of the visited events, grouped by their group IDs
+func visitedBy(visited []string, bes []model.Medium) map[string][]string {
+	groups := make(map[string]string)
+	for _, be := range bes {
+		groups[be.GetID()] = be.(*model.BusinessEvent).GroupID
+	}
+
+	byGroup := make(map[string][]string)
+	for _, id := range visited {
+		byGroup[groups[id]] = append(byGroup[groups[id]], id)
+	}
+
+	return byGroup
+}
+
+func TestPipeline_action_Ordered(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	act := &sluggishAction{&crowdedAction{recordingAction: newRecordingAction("persist",
+		actions.Async(), actions.BatchSize(1), actions.Ordered())}}
+
+	bes := groupedEvents("k1", "k2", "k1", "k2", "k1", "k3")
+	outcomes := NewPipeline(&product{}, Action(act)).run(context.Background(), bes)
+
+	assert.Equal(t, map[string][]string{
+		"k1": {"a", "c", "e"},
+		"k2": {"b", "d"},
+		"k3": {"f"},
+	}, visitedBy(act.visited, bes))
+	// the different keys are still processed at the same time
+	assert.Greater(t, act.peak, int32(1))
+	assert.Equal(t, []ActionOutcome{{Action: "persist", Index: 0, Processed: 6}}, outcomes)
+}
+
+func TestPipeline_Ordered_held_back(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	act := newRecordingAction("persist", actions.Async(), actions.BatchSize(1), actions.Concurrency(2),
+		actions.FailureMandate(model.StopAndRetry))
+	act.fail = map[string]error{"a": errors.New("boom")}
+
+	bes := groupedEvents("k1", "k2", "k1", "k2", "k1")
+	outcomes := NewPipeline(&product{}, Ordered(model.GroupKey), Action(act)).run(context.Background(), bes)
+
+	// the events behind the failed one don't overtake it
+	assert.ElementsMatch(t, []string{"a", "b", "d"}, act.visited)
+	assert.ErrorIs(t, bes[2].GetError(), model.ErrHeldBack)
+	assert.ErrorIs(t, bes[4].GetError(), model.ErrHeldBack)
+	assert.NoError(t, bes[1].GetError())
+	assert.NoError(t, bes[3].GetError())
+	assert.Equal(t, []ActionOutcome{{Action: "persist", Index: 0, Processed: 5, Failed: 3}}, outcomes)
+}
+
+func TestLanes(t *testing.T) {
+	bes := groupedEvents("k1", "k2", "k3", "k1", "k4", "k2")
+
+	lns := lanes(bes, model.GroupKey, 2, 2)
+	require.Len(t, lns, 2)
+
+	ids := func(l lane) []string {
+		var ids []string
+		for _, b := range l.batches {
+			for _, be := range b {
+				ids = append(ids, be.GetID())
+			}
+		}
+		return ids
+	}
+
+	// the keys are dealt to the lanes in the order they first came in, and keep the order within them
+	assert.Equal(t, []string{"a", "c", "d"}, ids(lns[0]))
+	assert.Equal(t, []string{"b", "e", "f"}, ids(lns[1]))
+	assert.Len(t, lns[0].batches, 2)
+}
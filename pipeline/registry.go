@@ -0,0 +1,526 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/encryption"
+)
+
+type (
+	// Registry holds the actions, transform and enrich options, and dependencies that
+	// pipeline definitions refer to by name
+	Registry struct {
+		actions    map[string]ActionFactory
+		transforms map[string]TransformFactory
+		enrichers  map[string]EnrichFactory
+		deps       map[string]interface{}
+	}
+	// ActionFactory builds the pipeline option adding an action from its definition
+	ActionFactory func(args Args) (Option, error)
+	// TransformFactory builds an InputTransformer option from its definition
+	TransformFactory func(args Args) (actions.TransformOption, error)
+	// EnrichFactory builds an enrich function from its definition
+	EnrichFactory func(args Args) (actions.EnrichFn, error)
+
+	// Args are the definition of an action or an option, handed to its factory
+	Args struct {
+		// Params are the parameters from the definition
+		Params map[string]interface{}
+		// Base are the BaseOptions from the definition, to be passed to the action
+		Base []actions.BaseOption
+
+		deps     map[string]interface{}
+		options  []OptionDefinition
+		registry *Registry
+	}
+)
+
+// NewRegistry constructs a new registry with the standard actions and options registered
+func NewRegistry() *Registry {
+	r := &Registry{
+		actions:    make(map[string]ActionFactory),
+		transforms: make(map[string]TransformFactory),
+		enrichers:  make(map[string]EnrichFactory),
+		deps:       make(map[string]interface{}),
+	}
+
+	r.registerStandard()
+
+	return r
+}
+
+// RegisterAction registers an action under the name
+func (r *Registry) RegisterAction(name string, factory ActionFactory) *Registry {
+	r.actions[name] = factory
+	return r
+}
+
+// RegisterTransform registers an InputTransformer option under the name
+func (r *Registry) RegisterTransform(name string, factory TransformFactory) *Registry {
+	r.transforms[name] = factory
+	return r
+}
+
+// RegisterEnricher registers an enrich function under the name
+func (r *Registry) RegisterEnricher(name string, factory EnrichFactory) *Registry {
+	r.enrichers[name] = factory
+	return r
+}
+
+// Provide makes the dependency available to the definitions under the name
+func (r *Registry) Provide(name string, dep interface{}) *Registry {
+	r.deps[name] = dep
+	return r
+}
+
+// Dependency returns the dependency injected into the definition for the parameter name
+func Dependency[T any](args Args, name string) (T, error) {
+	var zero T
+
+	dep, ok := args.deps[name]
+	if !ok {
+		return zero, fmt.Errorf("dependency '%s' is missing", name)
+	}
+
+	typed, ok := dep.(T)
+	if !ok {
+		return zero, fmt.Errorf("dependency '%s' is of type %T, not %s", name, dep, reflect.TypeOf(&zero).Elem())
+	}
+
+	return typed, nil
+}
+
+// String returns the string parameter
+func (a Args) String(name string) (string, error) {
+	s, ok := a.Params[name].(string)
+	if !ok {
+		return "", fmt.Errorf("string parameter '%s' is missing", name)
+	}
+
+	return s, nil
+}
+
+// Int returns the integer parameter, or the default if it's not set
+func (a Args) Int(name string, def int) (int, error) {
+	v, ok := a.Params[name]
+	if !ok {
+		return def, nil
+	}
+
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	}
+
+	return 0, fmt.Errorf("parameter '%s' is not an integer", name)
+}
+
+// Float returns the decimal parameter, or the default if it's not set
+func (a Args) Float(name string, def float64) (float64, error) {
+	v, ok := a.Params[name]
+	if !ok {
+		return def, nil
+	}
+
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+
+	return 0, fmt.Errorf("parameter '%s' is not a number", name)
+}
+
+// Duration returns the duration parameter, e.g. "1m30s", or the default if it's not set
+func (a Args) Duration(name string, def time.Duration) (time.Duration, error) {
+	v, ok := a.Params[name]
+	if !ok {
+		return def, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("parameter '%s' is not a duration", name)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("parse parameter '%s' fail: %w", name, err)
+	}
+
+	return d, nil
+}
+
+// StringMap returns the parameter that maps strings to strings
+func (a Args) StringMap(name string) (map[string]string, error) {
+	v, ok := a.Params[name]
+	if !ok {
+		return nil, fmt.Errorf("map parameter '%s' is missing", name)
+	}
+
+	m := make(map[string]string)
+
+	switch raw := v.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range raw {
+			m[fmt.Sprint(k)] = fmt.Sprint(v)
+		}
+	case map[string]interface{}:
+		for k, v := range raw {
+			m[k] = fmt.Sprint(v)
+		}
+	default:
+		return nil, fmt.Errorf("parameter '%s' is not a map", name)
+	}
+
+	return m, nil
+}
+
+// Transforms builds the InputTransformer options of the definition
+func (a Args) Transforms() ([]actions.TransformOption, error) {
+	transforms := make([]actions.TransformOption, 0, len(a.options))
+
+	for _, def := range a.options {
+		factory, ok := a.registry.transforms[def.Name]
+		if !ok {
+			return nil, fmt.Errorf("transform '%s' is not registered", def.Name)
+		}
+
+		args, err := a.registry.args(def)
+		if err != nil {
+			return nil, fmt.Errorf("transform '%s': %w", def.Name, err)
+		}
+
+		transform, err := factory(args)
+		if err != nil {
+			return nil, fmt.Errorf("build transform '%s' fail: %w", def.Name, err)
+		}
+
+		transforms = append(transforms, transform)
+	}
+
+	return transforms, nil
+}
+
+// Enrichers builds the enrich options of the definition. The enrich functions of the same event are combined
+// into a single one with the EnrichEvent, and the ones without an event enrich all the events
+func (a Args) Enrichers() ([]actions.EnricherOption, error) {
+	var (
+		enrichers []actions.EnricherOption
+		mapping   = make(actions.EnricherMapping)
+		perEvent  = make(map[string][]actions.EnrichFn)
+		events    []string
+	)
+
+	for _, def := range a.options {
+		factory, ok := a.registry.enrichers[def.Name]
+		if !ok {
+			return nil, fmt.Errorf("enricher '%s' is not registered", def.Name)
+		}
+
+		args, err := a.registry.args(def)
+		if err != nil {
+			return nil, fmt.Errorf("enricher '%s': %w", def.Name, err)
+		}
+
+		enrich, err := factory(args)
+		if err != nil {
+			return nil, fmt.Errorf("build enricher '%s' fail: %w", def.Name, err)
+		}
+
+		if def.Event == "" {
+			enrichers = append(enrichers, enrich)
+			continue
+		}
+
+		if _, ok := perEvent[def.Event]; !ok {
+			events = append(events, def.Event)
+		}
+		perEvent[def.Event] = append(perEvent[def.Event], enrich)
+	}
+
+	for _, event := range events {
+		mapping[event] = actions.EnrichEvent(perEvent[event]...)
+	}
+
+	if len(mapping) > 0 {
+		enrichers = append(enrichers, mapping)
+	}
+
+	return enrichers, nil
+}
+
+func (r *Registry) args(def OptionDefinition) (Args, error) {
+	deps, err := r.resolve(def.Deps)
+	if err != nil {
+		return Args{}, err
+	}
+
+	return Args{Params: def.Params, deps: deps, registry: r}, nil
+}
+
+// registerStandard registers the actions and options that come with UBE
+func (r *Registry) registerStandard() {
+	r.RegisterAction("InputTransformer", func(args Args) (Option, error) {
+		transforms, err := args.Transforms()
+		if err != nil {
+			return nil, err
+		}
+
+		it := actions.InputTransformer(transforms...)
+		for _, opt := range args.Base {
+			opt(&it.Base)
+		}
+
+		return Action(it), nil
+	})
+
+	r.RegisterAction("Enricher", func(args Args) (Option, error) {
+		enrichers, err := args.Enrichers()
+		if err != nil {
+			return nil, err
+		}
+
+		e := actions.Enricher(enrichers...)
+		for _, opt := range args.Base {
+			opt(&e.Base)
+		}
+
+		return Action(e), nil
+	})
+
+	r.RegisterAction("Uploader", func(args Args) (Option, error) {
+		uploader, err := Dependency[actions.IUploader](args, "uploader")
+		if err != nil {
+			return nil, err
+		}
+
+		return Uploader(uploader, args.Base...), nil
+	})
+
+	r.RegisterAction("Persister", func(args Args) (Option, error) {
+		repo, err := Dependency[actions.IRepository](args, "repository")
+		if err != nil {
+			return nil, err
+		}
+
+		return Persister(repo, args.Base...), nil
+	})
+
+	r.RegisterAction("Encrypter", func(args Args) (Option, error) {
+		keys, err := Dependency[encryption.KeyProvider](args, "key_provider")
+		if err != nil {
+			return nil, err
+		}
+
+		return Encrypter(keys, args.Base...), nil
+	})
+
+	r.RegisterAction("Decrypter", func(args Args) (Option, error) {
+		keys, err := Dependency[encryption.KeyProvider](args, "key_provider")
+		if err != nil {
+			return nil, err
+		}
+
+		return Decrypter(keys, args.Base...), nil
+	})
+
+	r.RegisterAction("Publisher", func(args Args) (Option, error) {
+		publisher, err := Dependency[actions.IPublisher](args, "publisher")
+		if err != nil {
+			return nil, err
+		}
+
+		return Publisher(publisher, args.Base...), nil
+	})
+
+	r.RegisterAction("Servicer", func(args Args) (Option, error) {
+		service, err := Dependency[actions.IService](args, "service")
+		if err != nil {
+			return nil, err
+		}
+
+		return Action(actions.Servicer(service, args.Base...)), nil
+	})
+
+	// the Republisher runs after each action, wherever it is declared
+	r.RegisterAction("Republisher", func(args Args) (Option, error) {
+		republisher, err := Dependency[actions.IRepublisher](args, "republisher")
+		if err != nil {
+			return nil, err
+		}
+
+		maxAttempts, err := args.Int("max_attempts", 3)
+		if err != nil {
+			return nil, err
+		}
+
+		rep := actions.Republisher(republisher, maxAttempts, args.Base...)
+
+		// the retries are delayed if there's a backoff base
+		if _, ok := args.Params["backoff_base"]; ok {
+			backoff, err := backoffParams(args)
+			if err != nil {
+				return nil, err
+			}
+			rep.WithBackoff(backoff)
+		}
+
+		// the dead letter publisher is optional
+		if _, ok := args.deps["dead_letter"]; ok {
+			deadLetter, err := Dependency[actions.IPublisher](args, "dead_letter")
+			if err != nil {
+				return nil, err
+			}
+			rep.WithDeadLetter(deadLetter)
+		}
+
+		return AfterEach(rep), nil
+	})
+
+	// the Parker runs after each action, wherever it is declared
+	r.RegisterAction("Parker", func(args Args) (Option, error) {
+		store, err := Dependency[actions.IParkStore](args, "store")
+		if err != nil {
+			return nil, err
+		}
+
+		return Parker(store, args.Base...), nil
+	})
+
+	// the Alerter runs after each action, wherever it is declared
+	r.RegisterAction("Alerter", func(args Args) (Option, error) {
+		alerter, err := Dependency[actions.IAlerter](args, "alerter")
+		if err != nil {
+			return nil, err
+		}
+
+		return Alerter(alerter, args.Base...), nil
+	})
+
+	r.RegisterTransform("CreateEvent", func(args Args) (actions.TransformOption, error) {
+		category, source, err := categoryAndSource(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.CreateEvent(category, source), nil
+	})
+
+	r.RegisterTransform("EventFromQueueSource", func(args Args) (actions.TransformOption, error) {
+		category, source, err := categoryAndSource(args)
+		if err != nil {
+			return nil, err
+		}
+
+		eventNames, err := args.StringMap("event_names")
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.EventFromQueueSource(category, source, eventNames), nil
+	})
+
+	r.RegisterTransform("RecordsFromFilePointer", func(args Args) (actions.TransformOption, error) {
+		category, source, err := categoryAndSource(args)
+		if err != nil {
+			return nil, err
+		}
+
+		fileKey, err := args.String("file_key")
+		if err != nil {
+			return nil, err
+		}
+
+		eventName, err := args.String("event_name")
+		if err != nil {
+			return nil, err
+		}
+
+		downloader, err := Dependency[actions.IDownloader](args, "downloader")
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.RecordsFromFilePointer(fileKey, category, source, eventName, downloader), nil
+	})
+
+	r.RegisterTransform("RecordsFromKey", func(args Args) (actions.TransformOption, error) {
+		category, source, err := categoryAndSource(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.RecordsFromKey(category, source), nil
+	})
+
+	r.RegisterEnricher("WithDedupe", func(args Args) (actions.EnrichFn, error) {
+		repo, err := Dependency[actions.IRepository](args, "repository")
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.WithDedupe(repo), nil
+	})
+
+	r.RegisterEnricher("WithSubEntity", func(args Args) (actions.EnrichFn, error) {
+		subEntity, err := args.String("sub_entity")
+		if err != nil {
+			return nil, err
+		}
+
+		repo, err := Dependency[actions.IRepository](args, "repository")
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.WithSubEntity(subEntity, repo), nil
+	})
+
+	r.RegisterEnricher("WithPatchOriginal", func(args Args) (actions.EnrichFn, error) {
+		repo, err := Dependency[actions.IRepository](args, "repository")
+		if err != nil {
+			return nil, err
+		}
+
+		return actions.WithPatchOriginal(repo), nil
+	})
+}
+
+func backoffParams(args Args) (b actions.Backoff, err error) {
+	if b.Base, err = args.Duration("backoff_base", 0); err != nil {
+		return b, err
+	}
+
+	if b.Max, err = args.Duration("backoff_max", 0); err != nil {
+		return b, err
+	}
+
+	if b.Multiplier, err = args.Float("backoff_multiplier", 2); err != nil {
+		return b, err
+	}
+
+	if b.Jitter, err = args.Float("backoff_jitter", 0); err != nil {
+		return b, err
+	}
+
+	return b, nil
+}
+
+func categoryAndSource(args Args) (category, source string, err error) {
+	if category, err = args.String("category"); err != nil {
+		return "", "", err
+	}
+
+	if source, err = args.String("source"); err != nil {
+		return "", "", err
+	}
+
+	return category, source, nil
+}
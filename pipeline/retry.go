@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+// retrying is an action that retries the business events that failed within the invocation
+type retrying interface {
+	RetryPolicy() *actions.RetryPolicy
+}
+
+// unwrapper is an action that wraps another one, e.g. to run it through the middleware
+type unwrapper interface {
+	unwrap() action
+}
+
+// retryPolicyOf returns the retry policy of the action, looked up on the action the wrappers wrap, nil meaning no retries.
+// The stand-ins for the actions that weren't let to process the business events don't retry them
+func retryPolicyOf(act action) *actions.RetryPolicy {
+	for {
+		if r, ok := act.(retrying); ok {
+			return r.RetryPolicy()
+		}
+
+		w, ok := act.(unwrapper)
+		if !ok {
+			return nil
+		}
+		act = w.unwrap()
+	}
+}
+
+// invokeWithRetry lets the action process the business events, and then again the ones that failed with a retryable
+// error, until they succeed, run out of attempts, or the context is done. The events that already had an error,
+// e.g. the ones processed only by critical actions, are left as they are
+func invokeWithRetry(ctx context.Context, action action, bes []model.Medium) {
+	var clean []model.Medium
+	for _, be := range bes {
+		if be.GetError() == nil {
+			clean = append(clean, be)
+		}
+	}
+
+	invoke(ctx, action, bes)
+
+	policy := retryPolicyOf(action)
+	if policy == nil {
+		return
+	}
+
+	for attempt := 2; attempt <= policy.Attempts; attempt++ {
+		var failed []model.Medium
+		for _, be := range clean {
+			if policy.IsRetryable(be.GetError()) {
+				failed = append(failed, be)
+			}
+		}
+
+		if len(failed) == 0 {
+			return
+		}
+
+		if !wait(ctx, policy.Backoff.Delay(attempt-1)) {
+			model.LoggerFrom(ctx).Warn("No time left for another attempt, the failed business events are on their own now.",
+				"action", action.Name(), "failed", len(failed))
+			return
+		}
+
+		model.LoggerFrom(ctx).Info("Try, try again. Giving the failed business events another go.",
+			"action", action.Name(), "attempt", attempt, "failed", len(failed))
+
+		for _, be := range failed {
+			be.SetError(nil)
+		}
+
+		model.MetricsFrom(ctx).Count(model.MetricActionRetries, float64(len(failed)), nil)
+
+		invoke(ctx, action, failed)
+	}
+}
+
+// wait waits for the delay, returning false if the context was done before it was over
+func wait(ctx context.Context, delay time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+var errThrottled = errors.New("throttled")
+
+// flakyAction fails the business events with the error for the set number of times they're processed
+type flakyAction struct {
+	*recordingAction
+	failures map[string]int
+	err      error
+}
+
+func (a *flakyAction) Process(ctx context.Context, bes ...model.Medium) {
+	a.recordingAction.Process(ctx, bes...)
+
+	for _, be := range bes {
+		if a.failures[be.GetID()] > 0 {
+			a.failures[be.GetID()]--
+			be.SetError(a.err)
+		}
+	}
+}
+
+func TestPipeline_Retry(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	flaky := &flakyAction{
+		recordingAction: newRecordingAction("flaky",
+			actions.BatchSize(10),
+			actions.FailureMandate(model.StopFurtherProcessing),
+			actions.Retry(actions.RetryPolicy{Attempts: 3, Backoff: actions.Backoff{Base: time.Millisecond}}),
+		),
+		failures: map[string]int{"1": 1, "2": 5},
+		err:      errThrottled,
+	}
+	next := newRecordingAction("next")
+
+	p := NewPipeline(&product{}, Action(flaky), Action(next))
+
+	bes := []model.Medium{newEvent("1", "CreateProduct"), newEvent("2", "CreateProduct"), newEvent("3", "CreateProduct")}
+
+	outcomes := p.run(context.Background(), bes)
+
+	// only the failed events are retried, and only until they run out of attempts
+	assert.Equal(t, []string{"1", "2", "3", "1", "2", "2"}, flaky.visited)
+	assert.Equal(t, []string{"1", "3"}, next.visited)
+
+	require.ErrorIs(t, bes[1].GetError(), errThrottled)
+	assert.Equal(t, model.StopFurtherProcessing, bes[1].(model.PipelineMedium).GetPreviousActionMandate())
+
+	assert.Equal(t, ActionOutcome{Action: "flaky", Index: 0, Processed: 3, Failed: 1}, outcomes[0])
+}
+
+func TestPipeline_Retry_with_middleware(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	flaky := &flakyAction{
+		recordingAction: newRecordingAction("flaky",
+			actions.Retry(actions.RetryPolicy{Attempts: 3, Backoff: actions.Backoff{Base: time.Millisecond}}),
+		),
+		failures: map[string]int{"1": 2},
+		err:      errThrottled,
+	}
+
+	// the middleware wraps the action, but its retry policy still applies
+	p := NewPipeline(&product{}, Use(Logging()), Action(flaky))
+
+	bes := []model.Medium{newEvent("1", "CreateProduct")}
+
+	p.run(context.Background(), bes)
+
+	assert.Equal(t, []string{"1", "1", "1"}, flaky.visited)
+	assert.NoError(t, bes[0].GetError())
+}
+
+func TestPipeline_Retry_not_retryable(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	flaky := &flakyAction{
+		recordingAction: newRecordingAction("flaky",
+			actions.Retry(actions.RetryPolicy{Attempts: 3, Retryable: actions.RetryOn(errThrottled)}),
+		),
+		failures: map[string]int{"1": 1},
+		err:      errors.New("validation failed"),
+	}
+
+	p := NewPipeline(&product{}, Action(flaky))
+
+	bes := []model.Medium{newEvent("1", "CreateProduct")}
+
+	p.run(context.Background(), bes)
+
+	assert.Equal(t, []string{"1"}, flaky.visited)
+	assert.Error(t, bes[0].GetError())
+}
+
+func TestPipeline_Retry_context_done(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	flaky := &flakyAction{
+		recordingAction: newRecordingAction("flaky",
+			actions.Retry(actions.RetryPolicy{Attempts: 3, Backoff: actions.Backoff{Base: time.Minute}}),
+		),
+		failures: map[string]int{"1": 1},
+		err:      errThrottled,
+	}
+
+	p := NewPipeline(&product{}, Action(flaky))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	bes := []model.Medium{newEvent("1", "CreateProduct")}
+
+	p.run(ctx, bes)
+
+	assert.Equal(t, []string{"1"}, flaky.visited)
+	assert.ErrorIs(t, bes[0].GetError(), errThrottled)
+}
@@ -0,0 +1,209 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zale144/ube/model"
+)
+
+type (
+	// Router is an action that dispatches each business event to the first sub-pipeline whose
+	// matcher accepts it. Business events that no route accepts are left as they are
+	Router struct {
+		routes []*route
+		// err is why the routes can't run, reported once the pipeline is built
+		err error
+	}
+	// Matcher decides whether a business event should be taken by a route
+	Matcher func(be model.Medium) bool
+	// RouteOption is a func type abstraction of a route
+	RouteOption func(*Router)
+
+	route struct {
+		name     string
+		match    Matcher
+		pipeline *Pipeline
+	}
+)
+
+var _ composite = (*Router)(nil)
+
+// Route constructs a new action that dispatches the business events to the provided sub-pipelines.
+// The event name and category are only known after the InputTransformer, so Route should come after it
+func Route(routes ...RouteOption) Option {
+	r := &Router{}
+
+	for _, opt := range routes {
+		opt(r)
+	}
+
+	if len(r.routes) == 0 && r.err == nil {
+		r.err = errors.New("no routes were provided for the router")
+	}
+
+	return Action(r)
+}
+
+// Branch constructs a named sub-pipeline, with its own actions, mandates and batch sizes,
+// for the business events accepted by the matcher
+func Branch(name string, match Matcher, options ...Option) RouteOption {
+	return func(r *Router) {
+		sub := &Pipeline{
+			actions:  []action{},
+			settings: &settings{},
+		}
+
+		for _, option := range options {
+			option(sub)
+		}
+
+		if err := sub.init(); err != nil && r.err == nil {
+			r.err = fmt.Errorf("route '%s': %w", name, err)
+		}
+
+		r.routes = append(r.routes, &route{
+			name:     name,
+			match:    match,
+			pipeline: sub,
+		})
+	}
+}
+
+// Otherwise constructs a named sub-pipeline for the business events that no other route accepts
+func Otherwise(name string, options ...Option) RouteOption {
+	return Branch(name, func(model.Medium) bool { return true }, options...)
+}
+
+// OnEventName matches the business events with any of the provided event names
+func OnEventName(names ...string) Matcher {
+	return onAnyOf(func(be model.Medium) string { return be.GetEventName() }, names)
+}
+
+// OnEventCategory matches the business events with any of the provided event categories
+func OnEventCategory(categories ...string) Matcher {
+	return onAnyOf(func(be model.Medium) string {
+		if cat, ok := be.(interface{ GetEventCategory() string }); ok {
+			return cat.GetEventCategory()
+		}
+		return ""
+	}, categories)
+}
+
+// OnSourceURI matches the business events that came from any of the provided source URIs
+func OnSourceURI(uris ...string) Matcher {
+	return onAnyOf(func(be model.Medium) string {
+		if src, ok := be.(interface{ GetEventSource() string }); ok {
+			return src.GetEventSource()
+		}
+		return ""
+	}, uris)
+}
+
+// When matches the business events for which the predicate returns true
+func When(predicate func(be model.Medium) bool) Matcher {
+	return predicate
+}
+
+func onAnyOf(get func(be model.Medium) string, values []string) Matcher {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+
+	return func(be model.Medium) bool {
+		_, ok := set[get(be)]
+		return ok
+	}
+}
+
+// Process implements the action interface in UBE, runs each business event through its route
+func (r *Router) Process(ctx context.Context, bes ...model.Medium) {
+	r.process(ctx, bes)
+}
+
+func (r *Router) process(ctx context.Context, bes []model.Medium) []ActionOutcome {
+	var outcomes []ActionOutcome
+
+	groups := make([][]model.Medium, len(r.routes))
+
+	var unrouted int
+
+	for _, be := range bes {
+		routed := false
+		for i, rt := range r.routes {
+			if rt.match(be) {
+				groups[i] = append(groups[i], be)
+				routed = true
+				break
+			}
+		}
+
+		if !routed {
+			unrouted++
+		}
+	}
+
+	for i, rt := range r.routes {
+		if len(groups[i]) == 0 {
+			continue
+		}
+
+		model.LoggerFrom(ctx).Info("Taking the scenic route.", "route", rt.name, "size", len(groups[i]))
+
+		// the sub-pipeline works on the very same business events,
+		// so its results end up in the result of the parent pipeline
+		outcomes = append(outcomes, rt.pipeline.run(ctx, groups[i])...)
+	}
+
+	if unrouted > 0 {
+		model.LoggerFrom(ctx).Info("business events not matching any route", "size", unrouted)
+	}
+
+	return outcomes
+}
+
+func (r *Router) link(s *settings, offset int, l model.Logger) int {
+	for _, rt := range r.routes {
+		offset = rt.pipeline.link(s, offset, l)
+	}
+
+	return offset
+}
+
+func (r *Router) check() error {
+	return r.err
+}
+
+func (*Router) Name() string {
+	return "Router"
+}
+
+// DepCallNames returns the dependency calls of all the routes
+func (r *Router) DepCallNames() []string {
+	var names []string
+	for _, rt := range r.routes {
+		for _, act := range rt.pipeline.actions {
+			names = append(names, act.DepCallNames()...)
+		}
+	}
+
+	return names
+}
+
+func (*Router) IsCritical() bool {
+	return false
+}
+
+func (*Router) FailureMandate() model.ActionMandate {
+	return 0
+}
+
+func (*Router) BatchSize() int {
+	return 0
+}
+
+func (*Router) IsAsync() bool {
+	return false
+}
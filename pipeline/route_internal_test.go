@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+type recordingAction struct {
+	actions.Base
+	name    string
+	fail    map[string]error
+	visited []string
+	mu      sync.Mutex
+}
+
+func (a *recordingAction) Name() string           { return a.name }
+func (a *recordingAction) DepCallNames() []string { return []string{a.name} }
+
+func (a *recordingAction) Process(_ context.Context, bes ...model.Medium) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, be := range bes {
+		a.visited = append(a.visited, be.GetID())
+		if err, ok := a.fail[be.GetID()]; ok {
+			be.SetError(err)
+		}
+	}
+}
+
+func newEvent(id, name string) *model.BusinessEvent {
+	return &model.BusinessEvent{
+		ID:    id,
+		Event: &model.Event{EventHeader: model.EventHeader{EventName: name, EventCategory: "product"}},
+	}
+}
+
+func TestRoute_dispatches_by_event_name(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	first := &recordingAction{name: "first"}
+	create := &recordingAction{name: "create"}
+	update := &recordingAction{name: "update", fail: map[string]error{"3": errors.New("boom")}}
+	last := &recordingAction{name: "last"}
+
+	p := NewPipeline(&product{},
+		Action(first),
+		Route(
+			Branch("create", OnEventName("CreateProduct"), Action(create)),
+			Branch("update", OnEventName("UpdateProduct"), Action(update)),
+		),
+		Action(last),
+	)
+
+	bes := []model.Medium{
+		newEvent("1", "CreateProduct"),
+		newEvent("2", "UpdateProduct"),
+		newEvent("3", "UpdateProduct"),
+		newEvent("4", "DeleteProduct"),
+	}
+
+	p.run(context.Background(), bes)
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, first.visited)
+	assert.Equal(t, []string{"1"}, create.visited)
+	assert.Equal(t, []string{"2", "3"}, update.visited)
+	assert.Equal(t, []string{"1", "2", "4"}, last.visited)
+
+	result, err := newResult(context.Background(), bes)
+	require.Error(t, err)
+	assert.Equal(t, StatusPartiallyFailed, result.Status)
+	assert.Equal(t, []string{"boom"}, result.Errors)
+}
+
+func TestRoute_matchers(t *testing.T) {
+	be := newEvent("1", "CreateProduct")
+	be.Event.EventSource = "createQueue"
+
+	assert.True(t, OnEventName("UpdateProduct", "CreateProduct")(be))
+	assert.False(t, OnEventName("UpdateProduct")(be))
+	assert.True(t, OnEventCategory("product")(be))
+	assert.False(t, OnEventCategory("car")(be))
+	assert.True(t, OnSourceURI("createQueue")(be))
+	assert.False(t, OnSourceURI("updateQueue")(be))
+	assert.True(t, When(func(be model.Medium) bool { return be.GetID() == "1" })(be))
+}
+
+func TestRoute_index(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	p := NewPipeline(&product{},
+		Action(&recordingAction{name: "first"}),
+		Route(
+			Branch("create", OnEventName("CreateProduct"),
+				Action(&recordingAction{name: "a"}),
+				Action(&recordingAction{name: "b"}),
+			),
+			Otherwise("rest", Action(&recordingAction{name: "c"})),
+		),
+		Action(&recordingAction{name: "last"}),
+	)
+
+	router := p.actions[1].(*Router)
+
+	assert.Equal(t, 0, p.offset)
+	assert.Equal(t, 3, router.routes[0].pipeline.offset)
+	assert.Equal(t, 5, router.routes[1].pipeline.offset)
+	assert.Equal(t, []string{"a", "b", "c"}, router.DepCallNames())
+}
+
+func TestRoute_invalid(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	tests := []struct {
+		name    string
+		option  Option
+		wantErr string
+	}{
+		{
+			name:    "no routes",
+			option:  Route(),
+			wantErr: "build pipeline fail: no routes were provided for the router",
+		},
+		{
+			name:    "no route actions",
+			option:  Route(Otherwise("rest")),
+			wantErr: "build pipeline fail: route 'rest': no actions were provided for the pipeline",
+		},
+		{
+			name: "unknown depends_on",
+			option: Route(Otherwise("rest",
+				Action(newRecordingAction("a", actions.DependsOn("b"))),
+			)),
+			wantErr: "build pipeline fail: route 'rest': invalid pipeline action dependencies: " +
+				"action 'a' depends on 'b', which is not declared before it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newPipeline(&product{}, tt.option)
+			assert.EqualError(t, err, tt.wantErr)
+
+			assert.Panics(t, func() { NewPipeline(&product{}, tt.option) })
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/zale144/ube/model"
+)
+
+type (
+	// EventResult is the result of streaming a single input through the pipeline
+	EventResult struct {
+		// ID is the ID of the input
+		ID string
+		// BusinessEvent is the processed business event, nil if the input couldn't be converted to one
+		BusinessEvent model.PipelineMedium
+		// Err is the error of the business event, the conversion error of the input, or model.ErrDuplicate
+		// and model.ErrInFlight for the inputs that skipped the pipeline, like InvokePipeline skips them
+		Err error
+	}
+	// StreamOption is a func type abstraction of the stream configuration
+	StreamOption func(*stream)
+
+	stream struct {
+		window int
+	}
+)
+
+// Window sets how many inputs are taken from the stream and run through the pipeline at once.
+// By default, it's the largest batch size of the pipeline actions
+func Window(size int) StreamOption {
+	return func(s *stream) {
+		s.window = size
+	}
+}
+
+// InvokeStream runs the inputs through the pipeline in windows, emitting the result of every input on the
+// returned channel, in the order the inputs came in. Only one window is held in memory at a time,
+// and the next one isn't read from the inputs until the results of the current one were taken,
+// so a slow consumer slows the whole stream down. The returned channel is closed once the inputs
+// are closed, or the context is done
+func (p *Pipeline) InvokeStream(ctx context.Context, inputs <-chan model.Input, options ...StreamOption) <-chan EventResult {
+	s := &stream{window: p.window()}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	if s.window <= 0 {
+		s.window = 1
+	}
+
+	results := make(chan EventResult)
+
+	if ctx == nil {
+		// the batches keep their metadata in the context, so there has to be one
+		ctx = context.Background()
+	}
+
+	ctx = p.withMetrics(ctx)
+	ctx = p.withLogger(ctx)
+
+	go func() {
+		defer close(results)
+
+		var windows int
+
+		for {
+			ins, more := receive(ctx, inputs, s.window)
+			if len(ins) > 0 {
+				windows++
+				model.LoggerFrom(ctx).Info("Here comes another window of the stream. Keep 'em coming!",
+					"window", windows, "size", len(ins))
+
+				if !emit(ctx, results, p.runWindow(ctx, ins)) {
+					return
+				}
+			}
+
+			if !more {
+				model.LoggerFrom(ctx).Info("The stream has dried up.", "windows", windows)
+				return
+			}
+		}
+	}()
+
+	return results
+}
+
+// window is the largest batch size of the pipeline actions
+func (p *Pipeline) window() int {
+	size := 1
+	for _, act := range p.actions {
+		if act.BatchSize() > size {
+			size = act.BatchSize()
+		}
+	}
+
+	return size
+}
+
+// runWindow runs a window of inputs through the pipeline and collects the result of each of them.
+// The inputs processed already, or being processed by another invocation, come back with no business event
+func (p *Pipeline) runWindow(ctx context.Context, ins []model.Input) []EventResult {
+	result, err := p.invoke(ctx, "InvokeStream window", ins)
+
+	skipped := make(map[string]error, len(result.Duplicates)+len(result.InFlight))
+	for _, id := range result.Duplicates {
+		skipped[id] = model.ErrDuplicate
+	}
+	for _, id := range result.InFlight {
+		skipped[id] = model.ErrInFlight
+	}
+
+	results := make([]EventResult, len(ins))
+
+	// every input that wasn't skipped makes a single business event, in the order of the inputs
+	var n int
+
+	for i, in := range ins {
+		results[i] = EventResult{ID: in.GetID()}
+
+		if skip, ok := skipped[in.GetID()]; ok {
+			results[i].Err = skip
+			continue
+		}
+
+		if n >= len(result.BusinessEvents) {
+			// the inputs didn't make it into the pipeline
+			results[i].Err = err
+			continue
+		}
+
+		be := result.BusinessEvents[n]
+		results[i].BusinessEvent, results[i].Err = be, be.GetError()
+		n++
+	}
+
+	return results
+}
+
+// receive takes up to size inputs, returning false once there are no more of them to take.
+// Nothing is taken if the context is done, as there would be no one to take the results
+func receive(ctx context.Context, inputs <-chan model.Input, size int) ([]model.Input, bool) {
+	ins := make([]model.Input, 0, size)
+
+	for len(ins) < size {
+		select {
+		case in, ok := <-inputs:
+			if !ok {
+				return ins, false
+			}
+			ins = append(ins, in)
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+
+	return ins, true
+}
+
+// emit sends the results, returning false if the context was done before all of them were taken
+func emit(ctx context.Context, results chan<- EventResult, window []EventResult) bool {
+	for _, res := range window {
+		select {
+		case results <- res:
+		case <-ctx.Done():
+			model.LoggerFrom(ctx).Warn("Nobody is listening anymore, so the rest of the stream stays where it is.", "error", ctx.Err())
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/model"
+)
+
+// refusingAction fails the business event of the refused input
+type refusingAction struct {
+	*recordingAction
+	refuse string
+}
+
+func (a *refusingAction) Process(ctx context.Context, bes ...model.Medium) {
+	a.recordingAction.Process(ctx, bes...)
+
+	for _, be := range bes {
+		if be.(*model.BusinessEvent).Event.ID == a.refuse {
+			be.SetError(errors.New("boom"))
+		}
+	}
+}
+
+func produce(n int) <-chan model.Input {
+	inputs := make(chan model.Input)
+
+	go func() {
+		defer close(inputs)
+		for i := 1; i <= n; i++ {
+			inputs <- model.Message{ID: strconv.Itoa(i), Body: []byte(`{}`)}
+		}
+	}()
+
+	return inputs
+}
+
+// batchSizes is a middleware that records the size of every processed batch
+func batchSizes(sizes *[]int) Middleware {
+	var mu sync.Mutex
+
+	return func(next ActionFunc) ActionFunc {
+		return func(ctx context.Context, call ActionCall, bes ...model.Medium) {
+			mu.Lock()
+			*sizes = append(*sizes, len(bes))
+			mu.Unlock()
+
+			next(ctx, call, bes...)
+		}
+	}
+}
+
+func TestPipeline_InvokeStream(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	tests := []struct {
+		name    string
+		options []StreamOption
+		sizes   []int
+	}{
+		{name: "batch size window", sizes: []int{3, 3, 1}},
+		{name: "custom window", options: []StreamOption{Window(4)}, sizes: []int{3, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sizes []int
+
+			act := &refusingAction{recordingAction: newRecordingAction("save", actions.BatchSize(3)), refuse: "5"}
+
+			p := NewPipeline(&product{}, Use(batchSizes(&sizes)), Action(act))
+
+			var (
+				ids  []string
+				errs []error
+			)
+
+			for res := range p.InvokeStream(context.Background(), produce(7), tt.options...) {
+				require.NotNil(t, res.BusinessEvent)
+				ids = append(ids, res.ID)
+				errs = append(errs, res.Err)
+			}
+
+			assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7"}, ids)
+			assert.Equal(t, []error{nil, nil, nil, nil, errors.New("boom"), nil, nil}, errs)
+			assert.Equal(t, tt.sizes, sizes)
+		})
+	}
+}
+
+func TestPipeline_InvokeStream_cancelled(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	act := newRecordingAction("save")
+	p := NewPipeline(&product{}, Action(act))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := p.InvokeStream(ctx, produce(100), Window(1))
+
+	res := <-results
+	assert.Equal(t, "1", res.ID)
+
+	cancel()
+
+	// the stream stops at the window in flight
+	for range results {
+	}
+
+	assert.LessOrEqual(t, len(act.visited), 3)
+}
+
+func TestPipeline_InvokeStream_nil_context(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	act := newRecordingAction("save")
+	p := NewPipeline(&product{}, Action(act))
+
+	var (
+		ctx context.Context
+		ids []string
+	)
+
+	for res := range p.InvokeStream(ctx, produce(2)) {
+		require.NoError(t, res.Err)
+		ids = append(ids, res.ID)
+	}
+
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
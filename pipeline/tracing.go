@@ -0,0 +1,178 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zale144/ube/model"
+)
+
+// Tracing makes the pipeline trace the invocations, the actions and their batches with the tracer.
+// The invocation continues the trace the business events came with, and the business events leave with
+// the trace context of the invocation, so that the Publisher and the Republisher pass it on
+func Tracing(tracer model.Tracer) Option {
+	return func(p *Pipeline) {
+		p.tracer = tracer
+	}
+}
+
+// traced is a business event that carries the trace context of the pipeline it went through
+type traced interface {
+	model.TraceCarrier
+	SetTraceParent(string)
+}
+
+// startInvocation starts the span of the invocation. Unless there's a parent in the context already, the span
+// continues the trace the business events came with, if they all came with the same one. Otherwise, it's linked to them
+func (p *Pipeline) startInvocation(ctx context.Context, name string, bes []model.Medium) (context.Context, model.Span) {
+	if p.tracer == nil {
+		return ctx, model.SpanFrom(nil)
+	}
+
+	ctx = model.WithTracer(ctx, p.tracer)
+
+	var incoming []model.SpanContext
+	seen := make(map[model.SpanContext]struct{})
+
+	for _, be := range bes {
+		tc, ok := be.(model.TraceCarrier)
+		if !ok {
+			continue
+		}
+		if sc, ok := model.ParseTraceParent(tc.GetTraceParent()); ok {
+			if _, dup := seen[sc]; !dup {
+				seen[sc] = struct{}{}
+				incoming = append(incoming, sc)
+			}
+		}
+	}
+
+	if _, ok := model.ParentSpanContext(ctx); !ok && len(incoming) == 1 {
+		ctx = model.WithRemoteSpanContext(ctx, incoming[0])
+		incoming = nil
+	}
+
+	ctx, span := p.tracer.Start(ctx, name)
+	span.SetAttribute("ube.events", len(bes))
+
+	for _, sc := range incoming {
+		span.AddLink(sc)
+	}
+
+	for _, be := range bes {
+		if t, ok := be.(traced); ok {
+			t.SetTraceParent(span.SpanContext().TraceParent())
+		}
+	}
+
+	return model.WithSpan(ctx, span), span
+}
+
+// endInvocation ends the span of the invocation with the outcome of the business events
+func endInvocation(span model.Span, bes []model.Medium) {
+	var (
+		failed int
+		err    error
+	)
+
+	for _, be := range bes {
+		if be.GetError() == nil {
+			continue
+		}
+		if failed++; err == nil {
+			err = be.GetError()
+		}
+	}
+
+	span.SetAttribute("ube.failed", failed)
+	span.SetAttribute("ube.outcome", outcomeOf(len(bes), failed))
+	span.End(err)
+}
+
+// startAction starts the span of the action processing all the business events
+func (p *Pipeline) startAction(ctx context.Context, act action, idx int) (context.Context, model.Span) {
+	if p.tracer == nil {
+		return ctx, model.SpanFrom(nil)
+	}
+
+	ctx, span := model.StartSpan(ctx, "action "+act.Name())
+	span.SetAttribute("ube.action", act.Name())
+	span.SetAttribute("ube.action_id", p.ids.byIndex[idx])
+	span.SetAttribute("ube.action_index", idx)
+
+	return model.WithSpan(ctx, span), span
+}
+
+// endAction ends the span of the action with how it fared with the business events
+func endAction(span model.Span, outcomes []ActionOutcome) {
+	var processed, failed int
+	for _, o := range outcomes {
+		processed += o.Processed
+		failed += o.Failed
+	}
+
+	span.SetAttribute("ube.processed", processed)
+	span.SetAttribute("ube.failed", failed)
+	span.SetAttribute("ube.outcome", outcomeOf(processed, failed))
+	span.End(nil)
+}
+
+// trace traces the action batches processed by fn, with the business events they held and how they fared.
+// The batch holds the business events the action skips as well, so only the ones it processed count
+func (p *Pipeline) trace(fn batchFn) batchFn {
+	return func(ctx context.Context, bes []model.Medium, action action, actionIdx int) (int, int) {
+		if p.tracer == nil {
+			return fn(ctx, bes, action, actionIdx)
+		}
+
+		ctx, span := model.StartSpan(ctx, "batch "+action.Name())
+		ctx = model.WithSpan(ctx, span)
+
+		span.SetAttribute("ube.action_id", p.ids.byIndex[actionIdx])
+		span.SetAttribute("ube.batch", batchOf(ctx))
+
+		var ids, names, keys []string
+		for _, be := range bes {
+			ids = append(ids, be.GetID())
+			names = append(names, be.GetEventName())
+			for _, ent := range be.GetEntities() {
+				if ent != nil && ent.GetKey() != nil {
+					keys = append(keys, model.StringifyKey(ent.GetKey()))
+				}
+			}
+		}
+
+		span.SetAttribute("ube.event_ids", ids)
+		span.SetAttribute("ube.event_names", names)
+		span.SetAttribute("ube.entity_keys", keys)
+
+		processed, failed := fn(ctx, bes, action, actionIdx)
+
+		span.SetAttribute("ube.processed", processed)
+		span.SetAttribute("ube.failed", failed)
+		span.SetAttribute("ube.outcome", outcomeOf(processed, failed))
+
+		var err error
+		if failed > 0 {
+			err = fmt.Errorf("%d of the %d business events failed", failed, processed)
+		}
+
+		span.End(err)
+
+		return processed, failed
+	}
+}
+
+// outcomeOf sums up how the business events fared
+func outcomeOf(processed, failed int) string {
+	switch {
+	case processed == 0:
+		return "skipped"
+	case failed == 0:
+		return "succeeded"
+	case failed == processed:
+		return "failed"
+	}
+
+	return "partially_failed"
+}
@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/zale144/ube/actions"
+	"github.com/zale144/ube/libs/tracing"
+	"github.com/zale144/ube/model"
+)
+
+const (
+	traceParentA = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traceParentB = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+)
+
+func tracedInput(id, traceParent string) model.Input {
+	msg := parkInput(id).(*model.Message)
+	msg.TraceParent = traceParent
+
+	return msg
+}
+
+func spansByName(spans []tracing.RecordedSpan) map[string]tracing.RecordedSpan {
+	byName := make(map[string]tracing.RecordedSpan, len(spans))
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	return byName
+}
+
+func TestTracing(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	persister := newRecordingAction("Persister", actions.Label("stock"), actions.BatchSize(10))
+	persister.fail = map[string]error{"2": errors.New("boom")}
+	publisher := newRecordingAction("Publisher", actions.BatchSize(10))
+
+	rec := tracing.NewRecorder()
+
+	p := NewPipeline(&product{}, Action(persister), Action(publisher), Tracing(rec))
+
+	result, err := p.InvokePipeline(context.Background(), tracedInput("1", traceParentA), tracedInput("2", traceParentA))
+	require.Error(t, err)
+
+	spans := rec.Spans()
+	require.Len(t, spans, 5)
+
+	byName := spansByName(spans)
+	invocation, action, batch := byName["InvokePipeline"], byName["action Persister"], byName["batch Persister"]
+
+	// the invocation continues the trace the business events came with
+	parent, _ := model.ParseTraceParent(traceParentA)
+	assert.Equal(t, parent.TraceID, invocation.TraceID)
+	assert.Equal(t, parent.SpanID, invocation.ParentSpanID)
+	assert.Empty(t, invocation.Links)
+	assert.Equal(t, "partially_failed", invocation.Attributes["ube.outcome"])
+	assert.EqualError(t, invocation.Err, "boom")
+
+	assert.Equal(t, invocation.SpanID, action.ParentSpanID)
+	assert.Equal(t, "Persister:stock", action.Attributes["ube.action_id"])
+	assert.Equal(t, "partially_failed", action.Attributes["ube.outcome"])
+
+	assert.Equal(t, action.SpanID, batch.ParentSpanID)
+	assert.Equal(t, []string{"1", "2"}, batch.Attributes["ube.event_ids"])
+	assert.Equal(t, []string{"created", "created"}, batch.Attributes["ube.event_names"])
+	assert.Equal(t, 1, batch.Attributes["ube.failed"])
+	assert.Error(t, batch.Err)
+
+	// the failed event is in the batch of the Publisher as well, but only the other one gets published
+	assert.Equal(t, []string{"1", "2"}, byName["batch Publisher"].Attributes["ube.event_ids"])
+	assert.Equal(t, 1, byName["batch Publisher"].Attributes["ube.processed"])
+
+	// the business events leave with the trace context of the invocation
+	want := model.SpanContext{TraceID: invocation.TraceID, SpanID: invocation.SpanID, Sampled: true}.TraceParent()
+	for _, be := range result.BusinessEvents {
+		assert.Equal(t, want, be.(model.TraceCarrier).GetTraceParent())
+	}
+}
+
+func TestTracing_Links(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	rec := tracing.NewRecorder()
+
+	p := NewPipeline(&product{}, Action(newRecordingAction("Persister")), Tracing(rec))
+
+	_, err := p.InvokePipeline(context.Background(), tracedInput("1", traceParentA), tracedInput("2", traceParentB), parkInput("3"))
+	require.NoError(t, err)
+
+	// the business events came with different traces, so the invocation starts a new one, linked to them
+	invocation := spansByName(rec.Spans())["InvokePipeline"]
+	assert.Empty(t, invocation.ParentSpanID)
+	assert.Len(t, invocation.Links, 2)
+	assert.Equal(t, "succeeded", invocation.Attributes["ube.outcome"])
+}
+
+func TestTracing_Disabled(t *testing.T) {
+	p := NewPipeline(&product{}, Action(newRecordingAction("Persister")))
+
+	result, err := p.InvokePipeline(context.Background(), tracedInput("1", traceParentA))
+	require.NoError(t, err)
+
+	// without a tracer, the trace context the business event came with is left as is
+	assert.Equal(t, traceParentA, result.BusinessEvents[0].(model.TraceCarrier).GetTraceParent())
+}
+
+func TestTracing_concurrent_publisher(t *testing.T) {
+	logger := zap.NewExample()
+	defer func() { _ = logger.Sync() }()
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var published []model.Input
+
+	pub := actions.NewMockIPublisher(ctrl)
+	pub.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msgs ...model.Input) error {
+			published = append(published, msgs...)
+			return nil
+		})
+
+	rec := tracing.NewRecorder()
+
+	p := NewPipeline(&product{},
+		Action(newRecordingAction("Persister")),
+		// the Publisher runs alongside the Uploader, on its own view of the business events
+		Publisher(pub, actions.DependsOn("Persister")),
+		Action(newRecordingAction("Uploader", actions.DependsOn("Persister"))),
+		Tracing(rec),
+	)
+
+	_, err := p.InvokePipeline(context.Background(), tracedInput("1", traceParentA), tracedInput("2", traceParentA))
+	require.NoError(t, err)
+
+	invocation := spansByName(rec.Spans())["InvokePipeline"]
+	want := model.SpanContext{TraceID: invocation.TraceID, SpanID: invocation.SpanID, Sampled: true}.TraceParent()
+
+	require.Len(t, published, 2)
+	for _, msg := range published {
+		assert.Equal(t, want, msg.(*model.Message).TraceParent)
+	}
+}